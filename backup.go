@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"leads/storage"
+)
+
+// BackupDir - directory the backups are written to, "backups" by default
+const BackupDir = "BACKUP_DIR"
+
+func backupDir() string {
+	return envOr(BackupDir, "backups")
+}
+
+// backupCommand - writes a snapshot of the DB_PATH database, which may be in use by a running server
+func backupCommand(args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	output := flags.String("o", "", "path of the backup, a new file of BACKUP_DIR by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("usage: backup [-o PATH]")
+	}
+
+	path := *output
+	if path == "" {
+		path = storage.BackupPath(backupDir())
+	}
+
+	ctx := context.Background()
+	s, err := connectSQL(ctx, os.Getenv(DBPATH))
+	if err != nil {
+		return err
+	}
+
+	info, err := s.Backup(ctx, path)
+	if err != nil {
+		return err
+	}
+	printBackup("backed up to", info)
+
+	return nil
+}
+
+// restoreCommand - replaces the DB_PATH database with a backup. The database is backed up to BACKUP_DIR first, so
+// a restore can be undone. Stop the server before restoring
+func restoreCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: restore PATH")
+	}
+
+	ctx := context.Background()
+	s, err := connectSQL(ctx, os.Getenv(DBPATH))
+	if err != nil {
+		return err
+	}
+
+	// Checked before the current database is backed up, so refusing the backup leaves nothing behind
+	if _, err := s.InspectBackup(ctx, args[0]); err != nil {
+		return err
+	}
+
+	current, err := s.Backup(ctx, storage.BackupPath(backupDir()))
+	if err != nil {
+		return fmt.Errorf("can't back up the current database: %w", err)
+	}
+	printBackup("current database backed up to", current)
+
+	info, err := s.Restore(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	printBackup("restored", info)
+
+	statuses, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if pending := pendingMigrations(statuses); pending > 0 {
+		fmt.Printf("%d migrations are pending, they are applied on start or by `migrate up`\n", pending)
+	}
+
+	return nil
+}
+
+func printBackup(action string, info *storage.BackupInfo) {
+	fmt.Printf("%s %s (%d bytes, schema version %d)\n", action, info.Path, info.Size, info.SchemaVersion)
+}
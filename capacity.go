@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"leads/storage"
+)
+
+// Capacity index of the assignment engine, SQL databases only
+const (
+	// CapacityIndex - when "true", the server keeps the usage of the clients in memory and assigns leads without reading
+	// all clients. Meant for a single server per database: changes made by others are caught up with by the checks
+	CapacityIndex = "CAPACITY_INDEX"
+	// CapacityIndexCheck - how often the index is compared with the database and rebuilt when they differ, e.g. 5m
+	// (the default). 0 turns the periodic check off
+	CapacityIndexCheck = "CAPACITY_INDEX_CHECK"
+)
+
+// enableCapacityIndex - loads the capacity index of the storage when CAPACITY_INDEX is "true" and starts checking it
+// until `ctx`, the one of the server, is done
+func enableCapacityIndex(ctx context.Context, s *storage.Storage) error {
+	if os.Getenv(CapacityIndex) != "true" {
+		return nil
+	}
+
+	interval, err := time.ParseDuration(envOr(CapacityIndexCheck, "5m"))
+	if err != nil || interval < 0 {
+		return fmt.Errorf("invalid %s '%s': expected a duration like 5m", CapacityIndexCheck, os.Getenv(CapacityIndexCheck))
+	}
+
+	if err := s.EnableCapacityIndex(ctx); err != nil {
+		return err
+	}
+
+	report, err := s.CheckCapacityIndex(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("capacity index: %d active clients, %d with free capacity, %d groups", report.Clients, report.Available, report.Groups)
+
+	if interval > 0 {
+		go checkCapacityIndex(ctx, s, interval)
+	}
+
+	return nil
+}
+
+// checkCapacityIndex - checks the index every `interval` until `ctx` is done
+func checkCapacityIndex(ctx context.Context, s *storage.Storage, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := s.CheckCapacityIndex(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("capacity index check failed: %v", err)
+			continue
+		}
+		if n := len(report.Differences); n > 0 {
+			// The first differences tell what's going on, all of them may be many
+			shown := report.Differences[:min(n, 10)]
+			log.Printf("capacity index rebuilt, %d differences from the database: %s", n, strings.Join(shown, "; "))
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// command - subcommand of the binary, receives the arguments following its name
+type command func(args []string) error
+
+var commands = map[string]command{
+	"migrate": migrateCommand,
+	"seed":    seedCommand,
+	"backup":  backupCommand,
+	"restore": restoreCommand,
+}
+
+func runCommand(name string, args []string) error {
+	cmd, ok := commands[name]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return fmt.Errorf("unknown command %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+
+	return cmd(args)
+}
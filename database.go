@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Settings of SQLite databases, set on every connection
+const (
+	// SQLiteJournalMode - DELETE, TRUNCATE, PERSIST, MEMORY, WAL or OFF. WAL by default: readers don't wait for the writer
+	SQLiteJournalMode = "SQLITE_JOURNAL_MODE"
+	// SQLiteBusyTimeout - how long a connection waits for another one to finish writing, e.g. 5s (the default)
+	SQLiteBusyTimeout = "SQLITE_BUSY_TIMEOUT"
+	// SQLiteForeignKeys - whether foreign keys are enforced, true by default
+	SQLiteForeignKeys = "SQLITE_FOREIGN_KEYS"
+	// SQLiteSynchronous - OFF, NORMAL, FULL or EXTRA. NORMAL by default, which is durable enough in WAL mode
+	SQLiteSynchronous = "SQLITE_SYNCHRONOUS"
+)
+
+// Connection pool settings of SQL databases. Not set means the database/sql default
+const (
+	// DBMaxOpenConns - limit of open connections, 0 is unlimited
+	DBMaxOpenConns = "DB_MAX_OPEN_CONNS"
+	// DBMaxIdleConns - idle connections kept open
+	DBMaxIdleConns = "DB_MAX_IDLE_CONNS"
+	// DBConnMaxLifetime - connections older than this are closed, e.g. 30m
+	DBConnMaxLifetime = "DB_CONN_MAX_LIFETIME"
+)
+
+var (
+	journalModes      = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+	synchronousLevels = []string{"OFF", "NORMAL", "FULL", "EXTRA"}
+)
+
+// sqliteSettings - pragmas of SQLite connections. The zero value leaves all of them at the SQLite defaults
+type sqliteSettings struct {
+	JournalMode string
+	BusyTimeout *time.Duration
+	ForeignKeys *bool
+	Synchronous string
+	// ImmediateTx - transactions take the write lock when they begin, so concurrent writers wait for busy_timeout
+	// instead of failing when a transaction that has read something starts writing
+	ImmediateTx bool
+}
+
+func sqliteSettingsFromEnv() (sqliteSettings, error) {
+	busyTimeout := 5 * time.Second
+	foreignKeys := true
+	settings := sqliteSettings{
+		JournalMode: envOr(SQLiteJournalMode, "WAL"),
+		BusyTimeout: &busyTimeout,
+		ForeignKeys: &foreignKeys,
+		Synchronous: envOr(SQLiteSynchronous, "NORMAL"),
+		ImmediateTx: true,
+	}
+
+	settings.JournalMode = strings.ToUpper(settings.JournalMode)
+	if !slices.Contains(journalModes, settings.JournalMode) {
+		return settings, fmt.Errorf("invalid %s '%s': expected one of %s", SQLiteJournalMode, settings.JournalMode, strings.Join(journalModes, ", "))
+	}
+
+	settings.Synchronous = strings.ToUpper(settings.Synchronous)
+	if !slices.Contains(synchronousLevels, settings.Synchronous) {
+		return settings, fmt.Errorf("invalid %s '%s': expected one of %s", SQLiteSynchronous, settings.Synchronous, strings.Join(synchronousLevels, ", "))
+	}
+
+	if value := os.Getenv(SQLiteBusyTimeout); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return settings, fmt.Errorf("invalid %s '%s': expected a duration like 5s", SQLiteBusyTimeout, value)
+		}
+		settings.BusyTimeout = &timeout
+	}
+
+	if value := os.Getenv(SQLiteForeignKeys); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return settings, fmt.Errorf("invalid %s '%s': expected true or false", SQLiteForeignKeys, value)
+		}
+		settings.ForeignKeys = &enabled
+	}
+
+	return settings, nil
+}
+
+// dsn - data source name of the go-sqlite3 driver opening the database file at `path` with the settings
+func (s sqliteSettings) dsn(path string) string {
+	params := url.Values{}
+	if s.JournalMode != "" {
+		params.Set("_journal_mode", s.JournalMode)
+	}
+	if s.BusyTimeout != nil {
+		params.Set("_busy_timeout", strconv.FormatInt(s.BusyTimeout.Milliseconds(), 10))
+	}
+	if s.ForeignKeys != nil {
+		params.Set("_foreign_keys", strconv.FormatBool(*s.ForeignKeys))
+	}
+	if s.Synchronous != "" {
+		params.Set("_synchronous", s.Synchronous)
+	}
+	if s.ImmediateTx {
+		params.Set("_txlock", "immediate")
+	}
+
+	if len(params) == 0 {
+		return path
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	return path + separator + params.Encode()
+}
+
+// sqliteReport - the settings a connection of the database actually has
+func sqliteReport(ctx context.Context, db *sql.DB) (string, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return "", fmt.Errorf("can't connect to database: %w", err)
+	}
+	defer conn.Close()
+
+	var journalMode string
+	var busyTimeout, foreignKeys, synchronous int
+	pragmas := []struct {
+		name string
+		dest any
+	}{
+		{"journal_mode", &journalMode},
+		{"busy_timeout", &busyTimeout},
+		{"foreign_keys", &foreignKeys},
+		{"synchronous", &synchronous},
+	}
+	for _, pragma := range pragmas {
+		if err := conn.QueryRowContext(ctx, "PRAGMA "+pragma.name).Scan(pragma.dest); err != nil {
+			return "", fmt.Errorf("failed to read pragma %s: %w", pragma.name, err)
+		}
+	}
+
+	level := strconv.Itoa(synchronous)
+	if synchronous >= 0 && synchronous < len(synchronousLevels) {
+		level = synchronousLevels[synchronous]
+	}
+
+	return fmt.Sprintf(
+		"journal_mode=%s busy_timeout=%s foreign_keys=%t synchronous=%s",
+		strings.ToUpper(journalMode), time.Duration(busyTimeout)*time.Millisecond, foreignKeys == 1, level,
+	), nil
+}
+
+// poolSettings - limits of the database/sql connection pool, nil ones keep the defaults
+type poolSettings struct {
+	MaxOpenConns    *int
+	MaxIdleConns    *int
+	ConnMaxLifetime *time.Duration
+}
+
+func poolSettingsFromEnv() (poolSettings, error) {
+	var settings poolSettings
+
+	for _, setting := range []struct {
+		name string
+		dest **int
+	}{
+		{DBMaxOpenConns, &settings.MaxOpenConns},
+		{DBMaxIdleConns, &settings.MaxIdleConns},
+	} {
+		if value := os.Getenv(setting.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return settings, fmt.Errorf("invalid %s '%s': expected a non-negative number", setting.name, value)
+			}
+			*setting.dest = &n
+		}
+	}
+
+	if value := os.Getenv(DBConnMaxLifetime); value != "" {
+		lifetime, err := time.ParseDuration(value)
+		if err != nil || lifetime < 0 {
+			return settings, fmt.Errorf("invalid %s '%s': expected a duration like 30m", DBConnMaxLifetime, value)
+		}
+		settings.ConnMaxLifetime = &lifetime
+	}
+
+	return settings, nil
+}
+
+func (p poolSettings) apply(db *sql.DB) {
+	if p.MaxOpenConns != nil {
+		db.SetMaxOpenConns(*p.MaxOpenConns)
+	}
+	if p.MaxIdleConns != nil {
+		db.SetMaxIdleConns(*p.MaxIdleConns)
+	}
+	if p.ConnMaxLifetime != nil {
+		db.SetConnMaxLifetime(*p.ConnMaxLifetime)
+	}
+}
+
+// poolReport - the pool limits in effect
+func poolReport(db *sql.DB, p poolSettings) string {
+	maxOpen := "unlimited"
+	if n := db.Stats().MaxOpenConnections; n > 0 {
+		maxOpen = strconv.Itoa(n)
+	}
+
+	// database/sql doesn't tell the idle limit: 2 by default, never above the open one
+	maxIdle := 2
+	if p.MaxIdleConns != nil {
+		maxIdle = max(*p.MaxIdleConns, 0)
+	}
+	if n := db.Stats().MaxOpenConnections; n > 0 {
+		maxIdle = min(maxIdle, n)
+	}
+
+	lifetime := "unlimited"
+	if p.ConnMaxLifetime != nil && *p.ConnMaxLifetime > 0 {
+		lifetime = p.ConnMaxLifetime.String()
+	}
+
+	return fmt.Sprintf("max_open_conns=%s max_idle_conns=%d conn_max_lifetime=%s", maxOpen, maxIdle, lifetime)
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return fallback
+}
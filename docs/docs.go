@@ -15,23 +15,221 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/backup": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Writes a consistent snapshot of the SQLite database to a new file of the backup directory (BACKUP_DIR)\nwith the online backup API, while requests keep being served. Copying the database file instead may\nproduce a corrupt backup. Restore it with the ` + "`" + `restore` + "`" + ` command.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Makes a backup of the database",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/storage.BackupInfo"
+                        }
+                    },
+                    "401": {
+                        "description": "The admin token is missing or wrong",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "ADMIN_TOKEN is not set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "The database is not SQLite",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/capacity-index/check": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "The capacity index (CAPACITY_INDEX=true) keeps the usage of the clients in the server process, so\nassignments don't read all clients. The check reads the clients and groups from the database, reports\nwhat the index had wrong and rebuilds it when anything was. The server runs the check every\nCAPACITY_INDEX_CHECK as well.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Compares the capacity index with the database",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.CapacityIndexReport"
+                        }
+                    },
+                    "401": {
+                        "description": "The admin token is missing or wrong",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "ADMIN_TOKEN is not set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "The capacity index is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/clients": {
             "get": {
+                "description": "Clients are filtered, sorted and paginated by the database. Pass ` + "`" + `meta.next_cursor` + "`" + ` of the response as ` + "`" + `cursor` + "`" + `\n(together with the same ` + "`" + `sort` + "`" + `) to receive the next page.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "client"
                 ],
-                "summary": "Receives a list of clients",
+                "summary": "Receives a page of clients",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "HIGH",
+                                "MEDIUM",
+                                "LOW"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Priorities of clients",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client's time frame starts not later than this date",
+                        "name": "active_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client's time frame ends not earlier than this date",
+                        "name": "active_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Client still can receive leads",
+                        "name": "has_capacity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Beginning of the client's name",
+                        "name": "name_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metadata value, e.g. metadata[crm.id]=42. Can be repeated for several keys",
+                        "name": "metadata[key]",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "name",
+                            "-name",
+                            "priority",
+                            "-priority",
+                            "start_date",
+                            "-start_date",
+                            "end_date",
+                            "-end_date",
+                            "lead_capacity",
+                            "-lead_capacity",
+                            "free_capacity",
+                            "-free_capacity"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Sort field, prefix with '-' for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the next page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 500,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/storage.Client"
-                            }
+                            "$ref": "#/definitions/storage.ClientsPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
                         }
                     },
                     "500": {
@@ -62,10 +260,28 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/storage.Client"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created client"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
                         "schema": {
-                            "type": "bool"
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
                         }
                     },
                     "500": {
@@ -105,6 +321,90 @@ const docTemplate = `{
                             "$ref": "#/definitions/storage.Lead"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/clients/import": {
+            "post": {
+                "description": "The file is sent either as the request body or as the ` + "`" + `file` + "`" + ` field of a multipart form.\nFormat is taken from ` + "`" + `format` + "`" + ` query parameter or from the Content-Type (` + "`" + `text/csv` + "`" + `, ` + "`" + `application/x-ndjson` + "`" + `).\nCSV must have a header with columns name, start_date, end_date, priority, lead_capacity and optional metadata (JSON object).\nEvery row is validated and all valid rows are imported in a single transaction.\nIn ` + "`" + `all_or_nothing` + "`" + ` mode a single invalid row cancels the import, in ` + "`" + `skip_invalid` + "`" + ` mode invalid rows are only reported.",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client"
+                ],
+                "summary": "Creates clients from a CSV or NDJSON file",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "all_or_nothing",
+                            "skip_invalid"
+                        ],
+                        "type": "string",
+                        "default": "all_or_nothing",
+                        "description": "Import mode",
+                        "name": "mode",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -116,7 +416,7 @@ const docTemplate = `{
         },
         "/clients/{id}": {
             "get": {
-                "description": "Returns a single client array with the found user or a string with an error in case user is not found",
+                "description": "Returns a single client array with the found user or a string with an error in case user is not found.\nWith ` + "`" + `as_of` + "`" + ` returns the client as it was at that moment (RFC 3339 or ` + "`" + `YYYY-MM-DD HH:MM:SS` + "`" + ` in UTC),\narchived or not, with the number of leads assigned to it then (see storage.ClientAsOf).",
                 "produces": [
                     "application/json"
                 ],
@@ -131,6 +431,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Point in time",
+                        "name": "as_of",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -143,10 +449,16 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
@@ -156,38 +468,1336 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "handlers.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                }
-            }
-        },
-        "storage.AssignLeadRequest": {
-            "type": "object",
-            "properties": {
-                "lead_end": {
-                    "type": "string"
-                },
-                "lead_start": {
-                    "type": "string"
+            },
+            "delete": {
+                "description": "Soft-archives the client: it is hidden from the clients list and no longer receives leads.\nQuery parameter ` + "`" + `leads` + "`" + ` decides what happens to its leads: ` + "`" + `keep` + "`" + ` leaves them with the archived client,\n` + "`" + `reassign` + "`" + ` passes them through the assignment engine again (leads that fit no one go to the pending queue),\n` + "`" + `pending` + "`" + ` moves them to the pending queue.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client"
+                ],
+                "summary": "Archives client by clientID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "keep",
+                            "reassign",
+                            "pending"
+                        ],
+                        "type": "string",
+                        "default": "keep",
+                        "description": "Leads policy",
+                        "name": "leads",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ArchiveResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "storage.Client": {
-            "type": "object",
-            "properties": {
-                "end_date": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
+        "/clients/{id}/history": {
+            "get": {
+                "description": "Every change of a client (create, update, archive, restore) is a new version with the values before and after the change.\n` + "`" + `changed_by` + "`" + ` is taken from the ` + "`" + `X-Actor` + "`" + ` header of the request that made the change.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client"
+                ],
+                "summary": "Receives all versions of the client",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/storage.ClientChange"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/clients/{id}/leads": {
+            "get": {
+                "description": "Leads are ordered by their start date. Pass ` + "`" + `meta.next_cursor` + "`" + ` of the response as ` + "`" + `cursor` + "`" + ` to receive the next page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client"
+                ],
+                "summary": "Receives a page of client's leads",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the next page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 500,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.LeadsPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/clients/{id}/metadata": {
+            "put": {
+                "description": "Metadata is an arbitrary JSON object. When a schema for client metadata is set, metadata must match it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client"
+                ],
+                "summary": "Replaces metadata of the client",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Metadata",
+                        "name": "_",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.Client"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/clients/{id}/restore": {
+            "post": {
+                "description": "Makes the client visible and available for assignment again. Leads that were reassigned or moved to the pending queue are not returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client"
+                ],
+                "summary": "Restores an archived client",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.Client"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/export/clients": {
+            "get": {
+                "description": "Rows are streamed from the database as they are read. Format is taken from ` + "`" + `format` + "`" + ` query parameter\nor from the Accept header (` + "`" + `text/csv` + "`" + `, ` + "`" + `application/x-ndjson` + "`" + `, ` + "`" + `application/vnd.openxmlformats-officedocument.spreadsheetml.sheet` + "`" + `), CSV by default.\nAccepts the same filters and sort as GET /clients.\nCSV and XLSX text starting with ` + "`" + `=` + "`" + `, ` + "`" + `+` + "`" + `, ` + "`" + `-` + "`" + ` or ` + "`" + `@` + "`" + ` is prefixed with ` + "`" + `'` + "`" + `, so spreadsheets don't run it as a formula.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "export"
+                ],
+                "summary": "Streams clients as a file",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "HIGH",
+                                "MEDIUM",
+                                "LOW"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Priorities of clients",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client's time frame starts not later than this date",
+                        "name": "active_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client's time frame ends not earlier than this date",
+                        "name": "active_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Client still can receive leads",
+                        "name": "has_capacity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Beginning of the client's name",
+                        "name": "name_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metadata value, e.g. metadata[crm.id]=42. Can be repeated for several keys",
+                        "name": "metadata[key]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "id",
+                        "description": "Sort field, prefix with '-' for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/export/leads": {
+            "get": {
+                "description": "Rows are streamed from the database as they are read. Format is taken from ` + "`" + `format` + "`" + ` query parameter\nor from the Accept header (` + "`" + `text/csv` + "`" + `, ` + "`" + `application/x-ndjson` + "`" + `, ` + "`" + `application/vnd.openxmlformats-officedocument.spreadsheetml.sheet` + "`" + `), CSV by default.\nAccepts the same filters as GET /leads.\nCSV and XLSX text starting with ` + "`" + `=` + "`" + `, ` + "`" + `+` + "`" + `, ` + "`" + `-` + "`" + ` or ` + "`" + `@` + "`" + ` is prefixed with ` + "`" + `'` + "`" + `, so spreadsheets don't run it as a formula.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "export"
+                ],
+                "summary": "Streams leads as a file",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Client ID",
+                        "name": "client_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "ASSIGNED",
+                            "PENDING"
+                        ],
+                        "type": "string",
+                        "description": "Lead status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Lead starts not earlier than this date",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Lead ends not later than this date",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metadata value, e.g. metadata[source]=web. Can be repeated for several keys",
+                        "name": "metadata[key]",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "group"
+                ],
+                "summary": "Receives a list of client groups",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/storage.ClientGroup"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Group has its own capacity pool shared by all members. A lead is assigned to a member only when both\nthe member and its group have free capacity.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "group"
+                ],
+                "summary": "Creates a new client group",
+                "parameters": [
+                    {
+                        "description": "New group payload",
+                        "name": "_",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/storage.ClientGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ClientGroup"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created group"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "group"
+                ],
+                "summary": "Get client group by groupID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ClientGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "group"
+                ],
+                "summary": "Updates name and capacity of the client group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Group payload",
+                        "name": "_",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/storage.ClientGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ClientGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Members of the group become standalone clients and keep their leads.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "group"
+                ],
+                "summary": "Deletes the client group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{id}/members": {
+            "post": {
+                "description": "A client belongs to one group at most, so it leaves its previous group.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "group"
+                ],
+                "summary": "Adds a client to the group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Member payload",
+                        "name": "_",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/storage.GroupMemberRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ClientGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{id}/members/{client_id}": {
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "group"
+                ],
+                "summary": "Removes a client from the group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client ID",
+                        "name": "client_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ClientGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/leads": {
+            "get": {
+                "description": "Leads are ordered by their start date. Pass ` + "`" + `meta.next_cursor` + "`" + ` of the response as ` + "`" + `cursor` + "`" + ` to receive the next page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lead"
+                ],
+                "summary": "Receives a page of leads",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Client ID",
+                        "name": "client_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "ASSIGNED",
+                            "PENDING"
+                        ],
+                        "type": "string",
+                        "description": "Lead status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Lead starts not earlier than this date",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Lead ends not later than this date",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metadata value, e.g. metadata[source]=web. Can be repeated for several keys",
+                        "name": "metadata[key]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the next page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 500,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.LeadsPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/leads/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lead"
+                ],
+                "summary": "Get lead by leadID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.Lead"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/leads/{id}/metadata": {
+            "put": {
+                "description": "Metadata is an arbitrary JSON object. When a schema for lead metadata is set, metadata must match it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lead"
+                ],
+                "summary": "Replaces metadata of the lead",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Metadata",
+                        "name": "_",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.Lead"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/metadata/schemas/{entity}": {
+            "get": {
+                "description": "Every tenant has its own schemas, the tenant is taken from the ` + "`" + `X-Tenant` + "`" + ` header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metadata"
+                ],
+                "summary": "Receives JSON Schema of clients or leads metadata",
+                "parameters": [
+                    {
+                        "enum": [
+                            "client",
+                            "lead"
+                        ],
+                        "type": "string",
+                        "description": "Entity",
+                        "name": "entity",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Tenant",
+                        "name": "X-Tenant",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.MetadataSchema"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Metadata of every client (lead) created or updated by requests of the tenant of the ` + "`" + `X-Tenant` + "`" + ` header must match the schema. Metadata saved before is not validated again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metadata"
+                ],
+                "summary": "Sets JSON Schema of clients or leads metadata",
+                "parameters": [
+                    {
+                        "enum": [
+                            "client",
+                            "lead"
+                        ],
+                        "type": "string",
+                        "description": "Entity",
+                        "name": "entity",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Tenant",
+                        "name": "X-Tenant",
+                        "in": "header"
+                    },
+                    {
+                        "description": "JSON Schema",
+                        "name": "_",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.MetadataSchema"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes the schema of the tenant of the ` + "`" + `X-Tenant` + "`" + ` header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metadata"
+                ],
+                "summary": "Removes JSON Schema of clients or leads metadata",
+                "parameters": [
+                    {
+                        "enum": [
+                            "client",
+                            "lead"
+                        ],
+                        "type": "string",
+                        "description": "Entity",
+                        "name": "entity",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Tenant",
+                        "name": "X-Tenant",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/clients": {
+            "get": {
+                "description": "Returns used and available capacity of every active client, percentage of free capacity\n(the same metric the assignment engine ranks clients by), leads per day of the elapsed part of the time frame,\ntime until the time frame ends, and totals per priority.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Receives capacity utilization of clients",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ClientsStats"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Stable machine-readable reason, see the Code* constants",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/storage.ErrorCode"
+                        }
+                    ]
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ValidationErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "$ref": "#/definitions/storage.ErrorCode"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.FieldError"
+                    }
+                }
+            }
+        },
+        "storage.ArchiveResult": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "type": "integer"
+                },
+                "leads": {
+                    "description": "Former leads of the client after the policy was applied",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.Lead"
+                    }
+                },
+                "leads_policy": {
+                    "$ref": "#/definitions/storage.LeadsPolicy"
+                }
+            }
+        },
+        "storage.AssignLeadRequest": {
+            "type": "object",
+            "required": [
+                "lead_end",
+                "lead_start"
+            ],
+            "properties": {
+                "lead_end": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "lead_start": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "metadata": {
+                    "type": "object"
+                }
+            }
+        },
+        "storage.BackupInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "schema_version": {
+                    "description": "SchemaVersion - version of the latest migration applied to the backup, 0 when there is none",
+                    "type": "integer"
+                },
+                "size": {
+                    "description": "Size - in bytes",
+                    "type": "integer"
+                }
+            }
+        },
+        "storage.CapacityIndexReport": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "description": "Available - clients with free capacity",
+                    "type": "integer"
+                },
+                "checked_at": {
+                    "type": "string"
+                },
+                "clients": {
+                    "description": "Clients - active clients in the database",
+                    "type": "integer"
+                },
+                "differences": {
+                    "description": "Differences - what the index had wrong, it's rebuilt from the database when there are any",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "groups": {
+                    "type": "integer"
+                }
+            }
+        },
+        "storage.Client": {
+            "type": "object",
+            "properties": {
+                "end_date": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "description": "Client group sharing its capacity pool with the client, if any",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
                 "lead_capacity": {
                     "type": "integer"
                 },
@@ -197,6 +1807,9 @@ const docTemplate = `{
                         "$ref": "#/definitions/storage.Lead"
                     }
                 },
+                "metadata": {
+                    "type": "object"
+                },
                 "name": {
                     "type": "string"
                 },
@@ -213,15 +1826,112 @@ const docTemplate = `{
                 }
             }
         },
+        "storage.ClientChange": {
+            "type": "object",
+            "properties": {
+                "changed_at": {
+                    "type": "string"
+                },
+                "changed_by": {
+                    "type": "string"
+                },
+                "new_values": {
+                    "type": "object"
+                },
+                "old_values": {
+                    "type": "object"
+                },
+                "operation": {
+                    "$ref": "#/definitions/storage.ClientOperation"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "storage.ClientGroup": {
+            "type": "object",
+            "properties": {
+                "available_capacity": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lead_capacity": {
+                    "type": "integer"
+                },
+                "members": {
+                    "description": "IDs of member clients",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "used_capacity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "storage.ClientGroupRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "lead_capacity": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "storage.ClientOperation": {
+            "type": "string",
+            "enum": [
+                "create",
+                "update",
+                "archive",
+                "restore",
+                "snapshot"
+            ],
+            "x-enum-comments": {
+                "ClientSnapshot": "State of a client that existed before the history was kept"
+            },
+            "x-enum-varnames": [
+                "ClientCreated",
+                "ClientUpdated",
+                "ClientArchived",
+                "ClientRestored",
+                "ClientSnapshot"
+            ]
+        },
         "storage.ClientRequest": {
             "type": "object",
+            "required": [
+                "end_date",
+                "name",
+                "priority",
+                "start_date"
+            ],
             "properties": {
                 "end_date": {
-                    "type": "string"
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "group_id": {
+                    "type": "integer"
                 },
                 "lead_capacity": {
                     "type": "integer"
                 },
+                "metadata": {
+                    "type": "object"
+                },
                 "name": {
                     "type": "string"
                 },
@@ -234,10 +1944,215 @@ const docTemplate = `{
                     ]
                 },
                 "start_date": {
+                    "type": "string",
+                    "format": "date-time"
+                }
+            }
+        },
+        "storage.ClientStats": {
+            "type": "object",
+            "properties": {
+                "available_capacity": {
+                    "type": "integer"
+                },
+                "client_id": {
+                    "type": "integer"
+                },
+                "free_percentage": {
+                    "description": "Same metric the assignment engine ranks clients by",
+                    "type": "integer"
+                },
+                "lead_capacity": {
+                    "type": "integer"
+                },
+                "leads_per_day": {
+                    "description": "Assigned leads per day of the elapsed part of the time frame",
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "string"
+                },
+                "used_capacity": {
+                    "type": "integer"
+                },
+                "window_ends_in_seconds": {
+                    "description": "0 when the time frame has already ended",
+                    "type": "integer"
+                }
+            }
+        },
+        "storage.ClientsPage": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.Client"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/storage.PageMeta"
+                }
+            }
+        },
+        "storage.ClientsStats": {
+            "type": "object",
+            "properties": {
+                "clients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.ClientStats"
+                    }
+                },
+                "generated_at": {
+                    "type": "string"
+                },
+                "priorities": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/storage.PriorityStats"
+                    }
+                }
+            }
+        },
+        "storage.ErrorCode": {
+            "type": "string",
+            "enum": [
+                "client_not_found",
+                "lead_not_found",
+                "group_not_found",
+                "metadata_schema_not_found",
+                "client_archived",
+                "no_clients_available",
+                "invalid_leads_policy",
+                "invalid_import_mode",
+                "invalid_sort",
+                "invalid_cursor",
+                "invalid_metadata",
+                "invalid_metadata_key",
+                "invalid_metadata_schema",
+                "unknown_metadata_entity",
+                "unknown_group",
+                "backup_unsupported",
+                "backup_exists",
+                "backup_not_found",
+                "invalid_backup",
+                "incompatible_backup",
+                "capacity_index_disabled"
+            ],
+            "x-enum-varnames": [
+                "CodeClientNotFound",
+                "CodeLeadNotFound",
+                "CodeGroupNotFound",
+                "CodeMetadataSchemaNotFound",
+                "CodeClientArchived",
+                "CodeNoClientsAvailable",
+                "CodeInvalidLeadsPolicy",
+                "CodeInvalidImportMode",
+                "CodeInvalidSort",
+                "CodeInvalidCursor",
+                "CodeInvalidMetadata",
+                "CodeInvalidMetadataKey",
+                "CodeInvalidMetadataSchema",
+                "CodeUnknownMetadataEntity",
+                "CodeUnknownGroup",
+                "CodeBackupUnsupported",
+                "CodeBackupExists",
+                "CodeBackupNotFound",
+                "CodeInvalidBackup",
+                "CodeIncompatibleBackup",
+                "CodeCapacityIndexDisabled"
+            ]
+        },
+        "storage.GroupMemberRequest": {
+            "type": "object",
+            "required": [
+                "client_id"
+            ],
+            "properties": {
+                "client_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "storage.ImportMode": {
+            "type": "string",
+            "enum": [
+                "all_or_nothing",
+                "skip_invalid"
+            ],
+            "x-enum-comments": {
+                "ImportAllOrNothing": "Nothing is imported when at least one row is invalid",
+                "ImportSkipInvalid": "Valid rows are imported, invalid ones are reported"
+            },
+            "x-enum-varnames": [
+                "ImportAllOrNothing",
+                "ImportSkipInvalid"
+            ]
+        },
+        "storage.ImportReport": {
+            "type": "object",
+            "properties": {
+                "committed": {
+                    "type": "boolean"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "$ref": "#/definitions/storage.ImportMode"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.ImportRow"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "storage.ImportRow": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "type": "integer"
+                },
+                "error": {
                     "type": "string"
+                },
+                "row": {
+                    "description": "Line number in the uploaded file",
+                    "type": "integer"
+                },
+                "status": {
+                    "$ref": "#/definitions/storage.ImportRowStatus"
                 }
             }
         },
+        "storage.ImportRowStatus": {
+            "type": "string",
+            "enum": [
+                "created",
+                "invalid",
+                "rejected"
+            ],
+            "x-enum-comments": {
+                "ImportRowRejected": "Valid row that was not imported because of other invalid rows"
+            },
+            "x-enum-varnames": [
+                "ImportRowCreated",
+                "ImportRowInvalid",
+                "ImportRowRejected"
+            ]
+        },
         "storage.Lead": {
             "type": "object",
             "properties": {
@@ -252,9 +2167,131 @@ const docTemplate = `{
                 },
                 "lead_start": {
                     "type": "string"
+                },
+                "metadata": {
+                    "type": "object"
+                },
+                "status": {
+                    "$ref": "#/definitions/storage.LeadStatus"
+                }
+            }
+        },
+        "storage.LeadStatus": {
+            "type": "string",
+            "enum": [
+                "ASSIGNED",
+                "PENDING"
+            ],
+            "x-enum-comments": {
+                "LeadStatusPending": "Lead waits in the pending queue without a client"
+            },
+            "x-enum-varnames": [
+                "LeadStatusAssigned",
+                "LeadStatusPending"
+            ]
+        },
+        "storage.LeadsPage": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.Lead"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/storage.PageMeta"
+                }
+            }
+        },
+        "storage.LeadsPolicy": {
+            "type": "string",
+            "enum": [
+                "keep",
+                "reassign",
+                "pending"
+            ],
+            "x-enum-comments": {
+                "LeadsKeep": "Leads stay with the archived client",
+                "LeadsPending": "Leads are moved to the pending queue",
+                "LeadsReassign": "Leads go through the assignment engine again"
+            },
+            "x-enum-varnames": [
+                "LeadsKeep",
+                "LeadsReassign",
+                "LeadsPending"
+            ]
+        },
+        "storage.MetadataEntity": {
+            "type": "string",
+            "enum": [
+                "client",
+                "lead"
+            ],
+            "x-enum-varnames": [
+                "MetadataClient",
+                "MetadataLead"
+            ]
+        },
+        "storage.MetadataSchema": {
+            "type": "object",
+            "properties": {
+                "entity": {
+                    "$ref": "#/definitions/storage.MetadataEntity"
+                },
+                "schema": {
+                    "type": "object"
+                },
+                "tenant": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "storage.PageMeta": {
+            "type": "object",
+            "properties": {
+                "has_more": {
+                    "type": "boolean"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "storage.PriorityStats": {
+            "type": "object",
+            "properties": {
+                "available_capacity": {
+                    "type": "integer"
+                },
+                "clients": {
+                    "type": "integer"
+                },
+                "free_percentage": {
+                    "type": "integer"
+                },
+                "lead_capacity": {
+                    "type": "integer"
+                },
+                "used_capacity": {
+                    "type": "integer"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "` + "`" + `Bearer \u003cADMIN_TOKEN\u003e` + "`" + `, required by the /admin endpoints",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
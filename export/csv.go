@@ -0,0 +1,37 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	c := &csvWriter{
+		w:      csv.NewWriter(w),
+		record: make([]string, len(columns)),
+	}
+
+	if err := c.w.Write(columns); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *csvWriter) WriteRow(values ...any) error {
+	for i, value := range values {
+		c.record[i] = cellText(value)
+	}
+
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
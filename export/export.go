@@ -0,0 +1,101 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+)
+
+type Format = string
+
+const (
+	CSV    Format = "csv"
+	NDJSON Format = "ndjson"
+	XLSX   Format = "xlsx"
+)
+
+// formulaPrefixes - first characters that make spreadsheet applications read a text cell as a formula
+const formulaPrefixes = "=+-@"
+
+var contentTypes = map[Format]string{
+	CSV:    "text/csv",
+	NDJSON: "application/x-ndjson",
+	XLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Writer - writes rows of a table to the output as they come, without keeping them in memory
+type Writer interface {
+	// WriteRow - writes values in the order of columns. Nil values are written as empty cells,
+	// json.RawMessage values as JSON objects in NDJSON and as JSON text elsewhere. CSV and XLSX text starting with
+	// = + - or @ is prefixed with ' to keep spreadsheets from running it as a formula
+	WriteRow(values ...any) error
+	// Close - finishes the document. Must be called even when no rows were written
+	Close() error
+}
+
+// New - creates a writer of the table with given columns
+func New(format Format, w io.Writer, columns []string) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w, columns)
+	case NDJSON:
+		return newNDJSONWriter(w, columns), nil
+	case XLSX:
+		return newXLSXWriter(w, columns)
+	}
+
+	return nil, fmt.Errorf("unsupported export format '%s'", format)
+}
+
+func ContentType(format Format) string {
+	return contentTypes[format]
+}
+
+// FromAccept - picks the first supported format of the Accept header. Returns empty string when there is none
+func FromAccept(accept string) Format {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		for format, contentType := range contentTypes {
+			if mediaType == contentType {
+				return format
+			}
+		}
+		if mediaType == "application/ndjson" {
+			return NDJSON
+		}
+	}
+
+	return ""
+}
+
+// text - value as a cell text
+func text(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case json.RawMessage:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// cellText - value as a cell text of a spreadsheet. Strings that would be read as a formula are prefixed with `'`,
+// so names like =HYPERLINK(...) stay text. Numbers, dates and JSON never need it
+func cellText(value any) string {
+	s, ok := value.(string)
+	if ok && s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+
+	return text(value)
+}
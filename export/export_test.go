@@ -0,0 +1,135 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var columns = []string{"id", "name", "start_date", "metadata"}
+
+// rows - a plain row and a row of text spreadsheets would read as formulas
+var rows = [][]any{
+	{1, "acme", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), json.RawMessage(`{"crm_id":"a"}`)},
+	{-2, `=HYPERLINK("http://evil","x")`, nil, "@SUM(A1)"},
+}
+
+func TestCSV(t *testing.T) {
+	got := export(t, CSV)
+
+	want := "id,name,start_date,metadata\n" +
+		`1,acme,2024-01-01T00:00:00Z,"{""crm_id"":""a""}"` + "\n" +
+		`-2,"'=HYPERLINK(""http://evil"",""x"")",,'@SUM(A1)` + "\n"
+	if got = strings.ReplaceAll(got, "\r\n", "\n"); got != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	got := export(t, NDJSON)
+
+	// NDJSON is read by programs, values stay as they are
+	want := `{"id":1,"name":"acme","start_date":"2024-01-01T00:00:00Z","metadata":{"crm_id":"a"}}` + "\n" +
+		`{"id":-2,"name":"=HYPERLINK(\"http://evil\",\"x\")","start_date":null,"metadata":"@SUM(A1)"}` + "\n"
+	if got != want {
+		t.Errorf("NDJSON =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	got := export(t, XLSX)
+
+	archive, err := zip.NewReader(strings.NewReader(got), int64(len(got)))
+	if err != nil {
+		t.Fatalf("the file is not a zip archive: %v", err)
+	}
+	sheet, err := archive.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatalf("the file has no sheet: %v", err)
+	}
+	defer sheet.Close()
+	content, err := io.ReadAll(sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cell := range []string{
+		`<c><v>-2</v></c>`,
+		`<t xml:space="preserve">&#39;=HYPERLINK(&#34;http://evil&#34;,&#34;x&#34;)</t>`,
+		`<t xml:space="preserve">&#39;@SUM(A1)</t>`,
+		`<t xml:space="preserve">acme</t>`,
+	} {
+		if !strings.Contains(string(content), cell) {
+			t.Errorf("sheet has no cell %s:\n%s", cell, content)
+		}
+	}
+}
+
+func TestCellText(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{"acme", "acme"},
+		{"", ""},
+		{"=1+1", "'=1+1"},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"a=b", "a=b"},
+		{-1, "-1"},
+		{json.RawMessage(`-1`), "-1"},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := cellText(tt.value); got != tt.want {
+			t.Errorf("cellText(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFromAccept(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Format
+	}{
+		{"text/csv", CSV},
+		{"application/json, application/x-ndjson;q=0.9", NDJSON},
+		{"application/ndjson", NDJSON},
+		{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", XLSX},
+		{"application/json", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := FromAccept(tt.accept); got != tt.want {
+			t.Errorf("FromAccept(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+// export - writes the rows in the format
+func export(t *testing.T, format Format) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	w, err := New(format, &out, columns)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row...); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	return out.String()
+}
@@ -0,0 +1,48 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	columns []string
+}
+
+func newNDJSONWriter(w io.Writer, columns []string) *ndjsonWriter {
+	return &ndjsonWriter{
+		w:       bufio.NewWriter(w),
+		columns: columns,
+	}
+}
+
+// WriteRow - writes the row as a JSON object with keys in the order of columns
+func (n *ndjsonWriter) WriteRow(values ...any) error {
+	n.w.WriteByte('{')
+
+	for i, value := range values {
+		if i > 0 {
+			n.w.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(n.columns[i])
+		n.w.Write(key)
+		n.w.WriteByte(':')
+
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		n.w.Write(raw)
+	}
+
+	n.w.WriteString("}\n")
+
+	return nil
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"leads/storage"
+)
+
+type AdminHandlers struct {
+	*BasicHandler
+	backups   storage.BackupRepository
+	capacity  storage.CapacityIndexRepository
+	backupDir string
+	token     string
+}
+
+// NewAdminHandlers - backups are written to `backupDir`. Requests need the bearer token `token`, all of them are refused
+// when it's empty
+func NewAdminHandlers(backups storage.BackupRepository, capacity storage.CapacityIndexRepository, backupDir string, token string) *AdminHandlers {
+	return &AdminHandlers{
+		backups:   backups,
+		capacity:  capacity,
+		backupDir: backupDir,
+		token:     token,
+	}
+}
+
+func (h *AdminHandlers) InstallRoutes(r gin.IRouter) {
+	a := r.Group("/admin", h.authorize)
+
+	a.POST("/backup", h.CreateBackup)
+	a.POST("/capacity-index/check", h.CheckCapacityIndex)
+}
+
+// authorize - lets through requests with the admin token in the `Authorization: Bearer` header
+func (h *AdminHandlers) authorize(c *gin.Context) {
+	if h.token == "" {
+		h.sendErrorResponse(c, http.StatusForbidden, CodeAdminDisabled, "admin endpoints are disabled, set ADMIN_TOKEN to enable them", nil)
+		c.Abort()
+		return
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+		h.sendErrorResponse(c, http.StatusUnauthorized, CodeUnauthorized, "admin endpoints need the admin token as a bearer token", nil)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// CreateBackup makes a backup of the database
+//
+// @Summary Makes a backup of the database
+// @Description Writes a consistent snapshot of the SQLite database to a new file of the backup directory (BACKUP_DIR)
+// @Description with the online backup API, while requests keep being served. Copying the database file instead may
+// @Description produce a corrupt backup. Restore it with the `restore` command.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Failure	401	{object} ErrorResponse "The admin token is missing or wrong"
+// @Failure	403	{object} ErrorResponse "ADMIN_TOKEN is not set"
+// @Failure	409	{object} ErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Failure	501	{object} ErrorResponse "The database is not SQLite"
+// @Success 201 {object} storage.BackupInfo
+// @Router /admin/backup [post]
+func (h *AdminHandlers) CreateBackup(c *gin.Context) {
+	info, err := h.backups.Backup(c, storage.BackupPath(h.backupDir))
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, info)
+}
+
+// CheckCapacityIndex compares the capacity index with the database
+//
+// @Summary Compares the capacity index with the database
+// @Description The capacity index (CAPACITY_INDEX=true) keeps the usage of the clients in the server process, so
+// @Description assignments don't read all clients. The check reads the clients and groups from the database, reports
+// @Description what the index had wrong and rebuilds it when anything was. The server runs the check every
+// @Description CAPACITY_INDEX_CHECK as well.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Failure	401	{object} ErrorResponse "The admin token is missing or wrong"
+// @Failure	403	{object} ErrorResponse "ADMIN_TOKEN is not set"
+// @Failure	500	{object} ErrorResponse
+// @Failure	501	{object} ErrorResponse "The capacity index is disabled"
+// @Success 200 {object} storage.CapacityIndexReport
+// @Router /admin/capacity-index/check [post]
+func (h *AdminHandlers) CheckCapacityIndex(c *gin.Context) {
+	report, err := h.capacity.CheckCapacityIndex(c)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	h.sendOk(c, report)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"leads/storage"
+	"leads/storage/storagefake"
+)
+
+const adminToken = "s3cret"
+
+// sendAdmin - POST request to the admin endpoint `target` with the admin token
+func sendAdmin(t *testing.T, r http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := request(http.MethodPost, target, "")
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+
+	return serve(t, r, req)
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	backups := &storagefake.BackupRepository{
+		BackupFunc: func(ctx context.Context, path string) (*storage.BackupInfo, error) {
+			return &storage.BackupInfo{Path: path}, nil
+		},
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		status        int
+		code          storage.ErrorCode
+	}{
+		{name: "no header", token: adminToken, status: http.StatusUnauthorized, code: CodeUnauthorized},
+		{name: "wrong token", token: adminToken, authorization: "Bearer guess", status: http.StatusUnauthorized, code: CodeUnauthorized},
+		{name: "token of another scheme", token: adminToken, authorization: "Basic " + adminToken, status: http.StatusUnauthorized, code: CodeUnauthorized},
+		{name: "token prefix", token: adminToken, authorization: "Bearer " + adminToken[:3], status: http.StatusUnauthorized, code: CodeUnauthorized},
+		{name: "admin disabled", authorization: "Bearer ", status: http.StatusForbidden, code: CodeAdminDisabled},
+		{name: "right token", token: adminToken, authorization: "Bearer " + adminToken, status: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRouter(NewAdminHandlers(backups, nil, t.TempDir(), tt.token))
+			req := request(http.MethodPost, "/admin/backup", "")
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := serve(t, r, req)
+
+			if tt.code == "" {
+				assertStatus(t, w, tt.status)
+				return
+			}
+			assertError(t, w, tt.status, tt.code)
+			if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("WWW-Authenticate header is missing")
+			}
+		})
+	}
+}
+
+func TestCreateBackup(t *testing.T) {
+	dir := t.TempDir()
+	backups := &storagefake.BackupRepository{
+		BackupFunc: func(ctx context.Context, path string) (*storage.BackupInfo, error) {
+			if filepath.Dir(path) != dir || !strings.HasPrefix(filepath.Base(path), "leads-") {
+				t.Errorf("backup path = %s, want a new file of %s", path, dir)
+			}
+			return &storage.BackupInfo{Path: path, Size: 4096, SchemaVersion: 20240715000000}, nil
+		},
+	}
+	r := newRouter(NewAdminHandlers(backups, nil, dir, adminToken))
+
+	w := sendAdmin(t, r, "/admin/backup")
+
+	assertStatus(t, w, http.StatusCreated)
+	if got := decode[storage.BackupInfo](t, w); got.Size != 4096 || got.SchemaVersion != 20240715000000 {
+		t.Errorf("backup = %+v", got)
+	}
+}
+
+func TestCreateBackupErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   storage.ErrorCode
+	}{
+		{name: "not SQLite", err: storageError(storage.ErrUnsupported, storage.CodeBackupUnsupported), status: http.StatusNotImplemented, code: storage.CodeBackupUnsupported},
+		{name: "exists", err: storageError(storage.ErrConflict, storage.CodeBackupExists), status: http.StatusConflict, code: storage.CodeBackupExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backups := &storagefake.BackupRepository{
+				BackupFunc: func(ctx context.Context, path string) (*storage.BackupInfo, error) {
+					return nil, tt.err
+				},
+			}
+
+			w := sendAdmin(t, newRouter(NewAdminHandlers(backups, nil, t.TempDir(), adminToken)), "/admin/backup")
+
+			assertError(t, w, tt.status, tt.code)
+		})
+	}
+}
+
+func TestCheckCapacityIndex(t *testing.T) {
+	enabled := true
+	capacity := &storagefake.CapacityIndexRepository{
+		CheckCapacityIndexFunc: func(ctx context.Context) (*storage.CapacityIndexReport, error) {
+			if !enabled {
+				return nil, storageError(storage.ErrUnsupported, storage.CodeCapacityIndexDisabled)
+			}
+			return &storage.CapacityIndexReport{Clients: 3, Available: 2, Differences: []string{"client 1 has changed"}}, nil
+		},
+	}
+	r := newRouter(NewAdminHandlers(nil, capacity, t.TempDir(), adminToken))
+
+	w := sendAdmin(t, r, "/admin/capacity-index/check")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.CapacityIndexReport](t, w); got.Clients != 3 || len(got.Differences) != 1 {
+		t.Errorf("report = %+v", got)
+	}
+
+	enabled = false
+	assertError(t, sendAdmin(t, r, "/admin/capacity-index/check"), http.StatusNotImplemented, storage.CodeCapacityIndexDisabled)
+}
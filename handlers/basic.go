@@ -4,24 +4,48 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"leads/storage"
 )
 
 type BasicHandler struct{}
 
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error string            `json:"error"`
+	Code  storage.ErrorCode `json:"code"` // Stable machine-readable reason, see the Code* constants
 }
 
 func (h *BasicHandler) sendOk(ctx *gin.Context, val any) {
 	ctx.JSON(http.StatusOK, val)
 }
 
+func (h *BasicHandler) sendCreated(ctx *gin.Context, location string, val any) {
+	ctx.Header("Location", location)
+	ctx.JSON(http.StatusCreated, val)
+}
+
+func (h *BasicHandler) sendNoContent(ctx *gin.Context) {
+	ctx.Status(http.StatusNoContent)
+}
+
+// sendError - responds with the status and the code matching the kind of the error
+func (h *BasicHandler) sendError(ctx *gin.Context, err error) {
+	status, code := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		h.sendInternalServerError(ctx, err)
+		return
+	}
+
+	_ = ctx.Error(err).SetType(gin.ErrorTypePublic)
+
+	h.sendErrorResponse(ctx, status, code, err.Error(), nil)
+}
+
 func (h *BasicHandler) sendInternalServerError(ctx *gin.Context, err error) {
 	_ = ctx.Error(err)
 
-	ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	h.sendErrorResponse(ctx, http.StatusInternalServerError, CodeInternal, err.Error(), nil)
 }
 
-func (h *BasicHandler) notFound(ctx *gin.Context, val any) {
-	ctx.JSON(http.StatusNotFound, val)
+func (h *BasicHandler) notFound(ctx *gin.Context, code storage.ErrorCode, format string, args ...any) {
+	h.sendError(ctx, storage.NotFound(code, format, args...))
 }
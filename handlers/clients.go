@@ -2,7 +2,7 @@ package handlers
 
 import (
 	"fmt"
-	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"leads/storage"
@@ -11,12 +11,14 @@ import (
 type ClientsHandlers struct {
 	*BasicHandler
 	basePath string
-	storage  *storage.Storage
+	clients  storage.ClientRepository
+	leads    storage.LeadRepository
 }
 
-func NewClientsHandlers(storage *storage.Storage) *ClientsHandlers {
+func NewClientsHandlers(clients storage.ClientRepository, leads storage.LeadRepository) *ClientsHandlers {
 	return &ClientsHandlers{
-		storage: storage,
+		clients: clients,
+		leads:   leads,
 	}
 }
 
@@ -24,8 +26,14 @@ func (h *ClientsHandlers) InstallRoutes(r gin.IRouter) {
 	c := r.Group("/clients")
 
 	c.POST("/", h.CreateClient)
+	c.POST("/import", h.ImportClients)
 	c.GET("/", h.GetClients)
 	c.GET("/:id", h.GetClient)
+	c.DELETE("/:id", h.DeleteClient)
+	c.POST("/:id/restore", h.RestoreClient)
+	c.GET("/:id/leads", h.GetClientLeads)
+	c.GET("/:id/history", h.GetClientHistory)
+	c.PUT("/:id/metadata", h.UpdateClientMetadata)
 	c.POST("/assign", h.AssignLead)
 }
 
@@ -35,69 +43,330 @@ func (h *ClientsHandlers) InstallRoutes(r gin.IRouter) {
 // @Param _ body storage.ClientRequest true "New client payload"
 // @Tags client
 // @Produce json
+// @Failure	400	{object} ValidationErrorResponse
+// @Failure	422	{object} ValidationErrorResponse
 // @Failure	500	{object} ErrorResponse
-// @Success 200 {bool} true
+// @Success 201 {object} storage.Client
+// @Header 201 {string} Location "URL of the created client"
 // @Router /clients [post]
 func (h *ClientsHandlers) CreateClient(c *gin.Context) {
 	var body storage.ClientRequest
 	if err := c.ShouldBindJSON(&body); err != nil {
-		h.sendInternalServerError(c, err)
+		h.sendBindError(c, err)
 		return
 	}
 
-	err := h.storage.CreateClient(c, body)
+	client, err := h.clients.CreateClient(c, body)
 	if err != nil {
-		h.sendInternalServerError(c, err)
+		h.sendError(c, err)
 		return
 	}
 
-	h.sendOk(c, true)
+	h.sendCreated(c, fmt.Sprintf("/clients/%d", client.ID), client)
 }
 
-// GetClients receives a list of clients
+// GetClients receives a page of clients
 //
-// @Summary Receives a list of clients
+// @Summary Receives a page of clients
+// @Description Clients are filtered, sorted and paginated by the database. Pass `meta.next_cursor` of the response as `cursor`
+// @Description (together with the same `sort`) to receive the next page.
+// @Param priority query []string false "Priorities of clients" collectionFormat(multi) Enums(HIGH, MEDIUM, LOW)
+// @Param active_from query string false "Client's time frame starts not later than this date"
+// @Param active_to query string false "Client's time frame ends not earlier than this date"
+// @Param has_capacity query bool false "Client still can receive leads"
+// @Param name_prefix query string false "Beginning of the client's name"
+// @Param metadata[key] query string false "Metadata value, e.g. metadata[crm.id]=42. Can be repeated for several keys"
+// @Param sort query string false "Sort field, prefix with '-' for descending order" Enums(id, -id, name, -name, priority, -priority, start_date, -start_date, end_date, -end_date, lead_capacity, -lead_capacity, free_capacity, -free_capacity) default(id)
+// @Param cursor query string false "Cursor of the next page"
+// @Param limit query int false "Page size" default(50) maximum(500)
 // @Tags client
 // @Produce json
+// @Failure	400	{object} ValidationErrorResponse
+// @Failure	422	{object} ValidationErrorResponse
 // @Failure	500	{object} ErrorResponse
-// @Success 200 {object} []storage.Client
+// @Success 200 {object} storage.ClientsPage
 // @Router /clients [get]
 func (h *ClientsHandlers) GetClients(c *gin.Context) {
-	clients, err := h.storage.GetClients(c, nil)
+	var filter storage.ClientsFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		h.sendBindError(c, err)
+		return
+	}
+	filter.Metadata = c.QueryMap("metadata")
+
+	page, err := h.clients.ListClients(c, filter)
 	if err != nil {
-		h.sendInternalServerError(c, err)
+		h.sendError(c, err)
 		return
 	}
 
-	h.sendOk(c, clients)
+	h.sendOk(c, page)
 }
 
 // GetClient get client by clientID
 //
 // @Summary Get client by clientID
-// @Description Returns a single client array with the found user or a string with an error in case user is not found
+// @Description Returns a single client array with the found user or a string with an error in case user is not found.
+// @Description With `as_of` returns the client as it was at that moment (RFC 3339 or `YYYY-MM-DD HH:MM:SS` in UTC),
+// @Description archived or not, with the number of leads assigned to it then (see storage.ClientAsOf).
 // @Param id path string true "Client ID"
+// @Param as_of query string false "Point in time"
 // @Tags client
 // @Produce json
+// @Failure	400	{object} ErrorResponse
 // @Failure	500	{object} ErrorResponse
-// @Failure	404	{object} string
+// @Failure	404	{object} ErrorResponse
 // @Success 200 {object} []storage.Client
 // @Router /clients/{id} [get]
 func (h *ClientsHandlers) GetClient(c *gin.Context) {
-	clientID, err := strconv.Atoi(c.Param("id"))
+	clientID, err := pathID(c, "id")
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if asOf := c.Query("as_of"); asOf != "" {
+		h.getClientAsOf(c, clientID, asOf)
+		return
+	}
+
+	client, err := h.clients.GetClients(c, &clientID)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if client == nil {
+		h.notFound(c, storage.CodeClientNotFound, "client with ID '%s' was not found", c.Param("id"))
+		return
+	}
+
+	h.sendOk(c, client)
+}
+
+// DeleteClient archives client by clientID
+//
+// @Summary Archives client by clientID
+// @Description Soft-archives the client: it is hidden from the clients list and no longer receives leads.
+// @Description Query parameter `leads` decides what happens to its leads: `keep` leaves them with the archived client,
+// @Description `reassign` passes them through the assignment engine again (leads that fit no one go to the pending queue),
+// @Description `pending` moves them to the pending queue.
+// @Param id path string true "Client ID"
+// @Param leads query string false "Leads policy" Enums(keep, reassign, pending) default(keep)
+// @Tags client
+// @Produce json
+// @Failure	400	{object} ErrorResponse
+// @Failure	409	{object} ErrorResponse
+// @Failure	422	{object} ErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 200 {object} storage.ArchiveResult
+// @Router /clients/{id} [delete]
+func (h *ClientsHandlers) DeleteClient(c *gin.Context) {
+	clientID, err := pathID(c, "id")
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	result, err := h.clients.ArchiveClient(c, clientID, c.DefaultQuery("leads", storage.LeadsKeep))
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if result == nil {
+		h.notFound(c, storage.CodeClientNotFound, "client with ID '%s' was not found", c.Param("id"))
+		return
+	}
+
+	h.sendOk(c, result)
+}
+
+// RestoreClient restores an archived client
+//
+// @Summary Restores an archived client
+// @Description Makes the client visible and available for assignment again. Leads that were reassigned or moved to the pending queue are not returned.
+// @Param id path string true "Client ID"
+// @Tags client
+// @Produce json
+// @Failure	400	{object} ErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 200 {object} storage.Client
+// @Router /clients/{id}/restore [post]
+func (h *ClientsHandlers) RestoreClient(c *gin.Context) {
+	clientID, err := pathID(c, "id")
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	client, err := h.clients.RestoreClient(c, clientID)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if client == nil {
+		h.notFound(c, storage.CodeClientNotFound, "client with ID '%s' was not found", c.Param("id"))
+		return
+	}
+
+	h.sendOk(c, client)
+}
+
+func (h *ClientsHandlers) getClientAsOf(c *gin.Context, clientID int, asOf string) {
+	at, err := parseAsOf(asOf)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	client, err := h.clients.ClientAsOf(c, clientID, at)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if client == nil {
+		h.notFound(c, storage.CodeClientNotFound, "client with ID '%d' did not exist at %s", clientID, asOf)
+		return
+	}
+
+	h.sendOk(c, client)
+}
+
+func parseAsOf(value string) (time.Time, error) {
+	at, err := storage.ParseTime(value)
+	if err != nil {
+		return time.Time{}, badRequest(CodeInvalidAsOf, "invalid as_of: %w", err)
+	}
+
+	return at, nil
+}
+
+// GetClientHistory receives all versions of the client
+//
+// @Summary Receives all versions of the client
+// @Description Every change of a client (create, update, archive, restore) is a new version with the values before and after the change.
+// @Description `changed_by` is taken from the `X-Actor` header of the request that made the change.
+// @Param id path string true "Client ID"
+// @Tags client
+// @Produce json
+// @Failure	400	{object} ErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 200 {object} []storage.ClientChange
+// @Router /clients/{id}/history [get]
+func (h *ClientsHandlers) GetClientHistory(c *gin.Context) {
+	clientID, err := pathID(c, "id")
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	history, err := h.clients.ClientHistory(c, clientID)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if history == nil {
+		h.notFound(c, storage.CodeClientNotFound, "client with ID '%s' was not found", c.Param("id"))
+		return
+	}
+
+	h.sendOk(c, history)
+}
+
+// GetClientLeads receives a page of client's leads
+//
+// @Summary Receives a page of client's leads
+// @Description Leads are ordered by their start date. Pass `meta.next_cursor` of the response as `cursor` to receive the next page.
+// @Param id path string true "Client ID"
+// @Param cursor query string false "Cursor of the next page"
+// @Param limit query int false "Page size" default(50) maximum(500)
+// @Tags client
+// @Produce json
+// @Failure	400	{object} ErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 200 {object} storage.LeadsPage
+// @Router /clients/{id}/leads [get]
+func (h *ClientsHandlers) GetClientLeads(c *gin.Context) {
+	clientID, err := pathID(c, "id")
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	var query struct {
+		Cursor string `form:"cursor"`
+		Limit  int    `form:"limit"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.sendBindError(c, err)
+		return
+	}
+
+	client, err := h.clients.GetClients(c, &clientID)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if client == nil {
+		h.notFound(c, storage.CodeClientNotFound, "client with ID '%s' was not found", c.Param("id"))
+		return
+	}
+
+	filter := storage.LeadsFilter{ClientID: &clientID, Cursor: query.Cursor, Limit: query.Limit}
+
+	page, err := h.leads.ListLeads(c, filter)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	h.sendOk(c, page)
+}
+
+// UpdateClientMetadata replaces metadata of the client
+//
+// @Summary Replaces metadata of the client
+// @Description Metadata is an arbitrary JSON object. When a schema for client metadata is set, metadata must match it.
+// @Param id path string true "Client ID"
+// @Param _ body object true "Metadata"
+// @Tags client
+// @Produce json
+// @Failure	400	{object} ErrorResponse
+// @Failure	422	{object} ErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 200 {object} storage.Client
+// @Router /clients/{id}/metadata [put]
+func (h *ClientsHandlers) UpdateClientMetadata(c *gin.Context) {
+	clientID, err := pathID(c, "id")
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	metadata, err := c.GetRawData()
 	if err != nil {
-		h.sendInternalServerError(c, err)
+		h.sendError(c, err)
 		return
 	}
 
-	client, err := h.storage.GetClients(c, &clientID)
+	client, err := h.clients.UpdateClientMetadata(c, clientID, metadata)
 	if err != nil {
-		h.sendInternalServerError(c, err)
+		h.sendError(c, err)
 		return
 	}
 
 	if client == nil {
-		h.notFound(c, fmt.Sprintf("client with ID '%s' was not found", c.Param("id")))
+		h.notFound(c, storage.CodeClientNotFound, "client with ID '%s' was not found", c.Param("id"))
 		return
 	}
 
@@ -113,19 +382,22 @@ func (h *ClientsHandlers) GetClient(c *gin.Context) {
 // @Description Then sort users by their priority and percentage of free capacity. Select the user with the highest indicator.
 // @Tags client
 // @Produce json
+// @Failure	400	{object} ValidationErrorResponse
+// @Failure	422	{object} ValidationErrorResponse
+// @Failure	503	{object} ErrorResponse
 // @Failure	500	{object} ErrorResponse
 // @Success 200 {object} storage.Lead
 // @Router /clients/assign [post]
 func (h *ClientsHandlers) AssignLead(c *gin.Context) {
 	var lead storage.AssignLeadRequest
 	if err := c.ShouldBindJSON(&lead); err != nil {
-		h.sendInternalServerError(c, err)
+		h.sendBindError(c, err)
 		return
 	}
 
-	createdLead, err := h.storage.AssignLead(c, lead)
+	createdLead, err := h.leads.AssignLead(c, lead)
 	if err != nil {
-		h.sendInternalServerError(c, err)
+		h.sendError(c, err)
 		return
 	}
 
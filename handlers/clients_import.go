@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"leads/storage"
+)
+
+const maxImportSize = 10 << 20
+
+var csvColumns = []string{"name", "start_date", "end_date", "priority", "lead_capacity"}
+
+// ImportClients creates clients from a CSV or NDJSON file
+//
+// @Summary Creates clients from a CSV or NDJSON file
+// @Description The file is sent either as the request body or as the `file` field of a multipart form.
+// @Description Format is taken from `format` query parameter or from the Content-Type (`text/csv`, `application/x-ndjson`).
+// @Description CSV must have a header with columns name, start_date, end_date, priority, lead_capacity and optional metadata (JSON object).
+// @Description Every row is validated and all valid rows are imported in a single transaction.
+// @Description In `all_or_nothing` mode a single invalid row cancels the import, in `skip_invalid` mode invalid rows are only reported.
+// @Param format query string false "File format" Enums(csv, ndjson)
+// @Param mode query string false "Import mode" Enums(all_or_nothing, skip_invalid) default(all_or_nothing)
+// @Tags client
+// @Accept text/csv,application/x-ndjson,multipart/form-data
+// @Produce json
+// @Failure	422	{object} ErrorResponse
+// @Failure	400	{object} ErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Success 200 {object} storage.ImportReport
+// @Router /clients/import [post]
+func (h *ClientsHandlers) ImportClients(c *gin.Context) {
+	body, contentType, err := importBody(c)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+	defer body.Close()
+
+	format := c.Query("format")
+	if format == "" {
+		format = importFormat(contentType)
+	}
+
+	var rows []storage.ImportRow
+	switch format {
+	case "csv":
+		rows, err = parseCSVImport(body)
+	case "ndjson":
+		rows, err = parseNDJSONImport(body)
+	default:
+		err = badRequest(CodeUnsupportedFormat, "unsupported import format '%s'", format)
+	}
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	report, err := h.clients.ImportClients(c, rows, c.DefaultQuery("mode", storage.ImportAllOrNothing))
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	h.sendOk(c, report)
+}
+
+// importBody - returns the uploaded file and its content type
+func importBody(c *gin.Context) (io.ReadCloser, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", badRequest(CodeMalformedFile, "can't read uploaded file: %w", err)
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return nil, "", badRequest(CodeMalformedFile, "can't read uploaded file: %w", err)
+		}
+
+		return file, header.Header.Get("Content-Type"), nil
+	}
+
+	return c.Request.Body, c.ContentType(), nil
+}
+
+func importFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return "csv"
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return "ndjson"
+	}
+
+	return ""
+}
+
+func parseCSVImport(r io.Reader) ([]storage.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, badRequest(CodeMalformedFile, "can't read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, badRequest(CodeMalformedFile, "CSV header has no '%s' column", name)
+		}
+	}
+
+	rows := []storage.ImportRow{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := storage.ImportRow{Row: line}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			row.Row = parseErr.Line
+			rows = append(rows, invalidRow(row, err))
+			continue
+		}
+		if err != nil {
+			return nil, badRequest(CodeMalformedFile, "can't read CSV: %w", err)
+		}
+
+		field := func(name string) string {
+			if i := columns[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		capacity, err := strconv.Atoi(field("lead_capacity"))
+		if err != nil {
+			rows = append(rows, invalidRow(row, fmt.Errorf("lead_capacity: %w", err)))
+			continue
+		}
+
+		var start, end storage.Timestamp
+		_ = start.UnmarshalParam(field("start_date"))
+		_ = end.UnmarshalParam(field("end_date"))
+
+		row.Client = storage.ClientRequest{
+			Name:         field("name"),
+			StartDate:    start,
+			EndDate:      end,
+			Priority:     field("priority"),
+			LeadCapacity: capacity,
+		}
+		if _, ok := columns["metadata"]; ok {
+			row.Client.Metadata = storage.Metadata(field("metadata"))
+		}
+		rows = append(rows, validateImportRow(row))
+	}
+
+	return rows, nil
+}
+
+func parseNDJSONImport(r io.Reader) ([]storage.ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportSize)
+
+	rows := []storage.ImportRow{}
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := storage.ImportRow{Row: line}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.Client); err != nil {
+			rows = append(rows, invalidRow(row, err))
+			continue
+		}
+
+		rows = append(rows, validateImportRow(row))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, badRequest(CodeMalformedFile, "can't read NDJSON: %w", err)
+	}
+
+	return rows, nil
+}
+
+// validateImportRow - applies the same binding rules as POST /clients
+func validateImportRow(row storage.ImportRow) storage.ImportRow {
+	if err := binding.Validator.ValidateStruct(&row.Client); err != nil {
+		return invalidRow(row, err)
+	}
+
+	return row
+}
+
+func invalidRow(row storage.ImportRow, err error) storage.ImportRow {
+	row.Status = storage.ImportRowInvalid
+	row.Error = validationMessage(err)
+
+	return row
+}
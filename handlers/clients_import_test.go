@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"leads/storage"
+	"leads/storage/storagefake"
+)
+
+// importRecorder - ImportClients double reporting the rows it received as they are
+func importRecorder(received *[]storage.ImportRow, mode *storage.ImportMode) *storagefake.ClientRepository {
+	return &storagefake.ClientRepository{
+		ImportClientsFunc: func(ctx context.Context, rows []storage.ImportRow, m storage.ImportMode) (*storage.ImportReport, error) {
+			*received = rows
+			*mode = m
+			return &storage.ImportReport{Mode: m, Total: len(rows), Rows: rows}, nil
+		},
+	}
+}
+
+func TestImportClientsCSV(t *testing.T) {
+	var rows []storage.ImportRow
+	var mode storage.ImportMode
+	r := clientsRouter(importRecorder(&rows, &mode), nil)
+
+	csv := "name,start_date,end_date,priority,lead_capacity,metadata\n" +
+		"acme,2024-01-01T00:00:00Z,2024-02-01 00:00:00,HIGH,5,\"{\"\"crm_id\"\":\"\"1\"\"}\"\n" +
+		"broken,2024-01-01T00:00:00Z,2024-02-01T00:00:00Z,HIGH,many,\n" +
+		"late,2024-03-01T00:00:00Z,2024-02-01T00:00:00Z,LOW,1,\n"
+	req := httptest.NewRequest(http.MethodPost, "/clients/import?mode=skip_invalid", bytes.NewBufferString(csv))
+	req.Header.Set("Content-Type", "text/csv")
+
+	w := serve(t, r, req)
+
+	assertStatus(t, w, http.StatusOK)
+	if mode != storage.ImportSkipInvalid {
+		t.Errorf("mode = %q, want %q", mode, storage.ImportSkipInvalid)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("rows = %+v, want 3", rows)
+	}
+	if rows[0].Status != "" || rows[0].Row != 2 || rows[0].Client.Name != "acme" || string(rows[0].Client.Metadata) != `{"crm_id":"1"}` {
+		t.Errorf("valid row = %+v", rows[0])
+	}
+	for _, row := range rows[1:] {
+		if row.Status != storage.ImportRowInvalid || row.Error == "" {
+			t.Errorf("row %d = %+v, want it invalid", row.Row, row)
+		}
+	}
+}
+
+func TestImportClientsNDJSONUpload(t *testing.T) {
+	var rows []storage.ImportRow
+	var mode storage.ImportMode
+	r := clientsRouter(importRecorder(&rows, &mode), nil)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, _ := form.CreateFormFile("file", "clients.ndjson")
+	_, _ = file.Write([]byte(validClient + "\n\n" + `{"name":"x","unknown":1}` + "\n"))
+	_ = form.Close()
+
+	// The format of an uploaded file comes from the query, its part has no useful content type
+	req := httptest.NewRequest(http.MethodPost, "/clients/import?format=ndjson", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	w := serve(t, r, req)
+
+	assertStatus(t, w, http.StatusOK)
+	if mode != storage.ImportAllOrNothing {
+		t.Errorf("mode = %q, want the default %q", mode, storage.ImportAllOrNothing)
+	}
+	if len(rows) != 2 || rows[0].Status != "" || rows[1].Row != 3 || rows[1].Status != storage.ImportRowInvalid {
+		t.Errorf("rows = %+v", rows)
+	}
+}
+
+func TestImportClientsErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		status      int
+		code        storage.ErrorCode
+	}{
+		{name: "unknown format", target: "/clients/import", contentType: "application/xml", body: "<clients/>", status: http.StatusBadRequest, code: CodeUnsupportedFormat},
+		{name: "missing column", target: "/clients/import", contentType: "text/csv", body: "name,priority\nacme,HIGH\n", status: http.StatusBadRequest, code: CodeMalformedFile},
+		{name: "empty CSV", target: "/clients/import?format=csv", contentType: "text/plain", body: "", status: http.StatusBadRequest, code: CodeMalformedFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			w := serve(t, clientsRouter(&storagefake.ClientRepository{}, nil), req)
+
+			assertError(t, w, tt.status, tt.code)
+		})
+	}
+
+	t.Run("invalid mode", func(t *testing.T) {
+		clients := &storagefake.ClientRepository{
+			ImportClientsFunc: func(ctx context.Context, rows []storage.ImportRow, mode storage.ImportMode) (*storage.ImportReport, error) {
+				return nil, storageError(storage.ErrInvalidInput, storage.CodeInvalidImportMode)
+			},
+		}
+		req := httptest.NewRequest(http.MethodPost, "/clients/import?mode=some", bytes.NewBufferString(validClient))
+		req.Header.Set("Content-Type", "application/x-ndjson")
+
+		assertError(t, serve(t, clientsRouter(clients, nil), req), http.StatusUnprocessableEntity, storage.CodeInvalidImportMode)
+	})
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"leads/storage"
+	"leads/storage/storagefake"
+)
+
+const validClient = `{"name":"acme","start_date":"2024-01-01T00:00:00Z","end_date":"2024-12-31 00:00:00","priority":"HIGH","lead_capacity":10}`
+
+func clientsRouter(clients *storagefake.ClientRepository, leads *storagefake.LeadRepository) http.Handler {
+	return newRouter(NewClientsHandlers(clients, leads))
+}
+
+func TestCreateClient(t *testing.T) {
+	clients := &storagefake.ClientRepository{
+		CreateClientFunc: func(ctx context.Context, c storage.ClientRequest) (*storage.Client, error) {
+			if c.Name != "acme" || c.LeadCapacity != 10 || c.Priority != "HIGH" {
+				t.Errorf("CreateClient(%+v): unexpected request", c)
+			}
+			if want := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC); !c.EndDate.Equal(want) {
+				t.Errorf("end date = %v, want %v", c.EndDate, want)
+			}
+			return &storage.Client{ID: 7, Name: c.Name}, nil
+		},
+	}
+
+	w := send(t, clientsRouter(clients, nil), http.MethodPost, "/clients/", validClient)
+
+	assertStatus(t, w, http.StatusCreated)
+	if got := w.Header().Get("Location"); got != "/clients/7" {
+		t.Errorf("Location = %q, want /clients/7", got)
+	}
+	if got := decode[storage.Client](t, w); got.ID != 7 {
+		t.Errorf("client = %+v, want ID 7", got)
+	}
+}
+
+func TestCreateClientUnknownGroup(t *testing.T) {
+	clients := &storagefake.ClientRepository{
+		CreateClientFunc: func(ctx context.Context, c storage.ClientRequest) (*storage.Client, error) {
+			return nil, storageError(storage.ErrInvalidInput, storage.CodeUnknownGroup)
+		},
+	}
+
+	w := send(t, clientsRouter(clients, nil), http.MethodPost, "/clients/", validClient)
+
+	assertError(t, w, http.StatusUnprocessableEntity, storage.CodeUnknownGroup)
+}
+
+func TestGetClients(t *testing.T) {
+	clients := &storagefake.ClientRepository{
+		ListClientsFunc: func(ctx context.Context, f storage.ClientsFilter) (*storage.ClientsPage, error) {
+			if !slices.Equal(f.Priority, []string{"HIGH", "LOW"}) {
+				t.Errorf("priorities = %v, want [HIGH LOW]", f.Priority)
+			}
+			if f.HasCapacity == nil || !*f.HasCapacity {
+				t.Errorf("has_capacity = %v, want true", f.HasCapacity)
+			}
+			if f.Metadata["crm.id"] != "00123" {
+				t.Errorf("metadata filter = %v, want crm.id=00123", f.Metadata)
+			}
+			if f.Sort != "-name" || f.Cursor != "abc" || f.Limit != 5 {
+				t.Errorf("sort, cursor, limit = %q, %q, %d", f.Sort, f.Cursor, f.Limit)
+			}
+			return &storage.ClientsPage{Data: []storage.Client{{ID: 1}, {ID: 2}}, Meta: storage.PageMeta{Limit: 5}}, nil
+		},
+	}
+
+	target := "/clients/?priority=HIGH&priority=LOW&has_capacity=true&metadata[crm.id]=00123&sort=-name&cursor=abc&limit=5"
+	w := send(t, clientsRouter(clients, nil), http.MethodGet, target, "")
+
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.ClientsPage](t, w); len(got.Data) != 2 || got.Meta.Limit != 5 {
+		t.Errorf("page = %+v", got)
+	}
+}
+
+func TestGetClientsErrors(t *testing.T) {
+	t.Run("invalid filter", func(t *testing.T) {
+		w := send(t, clientsRouter(&storagefake.ClientRepository{}, nil), http.MethodGet, "/clients/?priority=URGENT", "")
+
+		assertError(t, w, http.StatusUnprocessableEntity, CodeValidationFailed)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		clients := &storagefake.ClientRepository{
+			ListClientsFunc: func(ctx context.Context, f storage.ClientsFilter) (*storage.ClientsPage, error) {
+				return nil, storageError(storage.ErrInvalidInput, storage.CodeInvalidCursor)
+			},
+		}
+
+		w := send(t, clientsRouter(clients, nil), http.MethodGet, "/clients/?cursor=x", "")
+
+		assertError(t, w, http.StatusUnprocessableEntity, storage.CodeInvalidCursor)
+	})
+
+	t.Run("database failure", func(t *testing.T) {
+		clients := &storagefake.ClientRepository{
+			ListClientsFunc: func(ctx context.Context, f storage.ClientsFilter) (*storage.ClientsPage, error) {
+				return nil, errors.New("disk I/O error")
+			},
+		}
+
+		w := send(t, clientsRouter(clients, nil), http.MethodGet, "/clients/", "")
+
+		assertError(t, w, http.StatusInternalServerError, CodeInternal)
+	})
+}
+
+func TestGetClient(t *testing.T) {
+	clients := &storagefake.ClientRepository{
+		GetClientsFunc: func(ctx context.Context, clientID *int) ([]storage.Client, error) {
+			if clientID == nil || *clientID != 3 {
+				return nil, nil
+			}
+			return []storage.Client{{ID: 3, Name: "acme"}}, nil
+		},
+		ClientAsOfFunc: func(ctx context.Context, clientID int, asOf time.Time) (*storage.ClientAsOf, error) {
+			if want := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC); !asOf.Equal(want) {
+				t.Errorf("as_of = %v, want %v", asOf, want)
+			}
+			if clientID != 3 {
+				return nil, nil
+			}
+			return &storage.ClientAsOf{ID: 3, Version: 2}, nil
+		},
+	}
+	r := clientsRouter(clients, nil)
+
+	t.Run("found", func(t *testing.T) {
+		w := send(t, r, http.MethodGet, "/clients/3", "")
+
+		assertStatus(t, w, http.StatusOK)
+		if got := decode[[]storage.Client](t, w); len(got) != 1 || got[0].Name != "acme" {
+			t.Errorf("clients = %+v", got)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		assertError(t, send(t, r, http.MethodGet, "/clients/4", ""), http.StatusNotFound, storage.CodeClientNotFound)
+	})
+
+	t.Run("invalid ID", func(t *testing.T) {
+		assertError(t, send(t, r, http.MethodGet, "/clients/abc", ""), http.StatusBadRequest, CodeInvalidID)
+	})
+
+	t.Run("as of", func(t *testing.T) {
+		w := send(t, r, http.MethodGet, "/clients/3?as_of=2024-05-01T12:00:00%2B03:00", "")
+
+		assertStatus(t, w, http.StatusOK)
+		if got := decode[storage.ClientAsOf](t, w); got.Version != 2 {
+			t.Errorf("client = %+v, want version 2", got)
+		}
+	})
+
+	t.Run("did not exist then", func(t *testing.T) {
+		w := send(t, r, http.MethodGet, "/clients/4?as_of=2024-05-01T09:00:00Z", "")
+
+		assertError(t, w, http.StatusNotFound, storage.CodeClientNotFound)
+	})
+
+	t.Run("invalid as of", func(t *testing.T) {
+		assertError(t, send(t, r, http.MethodGet, "/clients/3?as_of=yesterday", ""), http.StatusBadRequest, CodeInvalidAsOf)
+	})
+}
+
+func TestDeleteClient(t *testing.T) {
+	var policies []storage.LeadsPolicy
+	clients := &storagefake.ClientRepository{
+		ArchiveClientFunc: func(ctx context.Context, clientID int, policy storage.LeadsPolicy) (*storage.ArchiveResult, error) {
+			policies = append(policies, policy)
+			switch clientID {
+			case 1:
+				return &storage.ArchiveResult{ClientID: 1, Policy: policy, Leads: []storage.Lead{}}, nil
+			case 2:
+				return nil, storageError(storage.ErrConflict, storage.CodeClientArchived)
+			}
+			return nil, nil
+		},
+	}
+	r := clientsRouter(clients, nil)
+
+	w := send(t, r, http.MethodDelete, "/clients/1", "")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.ArchiveResult](t, w); got.Policy != storage.LeadsKeep {
+		t.Errorf("policy = %q, want the default %q", got.Policy, storage.LeadsKeep)
+	}
+
+	assertStatus(t, send(t, r, http.MethodDelete, "/clients/1?leads=reassign", ""), http.StatusOK)
+	assertError(t, send(t, r, http.MethodDelete, "/clients/2", ""), http.StatusConflict, storage.CodeClientArchived)
+	assertError(t, send(t, r, http.MethodDelete, "/clients/3", ""), http.StatusNotFound, storage.CodeClientNotFound)
+	assertError(t, send(t, r, http.MethodDelete, "/clients/x", ""), http.StatusBadRequest, CodeInvalidID)
+
+	if want := []string{"keep", "reassign", "keep", "keep"}; !slices.Equal(policies, want) {
+		t.Errorf("policies = %v, want %v", policies, want)
+	}
+}
+
+func TestRestoreClient(t *testing.T) {
+	clients := &storagefake.ClientRepository{
+		RestoreClientFunc: func(ctx context.Context, clientID int) (*storage.Client, error) {
+			if clientID != 1 {
+				return nil, nil
+			}
+			return &storage.Client{ID: 1}, nil
+		},
+	}
+	r := clientsRouter(clients, nil)
+
+	assertStatus(t, send(t, r, http.MethodPost, "/clients/1/restore", ""), http.StatusOK)
+	assertError(t, send(t, r, http.MethodPost, "/clients/2/restore", ""), http.StatusNotFound, storage.CodeClientNotFound)
+}
+
+func TestGetClientLeads(t *testing.T) {
+	clients := &storagefake.ClientRepository{
+		GetClientsFunc: func(ctx context.Context, clientID *int) ([]storage.Client, error) {
+			if *clientID != 1 {
+				return nil, nil
+			}
+			return []storage.Client{{ID: 1}}, nil
+		},
+	}
+	leads := &storagefake.LeadRepository{
+		ListLeadsFunc: func(ctx context.Context, f storage.LeadsFilter) (*storage.LeadsPage, error) {
+			if f.ClientID == nil || *f.ClientID != 1 || f.Cursor != "c" || f.Limit != 2 {
+				t.Errorf("filter = %+v, want leads of client 1 after cursor c, 2 per page", f)
+			}
+			return &storage.LeadsPage{Data: []storage.Lead{{LeadID: "a", ClientID: 1}}}, nil
+		},
+	}
+	r := clientsRouter(clients, leads)
+
+	w := send(t, r, http.MethodGet, "/clients/1/leads?cursor=c&limit=2", "")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.LeadsPage](t, w); len(got.Data) != 1 {
+		t.Errorf("page = %+v", got)
+	}
+
+	// ListLeads is not called for a missing client
+	assertError(t, send(t, r, http.MethodGet, "/clients/2/leads", ""), http.StatusNotFound, storage.CodeClientNotFound)
+	assertError(t, send(t, r, http.MethodGet, "/clients/1/leads?limit=abc", ""), http.StatusBadRequest, CodeMalformedRequest)
+}
+
+func TestGetClientHistory(t *testing.T) {
+	clients := &storagefake.ClientRepository{
+		ClientHistoryFunc: func(ctx context.Context, clientID int) ([]storage.ClientChange, error) {
+			if clientID != 1 {
+				return nil, nil
+			}
+			return []storage.ClientChange{{Version: 1, Operation: storage.ClientCreated, NewValues: []byte(`{}`)}}, nil
+		},
+	}
+	r := clientsRouter(clients, nil)
+
+	w := send(t, r, http.MethodGet, "/clients/1/history", "")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[[]storage.ClientChange](t, w); len(got) != 1 || got[0].Operation != storage.ClientCreated {
+		t.Errorf("history = %+v", got)
+	}
+
+	assertError(t, send(t, r, http.MethodGet, "/clients/2/history", ""), http.StatusNotFound, storage.CodeClientNotFound)
+}
+
+func TestUpdateClientMetadata(t *testing.T) {
+	clients := &storagefake.ClientRepository{
+		UpdateClientMetadataFunc: func(ctx context.Context, clientID int, metadata storage.Metadata) (*storage.Client, error) {
+			switch {
+			case string(metadata) == "[]":
+				return nil, storageError(storage.ErrInvalidInput, storage.CodeInvalidMetadata)
+			case clientID != 1:
+				return nil, nil
+			}
+			return &storage.Client{ID: 1, Metadata: metadata}, nil
+		},
+	}
+	r := clientsRouter(clients, nil)
+
+	w := send(t, r, http.MethodPut, "/clients/1/metadata", `{"crm_id":"00123"}`)
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.Client](t, w); string(got.Metadata) != `{"crm_id":"00123"}` {
+		t.Errorf("metadata = %s", got.Metadata)
+	}
+
+	assertError(t, send(t, r, http.MethodPut, "/clients/1/metadata", `[]`), http.StatusUnprocessableEntity, storage.CodeInvalidMetadata)
+	assertError(t, send(t, r, http.MethodPut, "/clients/2/metadata", `{}`), http.StatusNotFound, storage.CodeClientNotFound)
+}
+
+func TestAssignLead(t *testing.T) {
+	available := true
+	leads := &storagefake.LeadRepository{
+		AssignLeadFunc: func(ctx context.Context, l storage.AssignLeadRequest) (*storage.Lead, error) {
+			if !available {
+				return nil, storage.ErrNoClientsAvailable
+			}
+			return &storage.Lead{LeadID: "l1", ClientID: 2, Status: storage.LeadStatusAssigned, LeadStart: l.LeadStart.Time, LeadEnd: l.LeadEnd.Time}, nil
+		},
+	}
+	r := clientsRouter(nil, leads)
+	body := `{"lead_start":"2024-03-01 10:00:00","lead_end":"2024-03-01T12:00:00+01:00"}`
+
+	w := send(t, r, http.MethodPost, "/clients/assign", body)
+	assertStatus(t, w, http.StatusOK)
+	got := decode[storage.Lead](t, w)
+	if got.ClientID != 2 || !got.LeadEnd.Equal(time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("lead = %+v", got)
+	}
+
+	available = false
+	assertError(t, send(t, r, http.MethodPost, "/clients/assign", body), http.StatusServiceUnavailable, storage.CodeNoClientsAvailable)
+
+	inverted := `{"lead_start":"2024-03-02T00:00:00Z","lead_end":"2024-03-01T00:00:00Z"}`
+	assertError(t, send(t, r, http.MethodPost, "/clients/assign", inverted), http.StatusUnprocessableEntity, CodeValidationFailed)
+}
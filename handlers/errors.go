@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"leads/storage"
+)
+
+// Error codes of the HTTP layer, in addition to the storage.Code* ones
+const (
+	CodeMalformedRequest  storage.ErrorCode = "malformed_request"
+	CodeValidationFailed  storage.ErrorCode = "validation_failed"
+	CodeInvalidID         storage.ErrorCode = "invalid_id"
+	CodeInvalidAsOf       storage.ErrorCode = "invalid_as_of"
+	CodeUnsupportedFormat storage.ErrorCode = "unsupported_format"
+	CodeMalformedFile     storage.ErrorCode = "malformed_file"
+	CodeUnauthorized      storage.ErrorCode = "unauthorized"
+	CodeAdminDisabled     storage.ErrorCode = "admin_disabled"
+	CodeInternal          storage.ErrorCode = "internal_error"
+)
+
+// errBadRequest - kind of errors caused by a request that can't be understood at all (as opposed to invalid input)
+var errBadRequest = errors.New("bad request")
+
+func badRequest(code storage.ErrorCode, format string, args ...any) error {
+	wrapped := fmt.Errorf(format, args...)
+
+	return &storage.Error{
+		Kind:    errBadRequest,
+		Code:    code,
+		Message: wrapped.Error(),
+		Err:     errors.Unwrap(wrapped),
+	}
+}
+
+// errorStatus - HTTP status and error code of the error. Errors of unknown kinds are internal ones
+func errorStatus(err error) (int, storage.ErrorCode) {
+	code := CodeInternal
+	var typed *storage.Error
+	if errors.As(err, &typed) {
+		code = typed.Code
+	}
+
+	switch {
+	case errors.Is(err, errBadRequest):
+		return http.StatusBadRequest, code
+	case errors.Is(err, storage.ErrInvalidInput):
+		return http.StatusUnprocessableEntity, code
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound, code
+	case errors.Is(err, storage.ErrConflict):
+		return http.StatusConflict, code
+	case errors.Is(err, storage.ErrNoCapacity):
+		return http.StatusServiceUnavailable, code
+	case errors.Is(err, storage.ErrUnsupported):
+		return http.StatusNotImplemented, code
+	}
+
+	return http.StatusInternalServerError, CodeInternal
+}
+
+// pathID - numeric ID from the path parameter `name`
+func pathID(c *gin.Context, name string) (int, error) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		return 0, badRequest(CodeInvalidID, "%s must be a number, got '%s'", name, c.Param(name))
+	}
+
+	return id, nil
+}
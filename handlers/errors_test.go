@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"leads/storage"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   storage.ErrorCode
+	}{
+		{"bad request", badRequest(CodeInvalidID, "id must be a number"), http.StatusBadRequest, CodeInvalidID},
+		{"invalid input", storageError(storage.ErrInvalidInput, storage.CodeInvalidSort), http.StatusUnprocessableEntity, storage.CodeInvalidSort},
+		{"not found", storageError(storage.ErrNotFound, storage.CodeClientNotFound), http.StatusNotFound, storage.CodeClientNotFound},
+		{"conflict", storageError(storage.ErrConflict, storage.CodeClientArchived), http.StatusConflict, storage.CodeClientArchived},
+		{"no capacity", storageError(storage.ErrNoCapacity, storage.CodeNoClientsAvailable), http.StatusServiceUnavailable, storage.CodeNoClientsAvailable},
+		{"unsupported", storageError(storage.ErrUnsupported, storage.CodeBackupUnsupported), http.StatusNotImplemented, storage.CodeBackupUnsupported},
+		{
+			name:   "wrapped",
+			err:    fmt.Errorf("assign: %w", storageError(storage.ErrNoCapacity, storage.CodeNoClientsAvailable)),
+			status: http.StatusServiceUnavailable,
+			code:   storage.CodeNoClientsAvailable,
+		},
+		{"unknown kind", storageError(errors.New("disk"), storage.CodeClientNotFound), http.StatusInternalServerError, CodeInternal},
+		{"plain", errors.New("database is locked"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, code := errorStatus(tt.err); status != tt.status || code != tt.code {
+				t.Errorf("errorStatus = %d %s, want %d %s", status, code, tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func TestPathID(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+		err   bool
+	}{
+		{"42", 42, false},
+		{"-1", -1, false},
+		{"abc", 0, true},
+		{"1.5", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Params = gin.Params{{Key: "id", Value: tt.value}}
+
+		id, err := pathID(c, "id")
+		if id != tt.want || (err != nil) != tt.err {
+			t.Errorf("pathID(%q) = %d, %v, want %d", tt.value, id, err, tt.want)
+		}
+		if status, code := errorStatus(err); err != nil && (status != http.StatusBadRequest || code != CodeInvalidID) {
+			t.Errorf("pathID(%q) error = %d %s, want %d %s", tt.value, status, code, http.StatusBadRequest, CodeInvalidID)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"leads/export"
+	"leads/storage"
+)
+
+var (
+	clientExportColumns = []string{"id", "name", "start_date", "end_date", "priority", "lead_capacity", "group_id", "used_capacity", "metadata"}
+	leadExportColumns   = []string{"lead_id", "client_id", "status", "lead_start", "lead_end", "metadata"}
+)
+
+type ExportHandlers struct {
+	*BasicHandler
+	clients storage.ClientRepository
+	leads   storage.LeadRepository
+}
+
+func NewExportHandlers(clients storage.ClientRepository, leads storage.LeadRepository) *ExportHandlers {
+	return &ExportHandlers{
+		clients: clients,
+		leads:   leads,
+	}
+}
+
+func (h *ExportHandlers) InstallRoutes(r gin.IRouter) {
+	e := r.Group("/export")
+
+	e.GET("/clients", h.ExportClients)
+	e.GET("/leads", h.ExportLeads)
+}
+
+// ExportClients streams clients as a file
+//
+// @Summary Streams clients as a file
+// @Description Rows are streamed from the database as they are read. Format is taken from `format` query parameter
+// @Description or from the Accept header (`text/csv`, `application/x-ndjson`, `application/vnd.openxmlformats-officedocument.spreadsheetml.sheet`), CSV by default.
+// @Description Accepts the same filters and sort as GET /clients.
+// @Description CSV and XLSX text starting with `=`, `+`, `-` or `@` is prefixed with `'`, so spreadsheets don't run it as a formula.
+// @Param format query string false "File format" Enums(csv, ndjson, xlsx)
+// @Param priority query []string false "Priorities of clients" collectionFormat(multi) Enums(HIGH, MEDIUM, LOW)
+// @Param active_from query string false "Client's time frame starts not later than this date"
+// @Param active_to query string false "Client's time frame ends not earlier than this date"
+// @Param has_capacity query bool false "Client still can receive leads"
+// @Param name_prefix query string false "Beginning of the client's name"
+// @Param metadata[key] query string false "Metadata value, e.g. metadata[crm.id]=42. Can be repeated for several keys"
+// @Param sort query string false "Sort field, prefix with '-' for descending order" default(id)
+// @Tags export
+// @Produce text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Failure	400	{object} ValidationErrorResponse
+// @Failure	422	{object} ValidationErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Success 200 {file} file
+// @Router /export/clients [get]
+func (h *ExportHandlers) ExportClients(c *gin.Context) {
+	var filter storage.ClientsFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		h.sendBindError(c, err)
+		return
+	}
+	filter.Metadata = c.QueryMap("metadata")
+
+	h.stream(c, "clients", clientExportColumns, func(write func(values ...any) error) error {
+		return h.clients.EachClient(c, filter, func(client storage.Client, used int) error {
+			return write(
+				client.ID,
+				client.Name,
+				client.StartDate,
+				client.EndDate,
+				client.Priority,
+				client.LeadCapacity,
+				optional(client.GroupID),
+				used,
+				client.Metadata,
+			)
+		})
+	})
+}
+
+// ExportLeads streams leads as a file
+//
+// @Summary Streams leads as a file
+// @Description Rows are streamed from the database as they are read. Format is taken from `format` query parameter
+// @Description or from the Accept header (`text/csv`, `application/x-ndjson`, `application/vnd.openxmlformats-officedocument.spreadsheetml.sheet`), CSV by default.
+// @Description Accepts the same filters as GET /leads.
+// @Description CSV and XLSX text starting with `=`, `+`, `-` or `@` is prefixed with `'`, so spreadsheets don't run it as a formula.
+// @Param format query string false "File format" Enums(csv, ndjson, xlsx)
+// @Param client_id query int false "Client ID"
+// @Param status query string false "Lead status" Enums(ASSIGNED, PENDING)
+// @Param from query string false "Lead starts not earlier than this date"
+// @Param to query string false "Lead ends not later than this date"
+// @Param metadata[key] query string false "Metadata value, e.g. metadata[source]=web. Can be repeated for several keys"
+// @Tags export
+// @Produce text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Failure	400	{object} ValidationErrorResponse
+// @Failure	422	{object} ValidationErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Success 200 {file} file
+// @Router /export/leads [get]
+func (h *ExportHandlers) ExportLeads(c *gin.Context) {
+	var filter storage.LeadsFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		h.sendBindError(c, err)
+		return
+	}
+	filter.Metadata = c.QueryMap("metadata")
+
+	h.stream(c, "leads", leadExportColumns, func(write func(values ...any) error) error {
+		return h.leads.EachLead(c, filter, func(lead storage.Lead) error {
+			return write(lead.LeadID, lead.ClientID, lead.Status, lead.LeadStart, lead.LeadEnd, lead.Metadata)
+		})
+	})
+}
+
+// stream - writes rows produced by `each` as a file. The response starts with the first row,
+// so errors that happen before it (e.g. invalid filters) are still sent as a regular error response
+func (h *ExportHandlers) stream(c *gin.Context, name string, columns []string, each func(write func(values ...any) error) error) {
+	format := c.Query("format")
+	if format == "" {
+		format = export.FromAccept(c.GetHeader("Accept"))
+	}
+	if format == "" {
+		format = export.CSV
+	}
+	if export.ContentType(format) == "" {
+		h.sendError(c, badRequest(CodeUnsupportedFormat, "unsupported export format '%s'", format))
+		return
+	}
+
+	var w export.Writer
+	start := func() error {
+		if w != nil {
+			return nil
+		}
+
+		c.Header("Content-Type", export.ContentType(format))
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+
+		var err error
+		w, err = export.New(format, c.Writer, columns)
+
+		return err
+	}
+
+	err := each(func(values ...any) error {
+		if err := start(); err != nil {
+			return err
+		}
+
+		return w.WriteRow(values...)
+	})
+	if err == nil {
+		err = start()
+	}
+	if err == nil {
+		err = w.Close()
+	}
+
+	if err != nil && w == nil {
+		h.sendError(c, err)
+		return
+	}
+	if err != nil {
+		// Part of the file is already sent, the error can only be logged
+		_ = c.Error(err)
+	}
+}
+
+// optional - unwraps an optional value, so it is written as a value or as an empty cell
+func optional[T any](v *T) any {
+	if v == nil {
+		return nil
+	}
+
+	return *v
+}
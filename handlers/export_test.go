@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"leads/storage"
+	"leads/storage/storagefake"
+)
+
+func TestExportClients(t *testing.T) {
+	clients := &storagefake.ClientRepository{
+		EachClientFunc: func(ctx context.Context, f storage.ClientsFilter, fn func(c storage.Client, used int) error) error {
+			if f.NamePrefix != "ac" {
+				t.Errorf("filter = %+v, want name prefix ac", f)
+			}
+			client := storage.Client{
+				ID:           1,
+				Name:         "acme",
+				StartDate:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				EndDate:      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				Priority:     "HIGH",
+				LeadCapacity: 5,
+				GroupID:      ptr(3),
+			}
+			return fn(client, 2)
+		},
+	}
+	r := newRouter(NewExportHandlers(clients, nil))
+
+	w := send(t, r, http.MethodGet, "/export/clients?name_prefix=ac", "")
+
+	assertStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="clients.csv"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	want := "id,name,start_date,end_date,priority,lead_capacity,group_id,used_capacity,metadata\n" +
+		"1,acme,2024-01-01T00:00:00Z,2024-02-01T00:00:00Z,HIGH,5,3,2,\n"
+	if got := strings.ReplaceAll(w.Body.String(), "\r\n", "\n"); got != want {
+		t.Errorf("export =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportClientsErrors(t *testing.T) {
+	t.Run("unsupported format", func(t *testing.T) {
+		w := send(t, newRouter(NewExportHandlers(&storagefake.ClientRepository{}, nil)), http.MethodGet, "/export/clients?format=pdf", "")
+
+		assertError(t, w, http.StatusBadRequest, CodeUnsupportedFormat)
+	})
+
+	t.Run("fails before the first row", func(t *testing.T) {
+		clients := &storagefake.ClientRepository{
+			EachClientFunc: func(ctx context.Context, f storage.ClientsFilter, fn func(c storage.Client, used int) error) error {
+				return storageError(storage.ErrInvalidInput, storage.CodeInvalidMetadataKey)
+			},
+		}
+
+		w := send(t, newRouter(NewExportHandlers(clients, nil)), http.MethodGet, "/export/clients?metadata[a-b]=1", "")
+
+		assertError(t, w, http.StatusUnprocessableEntity, storage.CodeInvalidMetadataKey)
+	})
+
+	t.Run("fails after the first row", func(t *testing.T) {
+		clients := &storagefake.ClientRepository{
+			EachClientFunc: func(ctx context.Context, f storage.ClientsFilter, fn func(c storage.Client, used int) error) error {
+				if err := fn(storage.Client{ID: 1}, 0); err != nil {
+					return err
+				}
+				return errors.New("connection lost")
+			},
+		}
+
+		w := send(t, newRouter(NewExportHandlers(clients, nil)), http.MethodGet, "/export/clients", "")
+
+		// The file has started, so the error is logged instead of being sent as an error response
+		assertStatus(t, w, http.StatusOK)
+		if got := w.Header().Get("Content-Type"); got != "text/csv" {
+			t.Errorf("Content-Type = %q, want the file's one", got)
+		}
+		if strings.Contains(w.Body.String(), "internal_error") {
+			t.Errorf("an error response was written into the file: %q", w.Body.String())
+		}
+	})
+}
+
+func TestExportLeads(t *testing.T) {
+	leads := &storagefake.LeadRepository{
+		EachLeadFunc: func(ctx context.Context, f storage.LeadsFilter, fn func(l storage.Lead) error) error {
+			if f.Status != storage.LeadStatusAssigned {
+				t.Errorf("filter = %+v, want assigned leads", f)
+			}
+			return fn(storage.Lead{
+				LeadID:    "a",
+				ClientID:  1,
+				Status:    storage.LeadStatusAssigned,
+				LeadStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				LeadEnd:   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				Metadata:  storage.Metadata(`{"source":"web"}`),
+			})
+		},
+	}
+	r := newRouter(NewExportHandlers(nil, leads))
+	req := request(http.MethodGet, "/export/leads?status=ASSIGNED", "")
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	w := serve(t, r, req)
+
+	assertStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	want := `{"lead_id":"a","client_id":1,"status":"ASSIGNED","lead_start":"2024-01-01T00:00:00Z","lead_end":"2024-01-02T00:00:00Z","metadata":{"source":"web"}}` + "\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("export = %s, want %s", got, want)
+	}
+
+	assertError(t, send(t, r, http.MethodGet, "/export/leads?status=LOST", ""), http.StatusUnprocessableEntity, CodeValidationFailed)
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"leads/storage"
+)
+
+type GroupsHandlers struct {
+	*BasicHandler
+	groups storage.GroupRepository
+}
+
+func NewGroupsHandlers(groups storage.GroupRepository) *GroupsHandlers {
+	return &GroupsHandlers{
+		groups: groups,
+	}
+}
+
+func (h *GroupsHandlers) InstallRoutes(r gin.IRouter) {
+	g := r.Group("/groups")
+
+	g.POST("/", h.CreateGroup)
+	g.GET("/", h.GetGroups)
+	g.GET("/:id", h.GetGroup)
+	g.PUT("/:id", h.UpdateGroup)
+	g.DELETE("/:id", h.DeleteGroup)
+	g.POST("/:id/members", h.AddMember)
+	g.DELETE("/:id/members/:client_id", h.RemoveMember)
+}
+
+// CreateGroup creates a new client group
+//
+// @Summary Creates a new client group
+// @Description Group has its own capacity pool shared by all members. A lead is assigned to a member only when both
+// @Description the member and its group have free capacity.
+// @Param _ body storage.ClientGroupRequest true "New group payload"
+// @Tags group
+// @Produce json
+// @Failure	400	{object} ValidationErrorResponse
+// @Failure	422	{object} ValidationErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Success 201 {object} storage.ClientGroup
+// @Header 201 {string} Location "URL of the created group"
+// @Router /groups [post]
+func (h *GroupsHandlers) CreateGroup(c *gin.Context) {
+	var body storage.ClientGroupRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		h.sendBindError(c, err)
+		return
+	}
+
+	group, err := h.groups.CreateGroup(c, body)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	h.sendCreated(c, fmt.Sprintf("/groups/%d", group.ID), group)
+}
+
+// GetGroups receives a list of client groups
+//
+// @Summary Receives a list of client groups
+// @Tags group
+// @Produce json
+// @Failure	500	{object} ErrorResponse
+// @Success 200 {object} []storage.ClientGroup
+// @Router /groups [get]
+func (h *GroupsHandlers) GetGroups(c *gin.Context) {
+	groups, err := h.groups.GetGroups(c, nil)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if groups == nil {
+		groups = []storage.ClientGroup{}
+	}
+
+	h.sendOk(c, groups)
+}
+
+// GetGroup get client group by groupID
+//
+// @Summary Get client group by groupID
+// @Param id path string true "Group ID"
+// @Tags group
+// @Produce json
+// @Failure	400	{object} ErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 200 {object} storage.ClientGroup
+// @Router /groups/{id} [get]
+func (h *GroupsHandlers) GetGroup(c *gin.Context) {
+	groupID, err := pathID(c, "id")
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	groups, err := h.groups.GetGroups(c, &groupID)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if len(groups) == 0 {
+		h.groupNotFound(c)
+		return
+	}
+
+	h.sendOk(c, groups[0])
+}
+
+// UpdateGroup updates name and capacity of the client group
+//
+// @Summary Updates name and capacity of the client group
+// @Param id path string true "Group ID"
+// @Param _ body storage.ClientGroupRequest true "Group payload"
+// @Tags group
+// @Produce json
+// @Failure	400	{object} ValidationErrorResponse
+// @Failure	422	{object} ValidationErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 200 {object} storage.ClientGroup
+// @Router /groups/{id} [put]
+func (h *GroupsHandlers) UpdateGroup(c *gin.Context) {
+	groupID, err := pathID(c, "id")
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	var body storage.ClientGroupRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		h.sendBindError(c, err)
+		return
+	}
+
+	group, err := h.groups.UpdateGroup(c, groupID, body)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if group == nil {
+		h.groupNotFound(c)
+		return
+	}
+
+	h.sendOk(c, group)
+}
+
+// DeleteGroup deletes the client group
+//
+// @Summary Deletes the client group
+// @Description Members of the group become standalone clients and keep their leads.
+// @Param id path string true "Group ID"
+// @Tags group
+// @Produce json
+// @Failure	400	{object} ErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 204
+// @Router /groups/{id} [delete]
+func (h *GroupsHandlers) DeleteGroup(c *gin.Context) {
+	groupID, err := pathID(c, "id")
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	deleted, err := h.groups.DeleteGroup(c, groupID)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if !deleted {
+		h.groupNotFound(c)
+		return
+	}
+
+	h.sendNoContent(c)
+}
+
+// AddMember adds a client to the group
+//
+// @Summary Adds a client to the group
+// @Description A client belongs to one group at most, so it leaves its previous group.
+// @Param id path string true "Group ID"
+// @Param _ body storage.GroupMemberRequest true "Member payload"
+// @Tags group
+// @Produce json
+// @Failure	400	{object} ValidationErrorResponse
+// @Failure	422	{object} ValidationErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 200 {object} storage.ClientGroup
+// @Router /groups/{id}/members [post]
+func (h *GroupsHandlers) AddMember(c *gin.Context) {
+	groupID, err := pathID(c, "id")
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	var body storage.GroupMemberRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		h.sendBindError(c, err)
+		return
+	}
+
+	group, err := h.groups.SetGroupMember(c, &groupID, body.ClientID)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if group == nil {
+		h.notFound(c, storage.CodeGroupNotFound, "group with ID '%d' or client with ID '%d' was not found", groupID, body.ClientID)
+		return
+	}
+
+	h.sendOk(c, group)
+}
+
+// RemoveMember removes a client from the group
+//
+// @Summary Removes a client from the group
+// @Param id path string true "Group ID"
+// @Param client_id path string true "Client ID"
+// @Tags group
+// @Produce json
+// @Failure	400	{object} ErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 200 {object} storage.ClientGroup
+// @Router /groups/{id}/members/{client_id} [delete]
+func (h *GroupsHandlers) RemoveMember(c *gin.Context) {
+	groupID, err := pathID(c, "id")
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	clientID, err := pathID(c, "client_id")
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	group, err := h.groups.RemoveGroupMember(c, groupID, clientID)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if group == nil {
+		h.notFound(c, storage.CodeClientNotFound, "client with ID '%d' is not a member of group with ID '%d'", clientID, groupID)
+		return
+	}
+
+	h.sendOk(c, group)
+}
+
+func (h *GroupsHandlers) groupNotFound(c *gin.Context) {
+	h.notFound(c, storage.CodeGroupNotFound, "group with ID '%s' was not found", c.Param("id"))
+}
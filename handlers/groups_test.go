@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"leads/storage"
+	"leads/storage/storagefake"
+)
+
+func TestCreateGroup(t *testing.T) {
+	groups := &storagefake.GroupRepository{
+		CreateGroupFunc: func(ctx context.Context, g storage.ClientGroupRequest) (*storage.ClientGroup, error) {
+			return &storage.ClientGroup{ID: 4, Name: g.Name, LeadCapacity: g.LeadCapacity, Members: []int{}}, nil
+		},
+	}
+	r := newRouter(NewGroupsHandlers(groups))
+
+	w := send(t, r, http.MethodPost, "/groups/", `{"name":"agency","lead_capacity":20}`)
+	assertStatus(t, w, http.StatusCreated)
+	if got := w.Header().Get("Location"); got != "/groups/4" {
+		t.Errorf("Location = %q, want /groups/4", got)
+	}
+
+	for name, body := range map[string]string{
+		"negative capacity": `{"name":"agency","lead_capacity":-5}`,
+		"missing capacity":  `{"name":"agency"}`,
+		"missing name":      `{"lead_capacity":5}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assertError(t, send(t, r, http.MethodPost, "/groups/", body), http.StatusUnprocessableEntity, CodeValidationFailed)
+		})
+	}
+}
+
+func TestGetGroups(t *testing.T) {
+	var list []storage.ClientGroup
+	groups := &storagefake.GroupRepository{
+		GetGroupsFunc: func(ctx context.Context, groupID *int) ([]storage.ClientGroup, error) {
+			if groupID == nil {
+				return list, nil
+			}
+			if *groupID == 1 {
+				return []storage.ClientGroup{{ID: 1, Members: []int{2}}}, nil
+			}
+			return nil, nil
+		},
+	}
+	r := newRouter(NewGroupsHandlers(groups))
+
+	w := send(t, r, http.MethodGet, "/groups/", "")
+	assertStatus(t, w, http.StatusOK)
+	if w.Body.String() != "[]" {
+		t.Errorf("no groups = %s, want []", w.Body.String())
+	}
+
+	list = []storage.ClientGroup{{ID: 1}, {ID: 2}}
+	if got := decode[[]storage.ClientGroup](t, send(t, r, http.MethodGet, "/groups/", "")); len(got) != 2 {
+		t.Errorf("groups = %+v", got)
+	}
+
+	w = send(t, r, http.MethodGet, "/groups/1", "")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.ClientGroup](t, w); got.ID != 1 || len(got.Members) != 1 {
+		t.Errorf("group = %+v", got)
+	}
+
+	assertError(t, send(t, r, http.MethodGet, "/groups/2", ""), http.StatusNotFound, storage.CodeGroupNotFound)
+	assertError(t, send(t, r, http.MethodGet, "/groups/two", ""), http.StatusBadRequest, CodeInvalidID)
+}
+
+func TestUpdateGroup(t *testing.T) {
+	groups := &storagefake.GroupRepository{
+		UpdateGroupFunc: func(ctx context.Context, groupID int, g storage.ClientGroupRequest) (*storage.ClientGroup, error) {
+			if groupID != 1 {
+				return nil, nil
+			}
+			return &storage.ClientGroup{ID: 1, Name: g.Name, LeadCapacity: g.LeadCapacity}, nil
+		},
+	}
+	r := newRouter(NewGroupsHandlers(groups))
+
+	w := send(t, r, http.MethodPut, "/groups/1", `{"name":"renamed","lead_capacity":3}`)
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.ClientGroup](t, w); got.Name != "renamed" || got.LeadCapacity != 3 {
+		t.Errorf("group = %+v", got)
+	}
+
+	assertError(t, send(t, r, http.MethodPut, "/groups/2", `{"name":"x","lead_capacity":3}`), http.StatusNotFound, storage.CodeGroupNotFound)
+	assertError(t, send(t, r, http.MethodPut, "/groups/1", `{"name":"x","lead_capacity":0}`), http.StatusUnprocessableEntity, CodeValidationFailed)
+}
+
+func TestDeleteGroup(t *testing.T) {
+	groups := &storagefake.GroupRepository{
+		DeleteGroupFunc: func(ctx context.Context, groupID int) (bool, error) {
+			return groupID == 1, nil
+		},
+	}
+	r := newRouter(NewGroupsHandlers(groups))
+
+	w := send(t, r, http.MethodDelete, "/groups/1", "")
+	assertStatus(t, w, http.StatusNoContent)
+	if w.Body.Len() != 0 {
+		t.Errorf("body = %q, want none", w.Body.String())
+	}
+
+	assertError(t, send(t, r, http.MethodDelete, "/groups/2", ""), http.StatusNotFound, storage.CodeGroupNotFound)
+}
+
+func TestAddMember(t *testing.T) {
+	groups := &storagefake.GroupRepository{
+		SetGroupMemberFunc: func(ctx context.Context, groupID *int, clientID int) (*storage.ClientGroup, error) {
+			if groupID == nil {
+				t.Fatal("adding a member moved the client out of its group")
+			}
+			if *groupID != 1 || clientID != 5 {
+				return nil, nil
+			}
+			return &storage.ClientGroup{ID: 1, Members: []int{5}}, nil
+		},
+	}
+	r := newRouter(NewGroupsHandlers(groups))
+
+	w := send(t, r, http.MethodPost, "/groups/1/members", `{"client_id":5}`)
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.ClientGroup](t, w); len(got.Members) != 1 || got.Members[0] != 5 {
+		t.Errorf("group = %+v", got)
+	}
+
+	assertError(t, send(t, r, http.MethodPost, "/groups/1/members", `{"client_id":6}`), http.StatusNotFound, storage.CodeGroupNotFound)
+	assertError(t, send(t, r, http.MethodPost, "/groups/1/members", `{}`), http.StatusUnprocessableEntity, CodeValidationFailed)
+}
+
+func TestRemoveMember(t *testing.T) {
+	groups := &storagefake.GroupRepository{
+		// Membership is checked by the storage, in the same transaction as the removal
+		RemoveGroupMemberFunc: func(ctx context.Context, groupID int, clientID int) (*storage.ClientGroup, error) {
+			if groupID != 1 || clientID != 5 {
+				return nil, nil
+			}
+			return &storage.ClientGroup{ID: 1, Members: []int{}}, nil
+		},
+	}
+	r := newRouter(NewGroupsHandlers(groups))
+
+	w := send(t, r, http.MethodDelete, "/groups/1/members/5", "")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.ClientGroup](t, w); len(got.Members) != 0 {
+		t.Errorf("group = %+v", got)
+	}
+
+	assertError(t, send(t, r, http.MethodDelete, "/groups/2/members/5", ""), http.StatusNotFound, storage.CodeClientNotFound)
+	assertError(t, send(t, r, http.MethodDelete, "/groups/1/members/x", ""), http.StatusBadRequest, CodeInvalidID)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"leads/storage"
+)
+
+type LeadsHandlers struct {
+	*BasicHandler
+	leads storage.LeadRepository
+}
+
+func NewLeadsHandlers(leads storage.LeadRepository) *LeadsHandlers {
+	return &LeadsHandlers{
+		leads: leads,
+	}
+}
+
+func (h *LeadsHandlers) InstallRoutes(r gin.IRouter) {
+	l := r.Group("/leads")
+
+	l.GET("/", h.GetLeads)
+	l.GET("/:id", h.GetLead)
+	l.PUT("/:id/metadata", h.UpdateLeadMetadata)
+}
+
+// GetLeads receives a page of leads
+//
+// @Summary Receives a page of leads
+// @Description Leads are ordered by their start date. Pass `meta.next_cursor` of the response as `cursor` to receive the next page.
+// @Param client_id query int false "Client ID"
+// @Param status query string false "Lead status" Enums(ASSIGNED, PENDING)
+// @Param from query string false "Lead starts not earlier than this date"
+// @Param to query string false "Lead ends not later than this date"
+// @Param metadata[key] query string false "Metadata value, e.g. metadata[source]=web. Can be repeated for several keys"
+// @Param cursor query string false "Cursor of the next page"
+// @Param limit query int false "Page size" default(50) maximum(500)
+// @Tags lead
+// @Produce json
+// @Failure	400	{object} ValidationErrorResponse
+// @Failure	422	{object} ValidationErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Success 200 {object} storage.LeadsPage
+// @Router /leads [get]
+func (h *LeadsHandlers) GetLeads(c *gin.Context) {
+	var filter storage.LeadsFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		h.sendBindError(c, err)
+		return
+	}
+	filter.Metadata = c.QueryMap("metadata")
+
+	page, err := h.leads.ListLeads(c, filter)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	h.sendOk(c, page)
+}
+
+// GetLead get lead by leadID
+//
+// @Summary Get lead by leadID
+// @Param id path string true "Lead ID"
+// @Tags lead
+// @Produce json
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 200 {object} storage.Lead
+// @Router /leads/{id} [get]
+func (h *LeadsHandlers) GetLead(c *gin.Context) {
+	lead, err := h.leads.GetLead(c, c.Param("id"))
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if lead == nil {
+		h.notFound(c, storage.CodeLeadNotFound, "lead with ID '%s' was not found", c.Param("id"))
+		return
+	}
+
+	h.sendOk(c, lead)
+}
+
+// UpdateLeadMetadata replaces metadata of the lead
+//
+// @Summary Replaces metadata of the lead
+// @Description Metadata is an arbitrary JSON object. When a schema for lead metadata is set, metadata must match it.
+// @Param id path string true "Lead ID"
+// @Param _ body object true "Metadata"
+// @Tags lead
+// @Produce json
+// @Failure	422	{object} ErrorResponse
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 200 {object} storage.Lead
+// @Router /leads/{id}/metadata [put]
+func (h *LeadsHandlers) UpdateLeadMetadata(c *gin.Context) {
+	metadata, err := c.GetRawData()
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	lead, err := h.leads.UpdateLeadMetadata(c, c.Param("id"), metadata)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if lead == nil {
+		h.notFound(c, storage.CodeLeadNotFound, "lead with ID '%s' was not found", c.Param("id"))
+		return
+	}
+
+	h.sendOk(c, lead)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"leads/storage"
+	"leads/storage/storagefake"
+)
+
+func TestGetLeads(t *testing.T) {
+	leads := &storagefake.LeadRepository{
+		ListLeadsFunc: func(ctx context.Context, f storage.LeadsFilter) (*storage.LeadsPage, error) {
+			if f.Status != storage.LeadStatusPending || f.ClientID != nil {
+				t.Errorf("filter = %+v, want pending leads of any client", f)
+			}
+			if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !f.From.Equal(want) {
+				t.Errorf("from = %v, want %v", f.From, want)
+			}
+			if f.Metadata["source"] != "web" {
+				t.Errorf("metadata filter = %v", f.Metadata)
+			}
+			return &storage.LeadsPage{Data: []storage.Lead{{LeadID: "a", Status: storage.LeadStatusPending}}}, nil
+		},
+	}
+	r := newRouter(NewLeadsHandlers(leads))
+
+	w := send(t, r, http.MethodGet, "/leads/?status=PENDING&from=2024-01-01%2000:00:00&metadata[source]=web", "")
+
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.LeadsPage](t, w); len(got.Data) != 1 {
+		t.Errorf("page = %+v", got)
+	}
+
+	assertError(t, send(t, r, http.MethodGet, "/leads/?status=LOST", ""), http.StatusUnprocessableEntity, CodeValidationFailed)
+	assertError(t, send(t, r, http.MethodGet, "/leads/?to=soon", ""), http.StatusUnprocessableEntity, CodeValidationFailed)
+}
+
+func TestGetLead(t *testing.T) {
+	leads := &storagefake.LeadRepository{
+		GetLeadFunc: func(ctx context.Context, leadID string) (*storage.Lead, error) {
+			if leadID != "a" {
+				return nil, nil
+			}
+			return &storage.Lead{LeadID: "a", ClientID: 1}, nil
+		},
+	}
+	r := newRouter(NewLeadsHandlers(leads))
+
+	w := send(t, r, http.MethodGet, "/leads/a", "")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.Lead](t, w); got.ClientID != 1 {
+		t.Errorf("lead = %+v", got)
+	}
+
+	assertError(t, send(t, r, http.MethodGet, "/leads/b", ""), http.StatusNotFound, storage.CodeLeadNotFound)
+}
+
+func TestUpdateLeadMetadata(t *testing.T) {
+	leads := &storagefake.LeadRepository{
+		UpdateLeadMetadataFunc: func(ctx context.Context, leadID string, metadata storage.Metadata) (*storage.Lead, error) {
+			switch {
+			case string(metadata) == `"text"`:
+				return nil, storageError(storage.ErrInvalidInput, storage.CodeInvalidMetadata)
+			case leadID != "a":
+				return nil, nil
+			}
+			return &storage.Lead{LeadID: "a", Metadata: metadata}, nil
+		},
+	}
+	r := newRouter(NewLeadsHandlers(leads))
+
+	w := send(t, r, http.MethodPut, "/leads/a/metadata", `{"source":"web"}`)
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.Lead](t, w); string(got.Metadata) != `{"source":"web"}` {
+		t.Errorf("metadata = %s", got.Metadata)
+	}
+
+	assertError(t, send(t, r, http.MethodPut, "/leads/a/metadata", `"text"`), http.StatusUnprocessableEntity, storage.CodeInvalidMetadata)
+	assertError(t, send(t, r, http.MethodPut, "/leads/b/metadata", `{}`), http.StatusNotFound, storage.CodeLeadNotFound)
+}
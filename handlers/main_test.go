@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"leads/storage"
+	"leads/storage/storagefake"
+)
+
+// testedRoutes - routes the tests have sent requests to, as "METHOD /path"
+var testedRoutes sync.Map
+
+// TestMain - fails a full run when a route of the API has no test
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	code := m.Run()
+	if code == 0 && flag.Lookup("test.run").Value.String() == "" {
+		if missing := untestedRoutes(); len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "routes without tests:\n  %s\n", strings.Join(missing, "\n  "))
+			code = 1
+		}
+	}
+
+	os.Exit(code)
+}
+
+type routeInstaller interface {
+	InstallRoutes(r gin.IRouter)
+}
+
+// newRouter - router of the handlers, set up like the server's one
+func newRouter(handlers ...routeInstaller) *gin.Engine {
+	r := gin.New()
+	r.ContextWithFallback = true
+	r.Use(RequestID, func(c *gin.Context) {
+		c.Next()
+		if path := c.FullPath(); path != "" {
+			testedRoutes.Store(c.Request.Method+" "+path, true)
+		}
+	})
+	r.NoRoute(RouteNotFound)
+
+	for _, h := range handlers {
+		h.InstallRoutes(r)
+	}
+
+	return r
+}
+
+// untestedRoutes - routes of all handlers no test has sent a request to
+func untestedRoutes() []string {
+	repo := &storagefake.Repository{}
+	r := newRouter(
+		NewClientsHandlers(repo, repo),
+		NewLeadsHandlers(repo),
+		NewStatsHandlers(repo),
+		NewExportHandlers(repo, repo),
+		NewGroupsHandlers(repo),
+		NewMetadataHandlers(repo),
+		NewAdminHandlers(repo, repo, os.TempDir(), "token"),
+	)
+
+	var missing []string
+	for _, route := range r.Routes() {
+		name := route.Method + " " + route.Path
+		if _, ok := testedRoutes.Load(name); !ok {
+			missing = append(missing, name)
+		}
+	}
+	slices.Sort(missing)
+
+	return missing
+}
+
+// serve - sends the request to the router. A body is sent as JSON unless the request has another content type
+func serve(t *testing.T, r http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	if req.Body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	return w
+}
+
+// request - a request with the body, if it's not empty
+func request(method, target, body string) *http.Request {
+	if body == "" {
+		return httptest.NewRequest(method, target, nil)
+	}
+
+	return httptest.NewRequest(method, target, strings.NewReader(body))
+}
+
+func send(t *testing.T, r http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serve(t, r, request(method, target, body))
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("can't decode response %q: %v", w.Body.String(), err)
+	}
+
+	return v
+}
+
+func assertStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d, body %s", w.Code, status, w.Body.String())
+	}
+}
+
+// assertError - the response is an ErrorResponse with the status and the code
+func assertError(t *testing.T, w *httptest.ResponseRecorder, status int, code storage.ErrorCode) {
+	t.Helper()
+
+	assertStatus(t, w, status)
+	if got := decode[ErrorResponse](t, w); got.Code != code {
+		t.Fatalf("error code = %q, want %q, body %s", got.Code, code, w.Body.String())
+	}
+}
+
+// storageError - error of the kind the storage returns
+func storageError(kind error, code storage.ErrorCode) error {
+	return &storage.Error{Kind: kind, Code: code, Message: string(code)}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
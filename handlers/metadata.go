@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"leads/storage"
+)
+
+type MetadataHandlers struct {
+	*BasicHandler
+	metadata storage.MetadataRepository
+}
+
+func NewMetadataHandlers(metadata storage.MetadataRepository) *MetadataHandlers {
+	return &MetadataHandlers{
+		metadata: metadata,
+	}
+}
+
+func (h *MetadataHandlers) InstallRoutes(r gin.IRouter) {
+	m := r.Group("/metadata/schemas")
+
+	m.GET("/:entity", h.GetSchema)
+	m.PUT("/:entity", h.SetSchema)
+	m.DELETE("/:entity", h.DeleteSchema)
+}
+
+// GetSchema receives JSON Schema of clients or leads metadata
+//
+// @Summary Receives JSON Schema of clients or leads metadata
+// @Description Every tenant has its own schemas, the tenant is taken from the `X-Tenant` header.
+// @Param entity path string true "Entity" Enums(client, lead)
+// @Param X-Tenant header string false "Tenant" default(default)
+// @Tags metadata
+// @Produce json
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 200 {object} storage.MetadataSchema
+// @Router /metadata/schemas/{entity} [get]
+func (h *MetadataHandlers) GetSchema(c *gin.Context) {
+	schema, err := h.metadata.GetMetadataSchema(c, c.Param("entity"))
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if schema == nil {
+		h.schemaNotFound(c)
+		return
+	}
+
+	h.sendOk(c, schema)
+}
+
+// SetSchema sets JSON Schema of clients or leads metadata
+//
+// @Summary Sets JSON Schema of clients or leads metadata
+// @Description Metadata of every client (lead) created or updated by requests of the tenant of the `X-Tenant` header must match the schema. Metadata saved before is not validated again.
+// @Param entity path string true "Entity" Enums(client, lead)
+// @Param X-Tenant header string false "Tenant" default(default)
+// @Param _ body object true "JSON Schema"
+// @Tags metadata
+// @Produce json
+// @Failure	500	{object} ErrorResponse
+// @Success 200 {object} storage.MetadataSchema
+// @Router /metadata/schemas/{entity} [put]
+func (h *MetadataHandlers) SetSchema(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	schema, err := h.metadata.SetMetadataSchema(c, c.Param("entity"), body)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	h.sendOk(c, schema)
+}
+
+// DeleteSchema removes JSON Schema of clients or leads metadata
+//
+// @Summary Removes JSON Schema of clients or leads metadata
+// @Description Removes the schema of the tenant of the `X-Tenant` header.
+// @Param entity path string true "Entity" Enums(client, lead)
+// @Param X-Tenant header string false "Tenant" default(default)
+// @Tags metadata
+// @Produce json
+// @Failure	500	{object} ErrorResponse
+// @Failure	404	{object} ErrorResponse
+// @Success 204
+// @Router /metadata/schemas/{entity} [delete]
+func (h *MetadataHandlers) DeleteSchema(c *gin.Context) {
+	deleted, err := h.metadata.DeleteMetadataSchema(c, c.Param("entity"))
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if !deleted {
+		h.schemaNotFound(c)
+		return
+	}
+
+	h.sendNoContent(c)
+}
+
+func (h *MetadataHandlers) schemaNotFound(c *gin.Context) {
+	h.notFound(c, storage.CodeMetadataSchemaNotFound, "metadata schema of '%s' was not found", c.Param("entity"))
+}
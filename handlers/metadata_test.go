@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"leads/storage"
+	"leads/storage/storagefake"
+)
+
+func TestMetadataSchemas(t *testing.T) {
+	schemas := map[storage.MetadataEntity]json.RawMessage{}
+	metadata := &storagefake.MetadataRepository{
+		GetMetadataSchemaFunc: func(ctx context.Context, entity storage.MetadataEntity) (*storage.MetadataSchema, error) {
+			schema, ok := schemas[entity]
+			if !ok {
+				return nil, nil
+			}
+			return &storage.MetadataSchema{Entity: entity, Schema: schema}, nil
+		},
+		SetMetadataSchemaFunc: func(ctx context.Context, entity storage.MetadataEntity, schema json.RawMessage) (*storage.MetadataSchema, error) {
+			if entity != storage.MetadataClient && entity != storage.MetadataLead {
+				return nil, storageError(storage.ErrInvalidInput, storage.CodeUnknownMetadataEntity)
+			}
+			schemas[entity] = schema
+			return &storage.MetadataSchema{Entity: entity, Schema: schema}, nil
+		},
+		DeleteMetadataSchemaFunc: func(ctx context.Context, entity storage.MetadataEntity) (bool, error) {
+			_, ok := schemas[entity]
+			delete(schemas, entity)
+			return ok, nil
+		},
+	}
+	r := newRouter(NewMetadataHandlers(metadata))
+	schema := `{"type":"object","properties":{"crm_id":{"type":"string"}}}`
+
+	assertError(t, send(t, r, http.MethodGet, "/metadata/schemas/client", ""), http.StatusNotFound, storage.CodeMetadataSchemaNotFound)
+
+	w := send(t, r, http.MethodPut, "/metadata/schemas/client", schema)
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.MetadataSchema](t, w); got.Entity != storage.MetadataClient || string(got.Schema) != schema {
+		t.Errorf("schema = %+v", got)
+	}
+
+	w = send(t, r, http.MethodGet, "/metadata/schemas/client", "")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.MetadataSchema](t, w); string(got.Schema) != schema {
+		t.Errorf("schema = %s", got.Schema)
+	}
+
+	assertError(t, send(t, r, http.MethodPut, "/metadata/schemas/group", schema), http.StatusUnprocessableEntity, storage.CodeUnknownMetadataEntity)
+
+	assertStatus(t, send(t, r, http.MethodDelete, "/metadata/schemas/client", ""), http.StatusNoContent)
+	assertError(t, send(t, r, http.MethodDelete, "/metadata/schemas/client", ""), http.StatusNotFound, storage.CodeMetadataSchemaNotFound)
+}
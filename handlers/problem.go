@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"leads/storage"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	problemTypePrefix  = "urn:leads:problem:"
+
+	// RequestIDHeader - ID of the request, taken from the caller or generated. Sent back and included in problem documents
+	RequestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+)
+
+// Problem - RFC 7807 problem document, sent instead of ErrorResponse when the client accepts application/problem+json
+type Problem struct {
+	Type      string       `json:"type"` // urn:leads:problem:<code>
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance"`
+	RequestID string       `json:"request_id"`
+	Code      string       `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// RequestID - middleware assigning an ID to every request
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(RequestIDHeader)
+	if id == "" {
+		id = uuid.NewString()
+	}
+
+	c.Set(requestIDKey, id)
+	c.Header(RequestIDHeader, id)
+
+	c.Next()
+}
+
+// RouteNotFound - responds to requests of unknown routes like to any other missing resource
+func RouteNotFound(c *gin.Context) {
+	var h BasicHandler
+	h.sendErrorResponse(c, http.StatusNotFound, "route_not_found", "no route for "+c.Request.Method+" "+c.Request.URL.Path, nil)
+}
+
+// sendErrorResponse - sends the error as a problem document or, for clients that don't ask for one, as ErrorResponse.
+// Field errors make the legacy response a ValidationErrorResponse
+func (h *BasicHandler) sendErrorResponse(ctx *gin.Context, status int, code storage.ErrorCode, detail string, fields []FieldError) {
+	if ctx.NegotiateFormat(gin.MIMEJSON, problemContentType) == problemContentType {
+		ctx.Header("Content-Type", problemContentType)
+		ctx.JSON(status, Problem{
+			Type:      problemTypePrefix + code,
+			Title:     http.StatusText(status),
+			Status:    status,
+			Detail:    detail,
+			Instance:  ctx.Request.URL.RequestURI(),
+			RequestID: ctx.GetString(requestIDKey),
+			Code:      code,
+			Errors:    fields,
+		})
+		return
+	}
+
+	if fields != nil {
+		ctx.JSON(status, ValidationErrorResponse{Error: detail, Code: code, Fields: fields})
+		return
+	}
+
+	ctx.JSON(status, ErrorResponse{Error: detail, Code: code})
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"leads/storage/storagefake"
+)
+
+func TestProblemResponse(t *testing.T) {
+	req := request(http.MethodGet, "/clients/abc", "")
+	req.Header.Set("Accept", problemContentType)
+	req.Header.Set(RequestIDHeader, "req-1")
+
+	w := serve(t, clientsRouter(&storagefake.ClientRepository{}, nil), req)
+
+	assertStatus(t, w, http.StatusBadRequest)
+	if got := w.Header().Get("Content-Type"); got != problemContentType {
+		t.Errorf("Content-Type = %q, want %q", got, problemContentType)
+	}
+	problem := decode[Problem](t, w)
+	want := Problem{
+		Type:      "urn:leads:problem:invalid_id",
+		Title:     "Bad Request",
+		Status:    http.StatusBadRequest,
+		Detail:    "id must be a number, got 'abc'",
+		Instance:  "/clients/abc",
+		RequestID: "req-1",
+		Code:      CodeInvalidID,
+	}
+	if problem.Errors != nil || problem.Type != want.Type || problem.Title != want.Title || problem.Status != want.Status ||
+		problem.Detail != want.Detail || problem.Instance != want.Instance || problem.RequestID != want.RequestID || problem.Code != want.Code {
+		t.Errorf("problem = %+v, want %+v", problem, want)
+	}
+}
+
+func TestProblemFieldErrors(t *testing.T) {
+	req := request(http.MethodPost, "/clients/?source=crm", `{"start_date":"2024-01-01T00:00:00Z","end_date":"2024-01-31T00:00:00Z","priority":"HIGH","lead_capacity":1}`)
+	req.Header.Set("Accept", problemContentType)
+
+	w := serve(t, clientsRouter(&storagefake.ClientRepository{}, nil), req)
+
+	assertStatus(t, w, http.StatusUnprocessableEntity)
+	problem := decode[Problem](t, w)
+	want := []FieldError{{Field: "name", Message: "is required"}}
+	if problem.Code != CodeValidationFailed || problem.Instance != "/clients/?source=crm" || !slices.Equal(problem.Errors, want) {
+		t.Errorf("problem = %+v, want the field errors %+v", problem, want)
+	}
+}
+
+// TestLegacyErrorResponse - clients that don't ask for problem documents get ErrorResponse
+func TestLegacyErrorResponse(t *testing.T) {
+	for _, accept := range []string{"", "application/json", "*/*"} {
+		req := request(http.MethodGet, "/clients/abc", "")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		w := serve(t, clientsRouter(&storagefake.ClientRepository{}, nil), req)
+
+		assertError(t, w, http.StatusBadRequest, CodeInvalidID)
+		if got := w.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+			t.Errorf("Accept %q: Content-Type = %q, want JSON", accept, got)
+		}
+		if got := decode[map[string]any](t, w); got["type"] != nil || got["error"] != "id must be a number, got 'abc'" {
+			t.Errorf("Accept %q: response = %v, want an ErrorResponse", accept, got)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	r := clientsRouter(&storagefake.ClientRepository{}, nil)
+
+	req := request(http.MethodGet, "/clients/abc", "")
+	req.Header.Set("Accept", problemContentType)
+	w := serve(t, r, req)
+
+	generated := w.Header().Get(RequestIDHeader)
+	if _, err := uuid.Parse(generated); err != nil {
+		t.Errorf("generated request ID %q is not a UUID: %v", generated, err)
+	}
+	if problem := decode[Problem](t, w); problem.RequestID != generated {
+		t.Errorf("problem request ID = %q, want %q", problem.RequestID, generated)
+	}
+	if next := serve(t, r, request(http.MethodGet, "/clients/abc", "")).Header().Get(RequestIDHeader); next == generated {
+		t.Errorf("two requests got the same ID %q", next)
+	}
+
+	req = request(http.MethodGet, "/clients/abc", "")
+	req.Header.Set(RequestIDHeader, "req-1")
+	if got := serve(t, r, req).Header().Get(RequestIDHeader); got != "req-1" {
+		t.Errorf("request ID = %q, want the caller's one", got)
+	}
+}
+
+func TestRouteNotFound(t *testing.T) {
+	w := send(t, clientsRouter(nil, nil), http.MethodGet, "/nothing", "")
+
+	assertError(t, w, http.StatusNotFound, "route_not_found")
+
+	req := request(http.MethodDelete, "/nothing?a=1", "")
+	req.Header.Set("Accept", problemContentType)
+	w = serve(t, clientsRouter(nil, nil), req)
+
+	assertStatus(t, w, http.StatusNotFound)
+	problem := decode[Problem](t, w)
+	if problem.Code != "route_not_found" || problem.Detail != "no route for DELETE /nothing" || problem.Instance != "/nothing?a=1" {
+		t.Errorf("problem = %+v", problem)
+	}
+}
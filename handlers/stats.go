@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"leads/storage"
+)
+
+type StatsHandlers struct {
+	*BasicHandler
+	stats storage.StatsRepository
+}
+
+func NewStatsHandlers(stats storage.StatsRepository) *StatsHandlers {
+	return &StatsHandlers{
+		stats: stats,
+	}
+}
+
+func (h *StatsHandlers) InstallRoutes(r gin.IRouter) {
+	s := r.Group("/stats")
+
+	s.GET("/clients", h.GetClientsStats)
+}
+
+// GetClientsStats receives capacity utilization of clients
+//
+// @Summary Receives capacity utilization of clients
+// @Description Returns used and available capacity of every active client, percentage of free capacity
+// @Description (the same metric the assignment engine ranks clients by), leads per day of the elapsed part of the time frame,
+// @Description time until the time frame ends, and totals per priority.
+// @Tags stats
+// @Produce json
+// @Failure	500	{object} ErrorResponse
+// @Success 200 {object} storage.ClientsStats
+// @Router /stats/clients [get]
+func (h *StatsHandlers) GetClientsStats(c *gin.Context) {
+	stats, err := h.stats.ClientsStats(c)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	h.sendOk(c, stats)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"leads/storage"
+	"leads/storage/storagefake"
+)
+
+func TestGetClientsStats(t *testing.T) {
+	var failure error
+	stats := &storagefake.StatsRepository{
+		ClientsStatsFunc: func(ctx context.Context) (*storage.ClientsStats, error) {
+			if failure != nil {
+				return nil, failure
+			}
+			return &storage.ClientsStats{
+				Clients:    []storage.ClientStats{{ClientID: 1, FreePercentage: 40}},
+				Priorities: map[storage.Priority]storage.PriorityStats{"HIGH": {Clients: 1}},
+			}, nil
+		},
+	}
+	r := newRouter(NewStatsHandlers(stats))
+
+	w := send(t, r, http.MethodGet, "/stats/clients", "")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[storage.ClientsStats](t, w); len(got.Clients) != 1 || got.Priorities["HIGH"].Clients != 1 {
+		t.Errorf("stats = %+v", got)
+	}
+
+	failure = errors.New("database is locked")
+	assertError(t, send(t, r, http.MethodGet, "/stats/clients", ""), http.StatusInternalServerError, CodeInternal)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"leads/storage"
+)
+
+// ValidationErrorResponse - the request can't be processed because of the listed fields
+type ValidationErrorResponse struct {
+	Error  string            `json:"error"`
+	Code   storage.ErrorCode `json:"code"`
+	Fields []FieldError      `json:"fields"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func init() {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// Report fields by the names clients send them with
+	engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+
+		return field.Name
+	})
+
+	// Dates are validated as time.Time, so `required` fails on unset ones. Values that are not dates stay strings
+	engine.RegisterCustomTypeFunc(func(v reflect.Value) any {
+		t := v.Interface().(storage.Timestamp)
+		if !t.Valid() {
+			return t.Raw()
+		}
+		return t.Time
+	}, storage.Timestamp{})
+
+	_ = engine.RegisterValidation("timestamp", validateTimestamp, true)
+	_ = engine.RegisterValidation("after", validateAfter)
+}
+
+// validateTimestamp - the value of a storage.Timestamp field was a date (or was not set)
+func validateTimestamp(fl validator.FieldLevel) bool {
+	_, ok := fl.Field().Interface().(time.Time)
+	return ok
+}
+
+// validateAfter - `after=Field` checks that the date is later than the date of the sibling Field.
+// Unset dates and values that are not dates are left to the `required` and `timestamp` rules of their fields
+func validateAfter(fl validator.FieldLevel) bool {
+	end, ok := dateValue(fl.Field())
+	if !ok {
+		return true
+	}
+
+	start, ok := dateValue(fl.Parent().FieldByName(fl.Param()))
+	if !ok {
+		return true
+	}
+
+	return end.After(start)
+}
+
+// dateValue - the set date of a time.Time or storage.Timestamp value
+func dateValue(v reflect.Value) (time.Time, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return time.Time{}, false
+	}
+
+	var date time.Time
+	switch value := v.Interface().(type) {
+	case time.Time:
+		date = value
+	case storage.Timestamp:
+		if !value.Valid() {
+			return time.Time{}, false
+		}
+		date = value.Time
+	}
+
+	return date, !date.IsZero()
+}
+
+// sendBindError - responds 422 with the failed rules of each field when the request is well-formed but invalid,
+// and 400 when it can't be decoded at all
+func (h *BasicHandler) sendBindError(ctx *gin.Context, err error) {
+	_ = ctx.Error(err).SetType(gin.ErrorTypeBind)
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		h.sendErrorResponse(ctx, http.StatusUnprocessableEntity, CodeValidationFailed, "validation failed", fieldErrors(validationErrs))
+		return
+	}
+
+	detail, fields := "malformed request", []FieldError{}
+
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		fields = append(fields, FieldError{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("must be %s, got %s", jsonType(typeErr.Type), typeErr.Value),
+		})
+	case errors.As(err, &syntaxErr):
+		detail = fmt.Sprintf("malformed JSON at offset %d: %s", syntaxErr.Offset, syntaxErr.Error())
+	case errors.Is(err, io.EOF):
+		detail = "request body is empty"
+	default:
+		detail = fmt.Sprintf("malformed request: %s", err.Error())
+	}
+
+	h.sendErrorResponse(ctx, http.StatusBadRequest, CodeMalformedRequest, detail, fields)
+}
+
+func fieldErrors(errs validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, FieldError{Field: fieldPath(fe), Message: fieldMessage(fe)})
+	}
+
+	return fields
+}
+
+// fieldPath - JSON path of the field without the name of the request struct, e.g. `priority[1]`
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+
+	return path
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "timestamp":
+		return fmt.Sprintf("must be an RFC 3339 or YYYY-MM-DD HH:MM:SS date, got '%v'", fe.Value())
+	case "after":
+		return fmt.Sprintf("must be later than %s", fieldName(fe.Param()))
+	case "oneof":
+		return fmt.Sprintf("must be one of %s, got '%v'", strings.ReplaceAll(fe.Param(), " ", ", "), fe.Value())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+	case "lte", "max":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	}
+
+	return fmt.Sprintf("failed on the '%s' rule", fe.Tag())
+}
+
+// validationMessage - one-line description of the field errors, used where there is no room for a list
+func validationMessage(err error) string {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err.Error()
+	}
+
+	messages := make([]string, 0, len(validationErrs))
+	for _, field := range fieldErrors(validationErrs) {
+		messages = append(messages, field.Field+" "+field.Message)
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// fieldName - snake_case name of a Go struct field, as clients know it
+func fieldName(goName string) string {
+	var name strings.Builder
+	for i, r := range goName {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			name.WriteByte('_')
+		}
+		name.WriteRune(r)
+	}
+
+	return strings.ToLower(name.String())
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+
+	return "an object"
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"leads/storage"
+	"leads/storage/storagefake"
+)
+
+func TestCreateClientValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   storage.ErrorCode
+	}{
+		{name: "malformed JSON", body: `{"name":`, status: http.StatusBadRequest, code: CodeMalformedRequest},
+		{name: "missing name", body: `{"start_date":"2024-01-01T00:00:00Z","end_date":"2024-02-01T00:00:00Z","priority":"LOW","lead_capacity":1}`, status: http.StatusUnprocessableEntity, code: CodeValidationFailed},
+		{name: "end before start", body: `{"name":"a","start_date":"2024-02-01T00:00:00Z","end_date":"2024-01-01T00:00:00Z","priority":"LOW","lead_capacity":1}`, status: http.StatusUnprocessableEntity, code: CodeValidationFailed},
+		{name: "invalid date", body: `{"name":"a","start_date":"tomorrow","end_date":"2024-01-01T00:00:00Z","priority":"LOW","lead_capacity":1}`, status: http.StatusUnprocessableEntity, code: CodeValidationFailed},
+		{name: "unknown priority", body: `{"name":"a","start_date":"2024-01-01T00:00:00Z","end_date":"2024-02-01T00:00:00Z","priority":"URGENT","lead_capacity":1}`, status: http.StatusUnprocessableEntity, code: CodeValidationFailed},
+		{name: "no capacity", body: `{"name":"a","start_date":"2024-01-01T00:00:00Z","end_date":"2024-02-01T00:00:00Z","priority":"LOW","lead_capacity":0}`, status: http.StatusUnprocessableEntity, code: CodeValidationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// CreateClient is not set, the storage must not be called
+			w := send(t, clientsRouter(&storagefake.ClientRepository{}, nil), http.MethodPost, "/clients/", tt.body)
+
+			assertError(t, w, tt.status, tt.code)
+		})
+	}
+}
+
+func TestValidationFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   []FieldError
+	}{
+		{
+			name:   "client",
+			method: http.MethodPost,
+			target: "/clients/",
+			body:   `{"start_date":"2024-02-01T00:00:00Z","end_date":"2024-01-01T00:00:00Z","priority":"URGENT","lead_capacity":-1}`,
+			want: []FieldError{
+				{Field: "name", Message: "is required"},
+				{Field: "end_date", Message: "must be later than start_date"},
+				{Field: "priority", Message: "must be one of HIGH, MEDIUM, LOW, got 'URGENT'"},
+				{Field: "lead_capacity", Message: "must be greater than 0"},
+			},
+		},
+		{
+			name:   "lead",
+			method: http.MethodPost,
+			target: "/clients/assign",
+			body:   `{"lead_start":"next monday"}`,
+			want: []FieldError{
+				{Field: "lead_start", Message: "must be an RFC 3339 or YYYY-MM-DD HH:MM:SS date, got 'next monday'"},
+				{Field: "lead_end", Message: "is required"},
+			},
+		},
+		{
+			name:   "query",
+			method: http.MethodGet,
+			target: "/clients/?priority=HIGH&priority=SOON",
+			want:   []FieldError{{Field: "priority[1]", Message: "must be one of HIGH, MEDIUM, LOW, got 'SOON'"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := send(t, clientsRouter(&storagefake.ClientRepository{}, &storagefake.LeadRepository{}), tt.method, tt.target, tt.body)
+
+			assertStatus(t, w, http.StatusUnprocessableEntity)
+			got := decode[ValidationErrorResponse](t, w)
+			if got.Code != CodeValidationFailed || !slices.Equal(got.Fields, tt.want) {
+				t.Errorf("response = %+v, want fields %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMalformedRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		detail string
+		fields []FieldError
+	}{
+		{name: "empty body", detail: "request body is empty"},
+		{name: "broken JSON", body: `{"name":"a",}`, detail: "malformed JSON at offset"},
+		{
+			name:   "wrong type",
+			body:   `{"name":"a","lead_capacity":"ten"}`,
+			detail: "malformed request",
+			fields: []FieldError{{Field: "lead_capacity", Message: "must be an integer, got string"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := send(t, clientsRouter(&storagefake.ClientRepository{}, nil), http.MethodPost, "/clients/", tt.body)
+
+			assertStatus(t, w, http.StatusBadRequest)
+			got := decode[ValidationErrorResponse](t, w)
+			if got.Code != CodeMalformedRequest || !strings.HasPrefix(got.Error, tt.detail) || !slices.Equal(got.Fields, tt.fields) {
+				t.Errorf("response = %+v, want %q with fields %+v", got, tt.detail, tt.fields)
+			}
+		})
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	for goName, want := range map[string]string{"LeadStart": "lead_start", "Name": "name", "StartDate": "start_date"} {
+		if got := fieldName(goName); got != want {
+			t.Errorf("fieldName(%q) = %q, want %q", goName, got, want)
+		}
+	}
+}
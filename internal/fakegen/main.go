@@ -0,0 +1,317 @@
+// fakegen - writes test doubles of the interfaces of a Go file: for every interface a struct with a function field per
+// method, `<Method>Func`. A method calls its function, or panics when it's not set, so a test sets only the methods
+// it expects to be called. Run by `go generate` of the storage package
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	source := flag.String("source", "", "Go file with the interfaces")
+	importPath := flag.String("import", "", "import path of the package of the source file")
+	pkg := flag.String("package", "", "package of the doubles")
+	output := flag.String("o", "", "file the doubles are written to")
+	flag.Parse()
+
+	if *source == "" || *importPath == "" || *pkg == "" || *output == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	code, err := generate(*source, *importPath, *pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(*output, code, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type method struct {
+	name    string
+	params  *ast.FieldList
+	results *ast.FieldList
+}
+
+type generator struct {
+	fset       *token.FileSet
+	interfaces map[string]*ast.InterfaceType
+	// qualifier - name the source package is imported by
+	qualifier string
+	// imports - import paths of the source file by their names
+	imports map[string]string
+	// used - names of the imports the doubles refer to
+	used map[string]bool
+}
+
+func generate(source, importPath, pkg string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, source, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+
+	g := &generator{
+		fset:       fset,
+		interfaces: make(map[string]*ast.InterfaceType),
+		qualifier:  path.Base(importPath),
+		imports:    make(map[string]string),
+		used:       map[string]bool{},
+	}
+
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		g.imports[name] = importPath
+	}
+
+	var names []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		if iface, ok := spec.Type.(*ast.InterfaceType); ok && spec.Name.IsExported() {
+			g.interfaces[spec.Name.Name] = iface
+			names = append(names, spec.Name.Name)
+		}
+		return false
+	})
+
+	var body bytes.Buffer
+	for _, name := range names {
+		methods, err := g.methods(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.writeDouble(&body, name, methods); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by fakegen from %s; DO NOT EDIT.\n\n", path.Base(source))
+	fmt.Fprintf(&out, "// Package %s - test doubles of the %s interfaces\n", pkg, g.qualifier)
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", pkg)
+	used := make([]string, 0, len(g.used))
+	for name := range g.used {
+		used = append(used, g.imports[name])
+	}
+	slices.Sort(used)
+	for _, importPath := range used {
+		fmt.Fprintf(&out, "\t%q\n", importPath)
+	}
+	fmt.Fprintf(&out, "\n\t%q\n)\n\n", importPath)
+	out.Write(body.Bytes())
+
+	code, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format the doubles: %w\n%s", err, out.String())
+	}
+
+	return code, nil
+}
+
+// methods - methods of the interface `name` and of the interfaces it embeds, in their order
+func (g *generator) methods(name string) ([]method, error) {
+	iface, ok := g.interfaces[name]
+	if !ok {
+		return nil, fmt.Errorf("interface %s is not declared in the source file", name)
+	}
+
+	var methods []method
+	for _, field := range iface.Methods.List {
+		switch t := field.Type.(type) {
+		case *ast.FuncType:
+			methods = append(methods, method{name: field.Names[0].Name, params: t.Params, results: t.Results})
+		case *ast.Ident:
+			embedded, err := g.methods(t.Name)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, embedded...)
+		default:
+			return nil, fmt.Errorf("interface %s embeds %T, only interfaces of the source file are supported", name, t)
+		}
+	}
+
+	return methods, nil
+}
+
+func (g *generator) writeDouble(w *bytes.Buffer, name string, methods []method) error {
+	fmt.Fprintf(w, "// %s - double of %s.%s\n", name, g.qualifier, name)
+	fmt.Fprintf(w, "type %s struct {\n", name)
+	for _, m := range methods {
+		signature, err := g.signature(m)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\t%sFunc func%s\n", m.name, signature)
+	}
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "var _ %s.%s = (*%s)(nil)\n\n", g.qualifier, name, name)
+
+	for _, m := range methods {
+		signature, err := g.signature(m)
+		if err != nil {
+			return err
+		}
+
+		// The receiver is named so that it doesn't shadow a parameter
+		fmt.Fprintf(w, "func (fake *%s) %s%s {\n", name, m.name, signature)
+		fmt.Fprintf(w, "\tif fake.%sFunc == nil {\n", m.name)
+		fmt.Fprintf(w, "\t\tpanic(\"unexpected call of %s.%s\")\n", name, m.name)
+		fmt.Fprintf(w, "\t}\n")
+		call := fmt.Sprintf("fake.%sFunc(%s)", m.name, callArguments(m.params))
+		if m.results == nil || len(m.results.List) == 0 {
+			fmt.Fprintf(w, "\t%s\n", call)
+		} else {
+			fmt.Fprintf(w, "\treturn %s\n", call)
+		}
+		fmt.Fprintf(w, "}\n\n")
+	}
+
+	return nil
+}
+
+// signature - parameters and results of the method with the types of the source package qualified
+func (g *generator) signature(m method) (string, error) {
+	var params []string
+	names := argumentNames(m.params)
+	i := 0
+	for _, field := range m.params.List {
+		t, err := g.typeString(field.Type)
+		if err != nil {
+			return "", err
+		}
+		for range max(len(field.Names), 1) {
+			params = append(params, names[i]+" "+t)
+			i++
+		}
+	}
+
+	var results []string
+	if m.results != nil {
+		for _, field := range m.results.List {
+			t, err := g.typeString(field.Type)
+			if err != nil {
+				return "", err
+			}
+			for range max(len(field.Names), 1) {
+				results = append(results, t)
+			}
+		}
+	}
+
+	signature := "(" + strings.Join(params, ", ") + ")"
+	switch len(results) {
+	case 0:
+	case 1:
+		signature += " " + results[0]
+	default:
+		signature += " (" + strings.Join(results, ", ") + ")"
+	}
+
+	return signature, nil
+}
+
+// argumentNames - names of the parameters, unnamed ones get `p<index>`
+func argumentNames(params *ast.FieldList) []string {
+	var names []string
+	for _, field := range params.List {
+		if len(field.Names) == 0 {
+			names = append(names, fmt.Sprintf("p%d", len(names)))
+			continue
+		}
+		for _, name := range field.Names {
+			if name.Name == "_" {
+				name = ast.NewIdent(fmt.Sprintf("p%d", len(names)))
+			}
+			names = append(names, name.Name)
+		}
+	}
+
+	return names
+}
+
+// callArguments - the parameters passed on to the function of the double, the variadic one with `...`
+func callArguments(params *ast.FieldList) string {
+	names := argumentNames(params)
+	if n := len(params.List); n > 0 {
+		if _, variadic := params.List[n-1].Type.(*ast.Ellipsis); variadic {
+			names[len(names)-1] += "..."
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// typeString - the type as written in the doubles package: exported identifiers belong to the source package
+func (g *generator) typeString(expr ast.Expr) (string, error) {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, g.fset, g.qualify(expr)); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (g *generator) qualify(expr ast.Expr) ast.Expr {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if t.IsExported() {
+			return &ast.SelectorExpr{X: ast.NewIdent(g.qualifier), Sel: ast.NewIdent(t.Name)}
+		}
+		return t
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			g.used[x.Name] = true
+		}
+		return t
+	case *ast.StarExpr:
+		return &ast.StarExpr{X: g.qualify(t.X)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Len: t.Len, Elt: g.qualify(t.Elt)}
+	case *ast.MapType:
+		return &ast.MapType{Key: g.qualify(t.Key), Value: g.qualify(t.Value)}
+	case *ast.Ellipsis:
+		return &ast.Ellipsis{Elt: g.qualify(t.Elt)}
+	case *ast.ChanType:
+		return &ast.ChanType{Dir: t.Dir, Value: g.qualify(t.Value)}
+	case *ast.FuncType:
+		return &ast.FuncType{Params: g.qualifyFields(t.Params), Results: g.qualifyFields(t.Results)}
+	}
+
+	return expr
+}
+
+func (g *generator) qualifyFields(fields *ast.FieldList) *ast.FieldList {
+	if fields == nil {
+		return nil
+	}
+
+	qualified := &ast.FieldList{}
+	for _, field := range fields.List {
+		qualified.List = append(qualified.List, &ast.Field{Names: field.Names, Type: g.qualify(field.Type)})
+	}
+
+	return qualified
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/joho/godotenv"
 )
@@ -13,13 +17,29 @@ func init() {
 	}
 }
 
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description `Bearer <ADMIN_TOKEN>`, required by the /admin endpoints
 func main() {
-	app, err := CreateApp()
+	// Without a command the binary serves the API
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Done on SIGINT or SIGTERM, which stop the server and its background work
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	app, err := CreateApp(ctx)
 	if err != nil {
 		log.Fatalf(err.Error())
 	}
 
-	err = app.Run()
+	err = serve(ctx, app)
 	if err != nil {
 		log.Fatalf(err.Error())
 	}
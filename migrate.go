@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"leads/storage"
+)
+
+const migrateUsage = `usage: migrate <command> [flags]
+
+commands:
+  status              lists the migrations and whether they are applied
+  up [-to VERSION]    applies the pending migrations, only those up to VERSION when it's set
+  down [-to VERSION]  rolls back the latest migration, or all migrations newer than VERSION when it's set
+  redo                rolls back the latest migration and applies it again
+  create [-dir DIR] NAME
+                      writes empty <version>_NAME.up.sql and <version>_NAME.down.sql migration files
+
+The database is the one DB_PATH points to.`
+
+// migrationVersionLayout - versions of new migrations are their creation time
+const migrationVersionLayout = "20060102150405"
+
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// migrateCommand - inspects and changes the schema version of the database
+func migrateCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	name, args := args[0], args[1:]
+	flags := flag.NewFlagSet("migrate "+name, flag.ContinueOnError)
+
+	if name == "create" {
+		return createMigration(flags, args)
+	}
+
+	var to int64
+	switch name {
+	case "up":
+		flags.Int64Var(&to, "to", storage.LatestVersion, "version to stop at")
+	case "down":
+		flags.Int64Var(&to, "to", -1, "version to roll back to, 0 rolls back all migrations")
+	case "status", "redo":
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", name, migrateUsage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments of migrate %s: %s\n\n%s", name, strings.Join(flags.Args(), " "), migrateUsage)
+	}
+
+	ctx := context.Background()
+	s, err := connectSQL(ctx, os.Getenv(DBPATH))
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "status":
+		return printMigrationStatus(ctx, s)
+	case "up":
+		return migrateUp(ctx, s, to)
+	case "down":
+		if to < 0 {
+			_, previous, err := latestMigration(ctx, s)
+			if err != nil {
+				return err
+			}
+			to = previous
+		}
+		return migrateDown(ctx, s, to)
+	default:
+		latest, previous, err := latestMigration(ctx, s)
+		if err != nil {
+			return err
+		}
+		if err := migrateDown(ctx, s, previous); err != nil {
+			return err
+		}
+		return migrateUp(ctx, s, latest)
+	}
+}
+
+func migrateUp(ctx context.Context, s *storage.Storage, to int64) error {
+	applied, err := s.MigrateUp(ctx, to)
+	for _, m := range applied {
+		fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Println("no pending migrations")
+	}
+
+	return nil
+}
+
+func migrateDown(ctx context.Context, s *storage.Storage, to int64) error {
+	rolledBack, err := s.MigrateDown(ctx, to)
+	for _, m := range rolledBack {
+		fmt.Printf("rolled back %d_%s\n", m.Version, m.Name)
+	}
+
+	return err
+}
+
+// latestMigration - versions of the latest applied migration and of the one applied before it, 0 when there is none
+func latestMigration(ctx context.Context, s *storage.Storage) (int64, int64, error) {
+	statuses, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var applied []int64
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			applied = append(applied, status.Version)
+		}
+	}
+	sort.Slice(applied, func(i, j int) bool { return applied[i] < applied[j] })
+
+	switch len(applied) {
+	case 0:
+		return 0, 0, errors.New("no migrations are applied")
+	case 1:
+		return applied[0], 0, nil
+	default:
+		return applied[len(applied)-1], applied[len(applied)-2], nil
+	}
+}
+
+func printMigrationStatus(ctx context.Context, s *storage.Storage) error {
+	statuses, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = status.AppliedAt.Format(time.RFC3339)
+		}
+		switch {
+		case status.Changed:
+			applied += " (changed since)"
+		case status.Missing:
+			applied += " (files are missing)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+	}
+
+	return w.Flush()
+}
+
+// pendingMigrations - number of the migrations that are not applied
+func pendingMigrations(statuses []storage.MigrationStatus) int {
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+
+	return pending
+}
+
+// createMigration - writes the files of a new migration. They are built into the binary on the next build, or read
+// right away with SQL_FROM_DISK=true
+func createMigration(flags *flag.FlagSet, args []string) error {
+	defaultDir := storage.SQLiteMigrationsDir
+	if isPostgresDSN(os.Getenv(DBPATH)) {
+		defaultDir = storage.PostgresMigrationsDir
+	}
+	dir := flags.String("dir", defaultDir, "directory of the migrations")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: migrate create [-dir DIR] NAME")
+	}
+
+	name := flags.Arg(0)
+	if !migrationName.MatchString(name) {
+		return fmt.Errorf("invalid migration name %q: use lowercase letters, digits and underscores", name)
+	}
+
+	// The default directory is relative to the repository root
+	migrationsDir, err := filepath.Abs(*dir)
+	if err != nil {
+		return fmt.Errorf("invalid migrations directory %q: %w", *dir, err)
+	}
+	if info, err := os.Stat(migrationsDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("migrations directory %s does not exist: run the command from the repository root or pass -dir", migrationsDir)
+	}
+
+	version := time.Now().UTC().Format(migrationVersionLayout)
+	for _, suffix := range []string{".up.sql", ".down.sql"} {
+		path := filepath.Join(migrationsDir, fmt.Sprintf("%s_%s%s", version, name, suffix))
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("can't create migration: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("can't create migration: %w", err)
+		}
+
+		fmt.Println("created", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"leads/seed"
+	"leads/storage"
+)
+
+// Seed - fixture set the server applies on start, e.g. "demo". Applied only together with SeedOnStart
+const Seed = "SEED"
+
+// SeedOnStart - "true" lets the server apply the Seed set on start. Refused for databases kept in files or on a server,
+// so they are never seeded by accident
+const SeedOnStart = "SEED_ON_START"
+
+// seedCommand - applies a fixture set to the DB_PATH database
+func seedCommand(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	list := flags.Bool("list", false, "list the fixture sets")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *list {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, set := range seed.Sets() {
+			fmt.Fprintf(w, "%s\t%s\n", set.Name, set.Description)
+		}
+		return w.Flush()
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: seed SET, seed -list shows the sets")
+	}
+
+	s, err := openSQL(os.Getenv(DBPATH))
+	if err != nil {
+		return err
+	}
+
+	report, err := seed.Apply(context.Background(), s, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("fixture set %s: %d clients created, %d already there\n", report.Set, report.Created, report.Existing)
+
+	return nil
+}
+
+// seedOnStart - applies the SEED fixture set, if any, to the in-memory database `dbPath`
+func seedOnStart(repo storage.Repository, dbPath string) error {
+	name := os.Getenv(Seed)
+	if name == "" {
+		return nil
+	}
+
+	if os.Getenv(SeedOnStart) != "true" {
+		return fmt.Errorf("%s is applied on start only with %s=true", Seed, SeedOnStart)
+	}
+	if !inMemoryDB(dbPath) {
+		return fmt.Errorf("%s is applied on start only to in-memory databases, seed the database with the seed command instead", Seed)
+	}
+
+	report, err := seed.Apply(context.Background(), repo, name)
+	if err != nil {
+		return fmt.Errorf("seeding failed: %w", err)
+	}
+	log.Printf("fixture set %s: %d clients created, %d already there", report.Set, report.Created, report.Existing)
+
+	return nil
+}
+
+// inMemoryDB - `dbPath` is the memory backend or an in-memory SQLite database, so its data is gone on restart
+func inMemoryDB(dbPath string) bool {
+	if dbPath == MemoryDB {
+		return true
+	}
+	if isPostgresDSN(dbPath) {
+		return false
+	}
+
+	return dbPath == ":memory:" || strings.HasPrefix(dbPath, "file::memory:") || strings.Contains(dbPath, "mode=memory")
+}
@@ -0,0 +1,150 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"leads/storage"
+)
+
+// Set - named group of fixture clients
+type Set struct {
+	Name        string
+	Description string
+	clients     func() []storage.ClientRequest
+}
+
+var sets = map[string]Set{
+	"empty": {
+		Name:        "empty",
+		Description: "no data, the database only gets its schema",
+		clients:     func() []storage.ClientRequest { return nil },
+	},
+	"demo": {
+		Name:        "demo",
+		Description: "four clients of different priorities to try the API with",
+		clients:     demoClients,
+	},
+	"load-test": {
+		Name:        "load-test",
+		Description: "1000 clients of mixed priorities, capacities and time frames",
+		clients:     loadTestClients,
+	},
+}
+
+// Report - result of applying a set
+type Report struct {
+	Set string
+	// Created - fixture clients that have been created
+	Created int
+	// Existing - fixture clients that were already there
+	Existing int
+}
+
+// Sets - all fixture sets ordered by name
+func Sets() []Set {
+	all := make([]Set, 0, len(sets))
+	for _, set := range sets {
+		all = append(all, set)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+
+	return all
+}
+
+// Apply - creates the clients of the set `name` in a single transaction. Fixture clients are told by their names:
+// those that exist are left as they are (archived clients don't count), so applying a set again changes nothing
+func Apply(ctx context.Context, clients storage.ClientRepository, name string) (*Report, error) {
+	set, ok := sets[name]
+	if !ok {
+		names := make([]string, 0, len(sets))
+		for _, set := range Sets() {
+			names = append(names, set.Name)
+		}
+
+		return nil, fmt.Errorf("unknown fixture set %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+
+	existing := make(map[string]bool)
+	err := clients.EachClient(ctx, storage.ClientsFilter{}, func(c storage.Client, _ int) error {
+		existing[c.Name] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clients: %w", err)
+	}
+
+	report := &Report{Set: set.Name}
+	var rows []storage.ImportRow
+	for _, c := range set.clients() {
+		if existing[c.Name] {
+			report.Existing++
+			continue
+		}
+		rows = append(rows, storage.ImportRow{Row: len(rows) + 1, Client: c})
+	}
+
+	if len(rows) == 0 {
+		return report, nil
+	}
+
+	imported, err := clients.ImportClients(ctx, rows, storage.ImportAllOrNothing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clients: %w", err)
+	}
+	if !imported.Committed {
+		for _, row := range imported.Rows {
+			if row.Status == storage.ImportRowInvalid {
+				return nil, fmt.Errorf("fixture client %s is invalid: %s", row.Client.Name, row.Error)
+			}
+		}
+	}
+	report.Created = imported.Imported
+
+	return report, nil
+}
+
+func demoClients() []storage.ClientRequest {
+	return []storage.ClientRequest{
+		client("client_1", "2024-04-10T00:00:00Z", "2025-07-10T00:00:00Z", "MEDIUM", 10),
+		client("client_2", "2024-05-10T00:00:00Z", "2025-08-10T00:00:00Z", "LOW", 5),
+		client("client_3", "2024-06-10T00:00:00Z", "2025-09-10T00:00:00Z", "HIGH", 2),
+		client("client_4", "2024-07-10T00:00:00Z", "2025-10-10T00:00:00Z", "HIGH", 4),
+	}
+}
+
+// loadTestClients - clients whose time frames start every day of 2024 and last from one to four quarters
+func loadTestClients() []storage.ClientRequest {
+	priorities := []storage.Priority{"HIGH", "MEDIUM", "LOW"}
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	clients := make([]storage.ClientRequest, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		from := start.AddDate(0, 0, i%366)
+		clients = append(clients, storage.ClientRequest{
+			Name:         fmt.Sprintf("load_client_%04d", i+1),
+			StartDate:    storage.Timestamp{Time: from},
+			EndDate:      storage.Timestamp{Time: from.AddDate(0, 3*(1+i%4), 0)},
+			Priority:     priorities[i%len(priorities)],
+			LeadCapacity: 10 + i%91,
+		})
+	}
+
+	return clients
+}
+
+func client(name, startDate, endDate string, priority storage.Priority, leadCapacity int) storage.ClientRequest {
+	from, _ := storage.ParseTime(startDate)
+	to, _ := storage.ParseTime(endDate)
+
+	return storage.ClientRequest{
+		Name:         name,
+		StartDate:    storage.Timestamp{Time: from},
+		EndDate:      storage.Timestamp{Time: to},
+		Priority:     priority,
+		LeadCapacity: leadCapacity,
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	_ "leads/docs"
 	"leads/handlers"
@@ -19,41 +21,222 @@ import (
 
 const DBPATH = "DB_PATH"
 
+// SQLFromDisk - when "true", SQL files are read from storage/ of the working directory instead of the ones built into
+// the binary. Lets query and migration edits be tried without rebuilding
+const SQLFromDisk = "SQL_FROM_DISK"
+
+// MigrateOnStart - when "false", the server doesn't apply pending migrations and refuses to start while there are any
+const MigrateOnStart = "MIGRATE_ON_START"
+
+// AdminToken - bearer token of the /admin endpoints. They are disabled while it's not set
+const AdminToken = "ADMIN_TOKEN"
+
+// shutdownTimeout - how long the server waits for the requests in progress once it's asked to stop
+const shutdownTimeout = 10 * time.Second
+
+// MemoryDB - DB_PATH value that keeps all data in memory instead of a SQLite file. The data is lost on restart
+const MemoryDB = "memory:"
+
 func health(ctx *gin.Context) {
 	ctx.String(http.StatusOK, "ok")
 }
 
-func CreateApp() (*gin.Engine, error) {
-	dbPath := os.Getenv(DBPATH)
-	ctx := context.Background()
+// actor - changes made by the request are recorded in the history as made by the `X-Actor` header value
+func actor(ctx *gin.Context) {
+	if name := ctx.GetHeader("X-Actor"); name != "" {
+		ctx.Request = ctx.Request.WithContext(storage.WithActor(ctx.Request.Context(), name))
+	}
 
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("can't open database: %w", err)
+	ctx.Next()
+}
+
+// tenant - metadata of the request is validated against the schemas of the `X-Tenant` header value
+func tenant(ctx *gin.Context) {
+	if name := ctx.GetHeader("X-Tenant"); name != "" {
+		ctx.Request = ctx.Request.WithContext(storage.WithTenant(ctx.Request.Context(), name))
 	}
 
-	sqlHelpers := storage.NewSQLHelper()
-	sqlStorage, err := storage.New(db, sqlHelpers)
-	if err != nil {
-		log.Fatal("can't connect to storage: ", err)
+	ctx.Next()
+}
+
+// CreateApp - the API of the DB_PATH storage. Its background work stops once `ctx` is done
+func CreateApp(ctx context.Context) (*gin.Engine, error) {
+	dbPath := os.Getenv(DBPATH)
+
+	var repo storage.Repository
+	var sqlStorage *storage.Storage
+	if dbPath == MemoryDB {
+		log.Print("using in-memory storage, data is lost on restart")
+		repo = storage.NewMemory()
+	} else {
+		var err error
+		if sqlStorage, err = openSQL(dbPath); err != nil {
+			return nil, err
+		}
+		repo = sqlStorage
 	}
 
-	if err := sqlStorage.Init(ctx); err != nil {
-		log.Fatal("can't init storage", err)
+	if err := seedOnStart(repo, dbPath); err != nil {
+		return nil, err
 	}
 
-	if err := sqlStorage.Migrations(ctx); err != nil {
-		log.Fatal("migrations failed", err)
+	if sqlStorage != nil {
+		if err := enableCapacityIndex(ctx, sqlStorage); err != nil {
+			return nil, err
+		}
 	}
 
-	clientsHandler := handlers.NewClientsHandlers(sqlStorage)
+	clientsHandler := handlers.NewClientsHandlers(repo, repo)
+	leadsHandler := handlers.NewLeadsHandlers(repo)
+	statsHandler := handlers.NewStatsHandlers(repo)
+	exportHandler := handlers.NewExportHandlers(repo, repo)
+	groupsHandler := handlers.NewGroupsHandlers(repo)
+	metadataHandler := handlers.NewMetadataHandlers(repo)
+	adminToken := os.Getenv(AdminToken)
+	if adminToken == "" {
+		log.Printf("%s is not set, the /admin endpoints are disabled", AdminToken)
+	}
+	adminHandler := handlers.NewAdminHandlers(repo, repo, backupDir(), adminToken)
 
 	r := gin.New()
+	// Lets storage read values of the request context (e.g. the actor and the tenant) through *gin.Context
+	r.ContextWithFallback = true
+	r.Use(handlers.RequestID, actor, tenant)
+	r.NoRoute(handlers.RouteNotFound)
 
 	r.GET("/health", health)
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
 	clientsHandler.InstallRoutes(r)
+	leadsHandler.InstallRoutes(r)
+	statsHandler.InstallRoutes(r)
+	exportHandler.InstallRoutes(r)
+	groupsHandler.InstallRoutes(r)
+	metadataHandler.InstallRoutes(r)
+	adminHandler.InstallRoutes(r)
 
 	return r, nil
 }
+
+// serve - serves the app on PORT (8080 by default, like gin does) until `ctx` is done, then lets the requests in
+// progress finish for up to shutdownTimeout
+func serve(ctx context.Context, app http.Handler) error {
+	server := &http.Server{Addr: ":" + envOr("PORT", "8080"), Handler: app}
+
+	failed := make(chan error, 1)
+	go func() {
+		failed <- server.ListenAndServe()
+	}()
+	log.Printf("listening on %s", server.Addr)
+
+	select {
+	case err := <-failed:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Print("shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return server.Shutdown(ctx)
+}
+
+// isPostgresDSN - DB_PATH is a PostgreSQL connection URL rather than a path of a SQLite file
+func isPostgresDSN(dbPath string) bool {
+	return strings.HasPrefix(dbPath, "postgres://") || strings.HasPrefix(dbPath, "postgresql://")
+}
+
+type storageConstructor func(db storage.DB, helpers storage.SQLHelpersReader) (*storage.Storage, error)
+
+// sqlBackend - driver and storage constructor of the database at `dbPath`
+func sqlBackend(dbPath string) (string, storageConstructor) {
+	if isPostgresDSN(dbPath) {
+		return "postgres", storage.NewPostgres
+	}
+
+	return "sqlite3", storage.New
+}
+
+// connectSQL - opens the database at `dbPath` with its migrations tracked, but not necessarily applied
+func connectSQL(ctx context.Context, dbPath string) (*storage.Storage, error) {
+	if dbPath == MemoryDB {
+		return nil, fmt.Errorf("%s=%s keeps data in memory, there is no database to work with", DBPATH, MemoryDB)
+	}
+
+	driver, newStorage := sqlBackend(dbPath)
+	dsn := dbPath
+	if driver == "sqlite3" {
+		settings, err := sqliteSettingsFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		dsn = settings.dsn(dbPath)
+	}
+
+	pool, err := poolSettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("can't open database: %w", err)
+	}
+	pool.apply(db)
+
+	var sqlHelpers storage.SQLHelpersReader = storage.NewEmbeddedSQL()
+	if os.Getenv(SQLFromDisk) == "true" {
+		sqlHelpers = storage.NewSQLHelper()
+	}
+
+	sqlStorage, err := newStorage(db, sqlHelpers)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to storage: %w", err)
+	}
+
+	if err := sqlStorage.Init(ctx); err != nil {
+		return nil, fmt.Errorf("can't init storage: %w", err)
+	}
+
+	report := poolReport(db, pool)
+	if driver == "sqlite3" {
+		pragmas, err := sqliteReport(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		report = pragmas + " " + report
+	}
+	log.Printf("%s database settings: %s", driver, report)
+
+	return sqlStorage, nil
+}
+
+// openSQL - opens the database at `dbPath` for serving. Pending migrations are applied, unless MIGRATE_ON_START is
+// "false": then they make it fail, so the schema only changes through the migrate command
+func openSQL(dbPath string) (*storage.Storage, error) {
+	ctx := context.Background()
+
+	sqlStorage, err := connectSQL(ctx, dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if os.Getenv(MigrateOnStart) == "false" {
+		statuses, err := sqlStorage.MigrationStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if pending := pendingMigrations(statuses); pending > 0 {
+			return nil, fmt.Errorf("the database has %d pending migrations, apply them with `migrate up`", pending)
+		}
+	} else if err := sqlStorage.Migrations(ctx); err != nil {
+		return nil, fmt.Errorf("migrations failed: %w", err)
+	}
+
+	if err := sqlStorage.Prepare(ctx); err != nil {
+		return nil, fmt.Errorf("can't prepare queries: %w", err)
+	}
+
+	return sqlStorage, nil
+}
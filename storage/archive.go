@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ArchiveClient - soft-archives the client and applies `policy` to its leads.
+// Returns nil result when there is no client with such ID and ErrConflict when it is already archived
+func (s *Storage) ArchiveClient(ctx context.Context, clientID int, policy LeadsPolicy) (*ArchiveResult, error) {
+	if policy != LeadsKeep && policy != LeadsReassign && policy != LeadsPending {
+		return nil, invalidInput(CodeInvalidLeadsPolicy, "unknown leads policy '%s'", policy)
+	}
+
+	unlock := s.lockCapacity()
+	defer unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	old, err := loadClientState(ctx, tx, clientID)
+	if err != nil || old == nil {
+		return nil, err
+	}
+	if old.ArchivedAt != nil {
+		return nil, conflict(CodeClientArchived, "client %d is already archived since %s", clientID, old.ArchivedAt.Format(time.RFC3339))
+	}
+
+	q := `UPDATE clients SET archived_at = ? WHERE id = ? AND archived_at IS NULL`
+	res, err := tx.ExecContext(ctx, q, timeArg(time.Now()), clientID)
+	if err != nil {
+		return nil, fmt.Errorf("can't archive client: %w", err)
+	}
+
+	archived, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("can't archive client: %w", err)
+	}
+	if archived == 0 {
+		return nil, nil
+	}
+
+	if err := recordClientChange(ctx, tx, clientID, ClientArchived, old); err != nil {
+		return nil, err
+	}
+
+	leads, err := s.clientLeads(ctx, tx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch policy {
+	case LeadsPending:
+		for i := range leads {
+			if err := moveToPending(ctx, tx, &leads[i]); err != nil {
+				return nil, err
+			}
+		}
+	case LeadsReassign:
+		for i := range leads {
+			// The archived client is already hidden inside the transaction, so the engine never picks it again
+			client, err := s.pickClient(ctx, tx, AssignLeadRequest{LeadStart: Timestamp{Time: leads[i].LeadStart}, LeadEnd: Timestamp{Time: leads[i].LeadEnd}})
+			if errors.Is(err, ErrNoClientsAvailable) {
+				if err := moveToPending(ctx, tx, &leads[i]); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			q := `UPDATE leads SET client_id = ? WHERE lead_id = ?`
+			if _, err := tx.ExecContext(ctx, q, client.ID, leads[i].LeadID); err != nil {
+				return nil, fmt.Errorf("can't reassign lead %s: %w", leads[i].LeadID, err)
+			}
+			leads[i].ClientID = client.ID
+
+			if err := recordLeadChange(ctx, tx, leads[i].LeadID, client.ID, LeadStatusAssigned); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("can't commit transaction: %w", err)
+	}
+
+	// The client leaves the index, the clients its leads went to have more of them
+	changed := []int{clientID}
+	for _, lead := range leads {
+		if lead.Status == LeadStatusAssigned {
+			changed = append(changed, lead.ClientID)
+		}
+	}
+	s.updateCapacity(ctx, changed, nil)
+
+	return &ArchiveResult{
+		ClientID: clientID,
+		Policy:   policy,
+		Leads:    leads,
+	}, nil
+}
+
+// RestoreClient - brings an archived client back. Leads that were reassigned or moved to the pending queue stay where they are.
+// Returns nil client when there is no client with such ID
+func (s *Storage) RestoreClient(ctx context.Context, clientID int) (*Client, error) {
+	unlock := s.lockCapacity()
+	defer unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	old, err := loadClientState(ctx, tx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if old != nil && old.ArchivedAt != nil {
+		q := `UPDATE clients SET archived_at = NULL WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, q, clientID); err != nil {
+			return nil, fmt.Errorf("can't restore client: %w", err)
+		}
+
+		if err := recordClientChange(ctx, tx, clientID, ClientRestored, old); err != nil {
+			return nil, err
+		}
+	}
+
+	clients, err := s.getClients(ctx, tx, &clientID)
+	if err != nil {
+		return nil, err
+	}
+	if len(clients) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("can't commit transaction: %w", err)
+	}
+	s.updateCapacity(ctx, []int{clientID}, nil)
+
+	return &clients[0], nil
+}
+
+func (s *Storage) clientLeads(ctx context.Context, q queryer, clientID int) ([]Lead, error) {
+	rows, err := q.QueryContext(ctx, s.queries[queryLeads]+" WHERE l.client_id = ? AND l.status = ?", clientID, LeadStatusAssigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leads: %w", err)
+	}
+	defer rows.Close()
+
+	leads := []Lead{}
+	for rows.Next() {
+		lead, err := scanLead(rows)
+		if err != nil {
+			return nil, err
+		}
+		leads = append(leads, *lead)
+	}
+
+	return leads, rows.Err()
+}
+
+func moveToPending(ctx context.Context, q queryer, lead *Lead) error {
+	query := `UPDATE leads SET client_id = NULL, status = ? WHERE lead_id = ?`
+	if _, err := q.ExecContext(ctx, query, LeadStatusPending, lead.LeadID); err != nil {
+		return fmt.Errorf("can't move lead %s to pending queue: %w", lead.LeadID, err)
+	}
+
+	if err := recordLeadChange(ctx, q, lead.LeadID, 0, LeadStatusPending); err != nil {
+		return err
+	}
+
+	lead.ClientID = 0
+	lead.Status = LeadStatusPending
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func testArchiveAndRestore(t *testing.T, r Repository) {
+	ctx := context.Background()
+
+	a := createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 3})
+	b := createClient(t, r, ClientRequest{Name: "b", Priority: "LOW", LeadCapacity: 1})
+	for range 2 {
+		assignLead(t, r, leadRequest())
+	}
+
+	result, err := r.ArchiveClient(ctx, a, LeadsReassign)
+	if err != nil {
+		t.Fatalf("ArchiveClient: %v", err)
+	}
+	if len(result.Leads) != 2 {
+		t.Fatalf("archive result = %+v, want 2 leads", result)
+	}
+	// The only other client takes one lead, the other one waits without a client
+	if result.Leads[0].ClientID != b || result.Leads[0].Status != LeadStatusAssigned {
+		t.Errorf("first lead = %+v, want it assigned to client %d", result.Leads[0], b)
+	}
+	if result.Leads[1].ClientID != 0 || result.Leads[1].Status != LeadStatusPending {
+		t.Errorf("second lead = %+v, want it pending", result.Leads[1])
+	}
+
+	if _, err := r.ArchiveClient(ctx, a, LeadsKeep); !errors.Is(err, ErrConflict) {
+		t.Errorf("second archive error = %v, want ErrConflict", err)
+	}
+	if clients, err := r.GetClients(ctx, &a); err != nil || len(clients) != 0 {
+		t.Errorf("GetClients of archived client = %+v, %v, want none", clients, err)
+	}
+	assertNoClients(t, r, leadRequest())
+
+	client, err := r.RestoreClient(ctx, a)
+	if err != nil {
+		t.Fatalf("RestoreClient: %v", err)
+	}
+	if client == nil || len(client.Leads) != 0 {
+		t.Fatalf("restored client = %+v, want it without leads", client)
+	}
+	if lead := assignLead(t, r, leadRequest()); lead.ClientID != a {
+		t.Errorf("lead went to client %d, want the restored %d", lead.ClientID, a)
+	}
+}
+
+// testArchiveKeep - leads stay with the archived client and keep taking its capacity, the engine skips the client
+func testArchiveKeep(t *testing.T, r Repository) {
+	ctx := context.Background()
+
+	a := createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 5})
+	b := createClient(t, r, ClientRequest{Name: "b", Priority: "LOW", LeadCapacity: 5})
+	first := assignLead(t, r, leadRequest())
+	assignLead(t, r, leadRequest())
+
+	result, err := r.ArchiveClient(ctx, a, LeadsKeep)
+	if err != nil {
+		t.Fatalf("ArchiveClient: %v", err)
+	}
+	if result.Policy != LeadsKeep || len(result.Leads) != 2 {
+		t.Fatalf("archive result = %+v, want 2 kept leads", result)
+	}
+	for _, lead := range result.Leads {
+		if lead.ClientID != a || lead.Status != LeadStatusAssigned {
+			t.Errorf("lead = %+v, want it still assigned to client %d", lead, a)
+		}
+	}
+
+	if lead, err := r.GetLead(ctx, first.LeadID); err != nil || lead.ClientID != a {
+		t.Errorf("GetLead = %+v, %v, want the lead of client %d", lead, err, a)
+	}
+	if lead := assignLead(t, r, leadRequest()); lead.ClientID != b {
+		t.Errorf("lead went to client %d, want %d", lead.ClientID, b)
+	}
+}
+
+func testArchivePending(t *testing.T, r Repository) {
+	ctx := context.Background()
+
+	a := createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 5})
+	createClient(t, r, ClientRequest{Name: "b", Priority: "LOW", LeadCapacity: 5})
+	for range 2 {
+		assignLead(t, r, leadRequest())
+	}
+
+	result, err := r.ArchiveClient(ctx, a, LeadsPending)
+	if err != nil {
+		t.Fatalf("ArchiveClient: %v", err)
+	}
+	for _, lead := range result.Leads {
+		if lead.ClientID != 0 || lead.Status != LeadStatusPending {
+			t.Errorf("lead = %+v, want it pending without a client", lead)
+		}
+	}
+
+	page, err := r.ListLeads(ctx, LeadsFilter{Status: LeadStatusPending})
+	if err != nil {
+		t.Fatalf("ListLeads: %v", err)
+	}
+	if len(page.Data) != 2 {
+		t.Errorf("pending leads = %+v, want 2", page.Data)
+	}
+}
+
+func testArchiveUnknown(t *testing.T, r Repository) {
+	ctx := context.Background()
+
+	clientID := createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 5})
+
+	if _, err := r.ArchiveClient(ctx, clientID, "drop"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("archive with unknown policy error = %v, want ErrInvalidInput", err)
+	}
+	if result, err := r.ArchiveClient(ctx, clientID+1, LeadsKeep); err != nil || result != nil {
+		t.Errorf("archive of unknown client = %+v, %v, want nil result", result, err)
+	}
+	if client, err := r.RestoreClient(ctx, clientID+1); err != nil || client != nil {
+		t.Errorf("restore of unknown client = %+v, %v, want nil client", client, err)
+	}
+
+	// Restoring an active client changes nothing
+	client, err := r.RestoreClient(ctx, clientID)
+	if err != nil || client == nil || client.ID != clientID {
+		t.Errorf("restore of active client = %+v, %v, want the client", client, err)
+	}
+}
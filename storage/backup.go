@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// backupTimeLayout - time part of the names of backup files, sorts like the times
+const backupTimeLayout = "20060102T150405.000Z"
+
+// BackupInfo - backup file of the database
+type BackupInfo struct {
+	Path string `json:"path"`
+	// Size - in bytes
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	// SchemaVersion - version of the latest migration applied to the backup, 0 when there is none
+	SchemaVersion int64 `json:"schema_version"`
+}
+
+// BackupPath - path of a new backup file in `dir`, named after the current time
+func BackupPath(dir string) string {
+	return filepath.Join(dir, "leads-"+time.Now().UTC().Format(backupTimeLayout)+".db")
+}
+
+// Backup - writes a consistent snapshot of the database to the new file `path` with the SQLite online backup API.
+// Requests keep being served meanwhile. The snapshot is written next to `path` and renamed once it's complete, so
+// a file at `path` is never a partial copy
+func (s *Storage) Backup(ctx context.Context, path string) (*BackupInfo, error) {
+	if err := s.requireSQLite(); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return nil, conflict(CodeBackupExists, "backup %s already exists", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("can't create backup directory: %w", err)
+	}
+
+	partial := path + ".partial"
+	_ = os.Remove(partial)
+	if err := s.writeBackup(ctx, partial); err != nil {
+		_ = os.Remove(partial)
+		return nil, err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		_ = os.Remove(partial)
+		return nil, fmt.Errorf("can't move backup in place: %w", err)
+	}
+
+	return s.InspectBackup(ctx, path)
+}
+
+func (s *Storage) writeBackup(ctx context.Context, path string) error {
+	dest, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("can't create backup: %w", err)
+	}
+	defer dest.Close()
+
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("can't create backup: %w", err)
+	}
+	defer destConn.Close()
+
+	srcConn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("can't connect to database: %w", err)
+	}
+	defer srcConn.Close()
+
+	if err := copyDatabase(destConn, srcConn); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	// The copy has the journal mode of the database, a WAL backup would get -wal and -shm files next to it once opened
+	if _, err := destConn.ExecContext(ctx, `PRAGMA journal_mode = DELETE`); err != nil {
+		return fmt.Errorf("failed to set journal mode of the backup: %w", err)
+	}
+
+	return nil
+}
+
+// InspectBackup - checks the backup `path` can be restored by this version: the file is an intact database, and every
+// migration applied to it is one of ours, unchanged. Backups of older schema versions pass, their pending migrations
+// are applied the usual way after a restore
+func (s *Storage) InspectBackup(ctx context.Context, path string) (*BackupInfo, error) {
+	if err := s.requireSQLite(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, NotFound(CodeBackupNotFound, "backup %s does not exist", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read backup: %w", err)
+	}
+
+	backup, err := openBackup(path)
+	if err != nil {
+		return nil, err
+	}
+	defer backup.Close()
+
+	var integrity string
+	if err := backup.QueryRowContext(ctx, `PRAGMA quick_check`).Scan(&integrity); err != nil {
+		return nil, invalidInput(CodeInvalidBackup, "%s is not a readable SQLite database: %w", path, err)
+	}
+	if integrity != "ok" {
+		return nil, invalidInput(CodeInvalidBackup, "backup %s is corrupt: %s", path, integrity)
+	}
+
+	var versioned bool
+	if err := backup.QueryRowContext(ctx, sqliteDialect{}.tableExistsQuery(), "schema_migrations").Scan(&versioned); err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if !versioned {
+		return nil, invalidInput(CodeInvalidBackup, "backup %s has no schema_migrations table, it's not a database of this service", path)
+	}
+
+	migrations, err := s.loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int64]Migration)
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	rows, err := backup.QueryContext(ctx, `SELECT version, name, checksum FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations of the backup: %w", err)
+	}
+	defer rows.Close()
+
+	info := &BackupInfo{Path: path, Size: file.Size(), CreatedAt: file.ModTime().UTC()}
+	for rows.Next() {
+		var version int64
+		var name, checksum string
+		if err := rows.Scan(&version, &name, &checksum); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if _, ok := retiredMigrations[version]; ok {
+			continue
+		}
+		m, ok := known[version]
+		if !ok {
+			return nil, invalidInput(
+				CodeIncompatibleBackup,
+				"backup %s has migration %d_%s applied, which this version doesn't know: it was made by a newer version",
+				path, version, name,
+			)
+		}
+		if m.Checksum != checksum {
+			return nil, invalidInput(
+				CodeIncompatibleBackup, "migration %d_%s of backup %s differs from the one of this version",
+				version, name, path,
+			)
+		}
+		info.SchemaVersion = max(info.SchemaVersion, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read migrations of the backup: %w", err)
+	}
+
+	return info, nil
+}
+
+// Restore - replaces the contents of the database with the backup `path` after InspectBackup has accepted it.
+// The copy is a single write transaction, so the database is either the backup or left as it was
+func (s *Storage) Restore(ctx context.Context, path string) (*BackupInfo, error) {
+	info, err := s.InspectBackup(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	backup, err := openBackup(path)
+	if err != nil {
+		return nil, err
+	}
+	defer backup.Close()
+
+	srcConn, err := backup.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't read backup: %w", err)
+	}
+	defer srcConn.Close()
+
+	destConn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to database: %w", err)
+	}
+	defer destConn.Close()
+
+	if err := copyDatabase(destConn, srcConn); err != nil {
+		return nil, fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	return info, nil
+}
+
+func (s *Storage) requireSQLite() error {
+	if _, ok := s.db.dialect.(sqliteDialect); !ok {
+		return unsupported(CodeBackupUnsupported, "backups are only made of SQLite databases, use the tools of the database server")
+	}
+
+	return nil
+}
+
+// openBackup - the backup file opened read-only, so inspecting it changes nothing
+func openBackup(path string) (*sql.DB, error) {
+	backup, err := sql.Open("sqlite3", "file:"+url.PathEscape(path)+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("can't open backup: %w", err)
+	}
+
+	return backup, nil
+}
+
+// copyDatabase - copies the main database of `src` over the one of `dest`. All pages are copied in a single step:
+// the source is read in one transaction, which writers of a WAL database don't wait for, and the copy doesn't start
+// over every time they change something
+func copyDatabase(dest, src *sql.Conn) error {
+	return dest.Raw(func(destDriver any) error {
+		return src.Raw(func(srcDriver any) error {
+			destConn, ok := destDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected connection %T", destDriver)
+			}
+			srcConn, ok := srcDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected connection %T", srcDriver)
+			}
+
+			backup, err := destConn.Backup("main", srcConn, "main")
+			if err != nil {
+				return err
+			}
+
+			done, err := backup.Step(-1)
+			if err != nil {
+				_ = backup.Finish()
+				return err
+			}
+			if !done {
+				_ = backup.Finish()
+				return errors.New("backup stopped before the last page")
+			}
+
+			return backup.Finish()
+		})
+	})
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteRepository(t)
+	createClient(t, s, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 2})
+	lead := assignLead(t, s, leadRequest())
+
+	path := filepath.Join(t.TempDir(), "backups", "leads.db")
+	info, err := s.Backup(ctx, path)
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if info.Path != path || info.Size == 0 || info.SchemaVersion != latestMigration(t, s) {
+		t.Errorf("backup = %+v, want the file of the latest schema version", info)
+	}
+	if _, err := os.Stat(path + ".partial"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the partial backup is left: %v", err)
+	}
+	if _, err := s.Backup(ctx, path); !errors.Is(err, ErrConflict) || errorCode(err) != CodeBackupExists {
+		t.Errorf("Backup to an existing file error = %v, want %s", err, CodeBackupExists)
+	}
+
+	// Changes made after the backup are gone once it's restored
+	createClient(t, s, ClientRequest{Name: "b", Priority: "LOW", LeadCapacity: 1})
+	if _, err := s.ArchiveClient(ctx, 1, LeadsPending); err != nil {
+		t.Fatalf("ArchiveClient: %v", err)
+	}
+
+	restored, err := s.Restore(ctx, path)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.SchemaVersion != info.SchemaVersion {
+		t.Errorf("restored backup = %+v, want %+v", restored, info)
+	}
+	clients, err := s.GetClients(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetClients: %v", err)
+	}
+	if len(clients) != 1 || clients[0].Name != "a" || len(clients[0].Leads) != 1 || clients[0].Leads[0].LeadID != lead.LeadID {
+		t.Errorf("clients = %+v, want the client of the backup with its lead", clients)
+	}
+}
+
+func TestInspectBackup(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteRepository(t)
+	createClient(t, s, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 2})
+	dir := t.TempDir()
+
+	// backup - backup of `s` changed by the statement `change`
+	backup := func(t *testing.T, change string) string {
+		path := filepath.Join(dir, t.Name()+".db")
+		if _, err := s.Backup(ctx, path); err != nil {
+			t.Fatalf("Backup: %v", err)
+		}
+		if change == "" {
+			return path
+		}
+
+		db, err := sql.Open("sqlite3", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if _, err := db.Exec(change); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+		kind error
+		code ErrorCode
+	}{
+		{
+			name: "missing",
+			path: func(t *testing.T) string { return filepath.Join(dir, "missing.db") },
+			kind: ErrNotFound,
+			code: CodeBackupNotFound,
+		},
+		{
+			name: "not a database",
+			path: func(t *testing.T) string {
+				path := filepath.Join(dir, "notes.txt")
+				if err := os.WriteFile(path, []byte("not a database, just some notes about the leads"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			},
+			kind: ErrInvalidInput,
+			code: CodeInvalidBackup,
+		},
+		{
+			name: "other database",
+			path: func(t *testing.T) string { return backup(t, `DROP TABLE schema_migrations`) },
+			kind: ErrInvalidInput,
+			code: CodeInvalidBackup,
+		},
+		{
+			name: "unknown migration",
+			path: func(t *testing.T) string {
+				return backup(t, `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (29990101000000, 'future', '', '')`)
+			},
+			kind: ErrInvalidInput,
+			code: CodeIncompatibleBackup,
+		},
+		{
+			name: "changed migration",
+			path: func(t *testing.T) string {
+				return backup(t, `UPDATE schema_migrations SET checksum = 'changed' WHERE version = 20240601000000`)
+			},
+			kind: ErrInvalidInput,
+			code: CodeIncompatibleBackup,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path(t)
+
+			if _, err := s.InspectBackup(ctx, path); !errors.Is(err, tt.kind) || errorCode(err) != tt.code {
+				t.Errorf("InspectBackup error = %v, want %s", err, tt.code)
+			}
+			if _, err := s.Restore(ctx, path); !errors.Is(err, tt.kind) || errorCode(err) != tt.code {
+				t.Errorf("Restore error = %v, want %s", err, tt.code)
+			}
+			if clients, err := s.GetClients(ctx, nil); err != nil || len(clients) != 1 {
+				t.Errorf("GetClients after a refused restore = %+v, %v, want the client", clients, err)
+			}
+		})
+	}
+
+	t.Run("retired migration", func(t *testing.T) {
+		path := backup(t, `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (20240706000000, 'mocks', '', '')`)
+
+		if info, err := s.InspectBackup(ctx, path); err != nil || info.SchemaVersion != latestMigration(t, s) {
+			t.Errorf("InspectBackup = %+v, %v, want the backup accepted", info, err)
+		}
+	})
+}
+
+func TestBackupPostgres(t *testing.T) {
+	s := &Storage{db: dialectDB{dialect: postgresDialect{}}}
+
+	if _, err := s.Backup(context.Background(), filepath.Join(t.TempDir(), "leads.db")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Backup error = %v, want ErrUnsupported", err)
+	}
+	if _, err := s.Restore(context.Background(), filepath.Join(t.TempDir(), "leads.db")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Restore error = %v, want ErrUnsupported", err)
+	}
+}
+
+// latestMigration - version of the newest migration of the storage
+func latestMigration(t *testing.T, s *Storage) int64 {
+	t.Helper()
+
+	migrations, err := s.loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return migrations[len(migrations)-1].Version
+}
+
+// errorCode - code of the storage error, empty for other errors
+func errorCode(err error) ErrorCode {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Code
+	}
+
+	return ""
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// assignWorkers - concurrent assignments of BenchmarkAssign
+const assignWorkers = 16
+
+// BenchmarkAssign - concurrent lead assignments with the go-sqlite3 DSN defaults, which the server used before the SQLite
+// settings existed, and with the settings the server uses by default. Writes SQLite refused as busy are reported
+// as busy/op
+func BenchmarkAssign(b *testing.B) {
+	variants := []struct {
+		name   string
+		params string
+	}{
+		{"go-sqlite3 DSN defaults", ""},
+		{"configured", serverSQLiteParams},
+	}
+
+	for _, variant := range variants {
+		b.Run(variant.name, func(b *testing.B) {
+			ctx := context.Background()
+			s := migratedStorage(b, openSQLite(b, variant.params), New)
+
+			const clients = 100
+			from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+			benchClients(b, s, clients, b.N/clients+1, from, from.AddDate(1, 0, 0))
+
+			lead := AssignLeadRequest{
+				LeadStart: Timestamp{Time: from.AddDate(0, 1, 0)},
+				LeadEnd:   Timestamp{Time: from.AddDate(0, 2, 0)},
+			}
+
+			var busy atomic.Int64
+			remaining := int64(b.N)
+			var wg sync.WaitGroup
+			b.ResetTimer()
+			for range assignWorkers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for atomic.AddInt64(&remaining, -1) >= 0 {
+						_, err := s.AssignLead(ctx, lead)
+						switch {
+						case err == nil:
+						case isBusy(err):
+							busy.Add(1)
+						default:
+							b.Error(err)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			b.StopTimer()
+
+			b.ReportMetric(float64(busy.Load())/float64(b.N), "busy/op")
+		})
+	}
+}
+
+// BenchmarkAssignGrowth - cost of an assignment as the leads table grows. The leads belong to clients whose time
+// frames are over: the assignment engine doesn't count them, the clients list it used to read for every assignment
+// joins them all. The million leads are skipped with -short
+func BenchmarkAssignGrowth(b *testing.B) {
+	for _, leads := range []int{0, 100_000, 1_000_000} {
+		b.Run(fmt.Sprintf("leads=%d", leads), func(b *testing.B) {
+			if testing.Short() && leads > 100_000 {
+				b.Skip("skipped with -short")
+			}
+
+			ctx := context.Background()
+			db := openSQLite(b, serverSQLiteParams)
+			s := migratedStorage(b, db, New)
+
+			const leadsPerPastClient = 1000
+			past := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+			if pastClients := (leads + leadsPerPastClient - 1) / leadsPerPastClient; pastClients > 0 {
+				benchClients(b, s, pastClients, leadsPerPastClient, past, past.AddDate(1, 0, 0))
+			}
+			benchLeads(b, db, leads, leadsPerPastClient, past.AddDate(0, 1, 0), past.AddDate(0, 2, 0))
+
+			// Capacity is never the limit, the runs of both benchmarks share the clients
+			from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+			benchClients(b, s, 100, 1_000_000, from, from.AddDate(1, 0, 0))
+
+			lead := AssignLeadRequest{
+				LeadStart: Timestamp{Time: from.AddDate(0, 1, 0)},
+				LeadEnd:   Timestamp{Time: from.AddDate(0, 2, 0)},
+			}
+
+			b.Run("assignment", func(b *testing.B) {
+				for range b.N {
+					if _, err := s.AssignLead(ctx, lead); err != nil {
+						b.Fatalf("failed to assign lead: %v", err)
+					}
+				}
+			})
+
+			b.Run("clients list", func(b *testing.B) {
+				for range b.N {
+					if _, err := s.GetClients(ctx, nil); err != nil {
+						b.Fatalf("failed to get clients: %v", err)
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkAssignIndex - assignments among clients of different time frames with the SQL engine and with the capacity
+// index, loading the index and checking it against the database. TestCapacityIndexPicksSameClients makes sure both
+// engines pick the same clients
+func BenchmarkAssignIndex(b *testing.B) {
+	const clients = 10000
+	ctx := context.Background()
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, indexed := range []bool{false, true} {
+		name := "sql"
+		if indexed {
+			name = "capacity index"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			s := migratedStorage(b, openSQLite(b, serverSQLiteParams), New)
+			importClients(b, s, staggeredClients(clients, from))
+			if indexed {
+				if err := s.EnableCapacityIndex(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ResetTimer()
+			for i := range b.N {
+				_, err := s.AssignLead(ctx, staggeredLead(i, from))
+				if err != nil && !errors.Is(err, ErrNoClientsAvailable) {
+					b.Fatalf("failed to assign lead: %v", err)
+				}
+			}
+			b.StopTimer()
+
+			if indexed {
+				assertIndexMatches(b, s)
+			}
+		})
+	}
+
+	b.Run("load", func(b *testing.B) {
+		s := migratedStorage(b, openSQLite(b, serverSQLiteParams), New)
+		importClients(b, s, staggeredClients(clients, from))
+
+		b.ResetTimer()
+		for range b.N {
+			if err := s.EnableCapacityIndex(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("check", func(b *testing.B) {
+		s := migratedStorage(b, openSQLite(b, serverSQLiteParams), New)
+		importClients(b, s, staggeredClients(clients, from))
+		if err := s.EnableCapacityIndex(ctx); err != nil {
+			b.Fatal(err)
+		}
+
+		b.ResetTimer()
+		for range b.N {
+			assertIndexMatches(b, s)
+		}
+	})
+}
+
+// benchLeads - inserts `count` assigned leads from `from` to `to`, `perClient` of them to each client starting from
+// the first one. The rows are written by SQLite itself, the history doesn't get them
+func benchLeads(b *testing.B, db *sql.DB, count, perClient int, from, to time.Time) {
+	b.Helper()
+
+	if count == 0 {
+		return
+	}
+
+	_, err := db.Exec(`
+		WITH RECURSIVE n(i) AS (SELECT 0 UNION ALL SELECT i + 1 FROM n WHERE i + 1 < ?)
+		INSERT INTO leads (lead_id, client_id, status, start_date, end_date)
+		SELECT printf('bench-lead-%d', i), 1 + i / ?, 'ASSIGNED', ?, ? FROM n`,
+		count, perClient, from.Format(time.RFC3339), to.Format(time.RFC3339),
+	)
+	if err != nil {
+		b.Fatalf("failed to create leads: %v", err)
+	}
+}
+
+// benchClients - creates `count` clients of mixed priorities active from `from` to `to`
+func benchClients(b *testing.B, clients ClientRepository, count, capacity int, from, to time.Time) {
+	b.Helper()
+	priorities := []Priority{"HIGH", "MEDIUM", "LOW"}
+
+	rows := make([]ImportRow, count)
+	for i := range rows {
+		rows[i] = ImportRow{
+			Row: i + 1,
+			Client: ClientRequest{
+				Name:         fmt.Sprintf("bench_client_%d", i+1),
+				StartDate:    Timestamp{Time: from},
+				EndDate:      Timestamp{Time: to},
+				Priority:     priorities[i%len(priorities)],
+				LeadCapacity: capacity,
+			},
+		}
+	}
+
+	importClients(b, clients, rows)
+}
+
+// isBusy - the error is SQLite refusing a write because another connection is writing
+func isBusy(err error) bool {
+	return strings.Contains(err.Error(), "database is locked") || strings.Contains(err.Error(), "database table is locked")
+}
@@ -0,0 +1,495 @@
+package storage
+
+import (
+	"container/heap"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// capacityIndex - active clients with free capacity kept in a heap ranked like sortByPriorityAndCapacity, and the usage
+// of the client groups, so the assignment engine finds the best client without reading all of them. Clients carry the
+// fields the engine needs, not their names and metadata
+type capacityIndex struct {
+	// mu - held by the changes of capacity from before their transaction until the index has followed them, so
+	// the index changes in the order of the database
+	mu      sync.Mutex
+	clients map[int]*indexedClient
+	groups  map[int]groupUsage
+	heap    clientHeap
+	// stale - the index missed a change and is rebuilt before it's used next
+	stale bool
+}
+
+type indexedClient struct {
+	candidate
+	// position - in the heap, -1 while the client is full
+	position int
+}
+
+type groupUsage struct {
+	capacity, used int
+}
+
+// clientHeap - container/heap of the clients with free capacity, the best ranked first
+type clientHeap []*indexedClient
+
+func (h clientHeap) Len() int {
+	return len(h)
+}
+
+func (h clientHeap) Less(i, j int) bool {
+	return ranksAbove(h[i].candidate, h[j].candidate)
+}
+
+func (h clientHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].position = i
+	h[j].position = j
+}
+
+func (h *clientHeap) Push(x any) {
+	c := x.(*indexedClient)
+	c.position = len(*h)
+	*h = append(*h, c)
+}
+
+func (h *clientHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	c.position = -1
+
+	return c
+}
+
+// positions - heap positions of a capacity index ordered by the rank of their clients, walks the index best first
+type positions struct {
+	index *capacityIndex
+	p     []int
+}
+
+func (h positions) Len() int {
+	return len(h.p)
+}
+
+func (h positions) Less(i, j int) bool {
+	return h.index.heap.Less(h.p[i], h.p[j])
+}
+
+func (h positions) Swap(i, j int) {
+	h.p[i], h.p[j] = h.p[j], h.p[i]
+}
+
+func (h *positions) Push(x any) {
+	h.p = append(h.p, x.(int))
+}
+
+func (h *positions) Pop() any {
+	p := h.p[len(h.p)-1]
+	h.p = h.p[:len(h.p)-1]
+
+	return p
+}
+
+func newCapacityIndex() *capacityIndex {
+	return &capacityIndex{
+		clients: make(map[int]*indexedClient),
+		groups:  make(map[int]groupUsage),
+	}
+}
+
+// load - replaces the contents of the index
+func (x *capacityIndex) load(clients []candidate, groups []ClientGroup) {
+	x.clients = make(map[int]*indexedClient, len(clients))
+	x.groups = make(map[int]groupUsage, len(groups))
+	x.heap = make(clientHeap, 0, len(clients))
+
+	for _, c := range clients {
+		entry := &indexedClient{candidate: c, position: -1}
+		x.clients[c.ID] = entry
+		if c.used < c.LeadCapacity {
+			entry.position = len(x.heap)
+			x.heap = append(x.heap, entry)
+		}
+	}
+	heap.Init(&x.heap)
+
+	for _, g := range groups {
+		x.groups[g.ID] = groupUsage{capacity: g.LeadCapacity, used: g.UsedCapacity}
+	}
+	x.stale = false
+}
+
+// best - the best ranked client able to take the lead. Clients are visited in the order of their rank until one fits,
+// which is O(log n) unless many better ranked clients don't
+func (x *capacityIndex) best(l AssignLeadRequest) (*candidate, bool) {
+	if len(x.heap) == 0 {
+		return nil, false
+	}
+
+	frontier := &positions{index: x, p: []int{0}}
+	for frontier.Len() > 0 {
+		p := heap.Pop(frontier).(int)
+		c := x.heap[p]
+		if x.fits(c.candidate, l) {
+			found := c.candidate
+			return &found, true
+		}
+
+		for _, child := range []int{2*p + 1, 2*p + 2} {
+			if child < len(x.heap) {
+				heap.Push(frontier, child)
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// fits - the client has free capacity, as well as its group, and its time frame contains the lead
+func (x *capacityIndex) fits(c candidate, l AssignLeadRequest) bool {
+	if c.used >= c.LeadCapacity || unsuitableTime(c.Client, l) {
+		return false
+	}
+	if c.GroupID != nil {
+		if g, ok := x.groups[*c.GroupID]; ok && g.used >= g.capacity {
+			return false
+		}
+	}
+
+	return true
+}
+
+// put - adds the client or replaces its entry
+func (x *capacityIndex) put(c candidate) {
+	entry, ok := x.clients[c.ID]
+	if !ok {
+		entry = &indexedClient{position: -1}
+		x.clients[c.ID] = entry
+	}
+	entry.candidate = c
+
+	full := c.used >= c.LeadCapacity
+	switch {
+	case full && entry.position >= 0:
+		heap.Remove(&x.heap, entry.position)
+	case !full && entry.position < 0:
+		heap.Push(&x.heap, entry)
+	case !full:
+		heap.Fix(&x.heap, entry.position)
+	}
+}
+
+func (x *capacityIndex) remove(clientID int) {
+	entry, ok := x.clients[clientID]
+	if !ok {
+		return
+	}
+
+	if entry.position >= 0 {
+		heap.Remove(&x.heap, entry.position)
+	}
+	delete(x.clients, clientID)
+}
+
+// assigned - the client and its group have got a lead
+func (x *capacityIndex) assigned(clientID int) {
+	entry, ok := x.clients[clientID]
+	if !ok {
+		return
+	}
+
+	c := entry.candidate
+	c.used++
+	x.put(c)
+
+	if c.GroupID != nil {
+		if g, ok := x.groups[*c.GroupID]; ok {
+			g.used++
+			x.groups[*c.GroupID] = g
+		}
+	}
+}
+
+// CapacityIndexReport - result of comparing the capacity index with the database
+type CapacityIndexReport struct {
+	// Clients - active clients in the database
+	Clients int `json:"clients"`
+	// Available - clients with free capacity
+	Available int `json:"available"`
+	Groups    int `json:"groups"`
+	// Differences - what the index had wrong, it's rebuilt from the database when there are any
+	Differences []string  `json:"differences"`
+	CheckedAt   time.Time `json:"checked_at"`
+}
+
+// EnableCapacityIndex - loads the capacity index, which AssignLead uses from then on. The index follows the changes
+// made through the storage. Changes made by other processes are noticed when the client they affect is picked, or by
+// CheckCapacityIndex
+func (s *Storage) EnableCapacityIndex(ctx context.Context) error {
+	x := newCapacityIndex()
+
+	clients, groups, err := s.capacitySnapshot(ctx, s.db)
+	if err != nil {
+		return fmt.Errorf("failed to load capacity index: %w", err)
+	}
+	x.load(clients, groups)
+	s.capacity = x
+
+	return nil
+}
+
+// CheckCapacityIndex - compares the capacity index with the database and rebuilds it when they differ
+func (s *Storage) CheckCapacityIndex(ctx context.Context) (*CapacityIndexReport, error) {
+	if s.capacity == nil {
+		return nil, unsupported(CodeCapacityIndexDisabled, "the capacity index is disabled")
+	}
+
+	unlock := s.lockCapacity()
+	defer unlock()
+
+	clients, groups, err := s.capacitySnapshot(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	x := s.capacity
+	report := &CapacityIndexReport{
+		Clients:     len(clients),
+		Groups:      len(groups),
+		Differences: []string{},
+		CheckedAt:   time.Now().UTC(),
+	}
+	if x.stale {
+		report.Differences = append(report.Differences, "the index missed a change")
+	}
+
+	active := make(map[int]bool, len(clients))
+	for _, c := range clients {
+		active[c.ID] = true
+		if c.used < c.LeadCapacity {
+			report.Available++
+		}
+
+		entry, ok := x.clients[c.ID]
+		switch {
+		case !ok:
+			report.Differences = append(report.Differences, fmt.Sprintf("client %d is missing", c.ID))
+		case entry.used != c.used:
+			report.Differences = append(report.Differences, fmt.Sprintf("client %d has %d leads assigned, the index has %d", c.ID, c.used, entry.used))
+		case !sameIndexedClient(entry.candidate, c):
+			report.Differences = append(report.Differences, fmt.Sprintf("client %d has changed", c.ID))
+		}
+	}
+	for id := range x.clients {
+		if !active[id] {
+			report.Differences = append(report.Differences, fmt.Sprintf("client %d is not active", id))
+		}
+	}
+
+	known := make(map[int]bool, len(groups))
+	for _, g := range groups {
+		known[g.ID] = true
+		usage, ok := x.groups[g.ID]
+		switch {
+		case !ok:
+			report.Differences = append(report.Differences, fmt.Sprintf("group %d is missing", g.ID))
+		case usage.used != g.UsedCapacity || usage.capacity != g.LeadCapacity:
+			report.Differences = append(report.Differences, fmt.Sprintf(
+				"group %d has %d of %d leads used, the index has %d of %d", g.ID, g.UsedCapacity, g.LeadCapacity, usage.used, usage.capacity,
+			))
+		}
+	}
+	for id := range x.groups {
+		if !known[id] {
+			report.Differences = append(report.Differences, fmt.Sprintf("group %d does not exist", id))
+		}
+	}
+
+	if len(report.Differences) > 0 {
+		slices.Sort(report.Differences)
+		x.load(clients, groups)
+	}
+
+	return report, nil
+}
+
+func sameIndexedClient(a, b candidate) bool {
+	sameGroup := (a.GroupID == nil) == (b.GroupID == nil) && (a.GroupID == nil || *a.GroupID == *b.GroupID)
+
+	return sameGroup && a.Priority == b.Priority && a.LeadCapacity == b.LeadCapacity &&
+		a.StartDate.Equal(b.StartDate) && a.EndDate.Equal(b.EndDate)
+}
+
+// lockCapacity - keeps the capacity index to the caller until the returned function is called. Changes of clients,
+// groups and leads take it before their transaction and update the index after committing
+func (s *Storage) lockCapacity() func() {
+	if s.capacity == nil {
+		return func() {}
+	}
+
+	s.capacity.mu.Lock()
+	return s.capacity.mu.Unlock
+}
+
+// pickIndexedClient - the assignment engine over the capacity index. The client found is read again in the
+// transaction, so a change the index has missed can't make it take a lead it has no capacity for
+func (s *Storage) pickIndexedClient(ctx context.Context, q queryer, l AssignLeadRequest) (*Client, error) {
+	x := s.capacity
+	if x.stale {
+		clients, groups, err := s.capacitySnapshot(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load capacity index: %w", err)
+		}
+		x.load(clients, groups)
+	}
+
+	// Each miss corrects the entries of a client and its group. Past a few of them, the index is far behind
+	const maxMisses = 3
+	for i := 0; i < maxMisses; i++ {
+		c, ok := x.best(l)
+		if !ok {
+			return nil, ErrNoClientsAvailable
+		}
+
+		if err := s.refreshCapacity(ctx, q, []int{c.ID}, nil); err != nil {
+			return nil, err
+		}
+		if entry, ok := x.clients[c.ID]; ok && x.fits(entry.candidate, l) {
+			client := entry.Client
+			return &client, nil
+		}
+	}
+
+	x.stale = true
+
+	return s.pickClient(ctx, q, l)
+}
+
+// updateCapacity - brings the clients and groups of a committed change up to date in the capacity index. When
+// reading them fails, the index is rebuilt before it's used next
+func (s *Storage) updateCapacity(ctx context.Context, clientIDs []int, groupIDs []int) {
+	if s.capacity == nil {
+		return
+	}
+
+	if err := s.refreshCapacity(ctx, s.db, clientIDs, groupIDs); err != nil {
+		s.capacity.stale = true
+	}
+}
+
+// refreshCapacity - reads the clients again, as well as the groups they belonged to, belong to and `groupIDs`
+func (s *Storage) refreshCapacity(ctx context.Context, q queryer, clientIDs []int, groupIDs []int) error {
+	x := s.capacity
+
+	groups := make(map[int]bool)
+	for _, id := range groupIDs {
+		groups[id] = true
+	}
+	for _, id := range clientIDs {
+		if entry, ok := x.clients[id]; ok && entry.GroupID != nil {
+			groups[*entry.GroupID] = true
+		}
+	}
+
+	// Bounded number of placeholders per query
+	const chunk = 500
+	for start := 0; start < len(clientIDs); start += chunk {
+		ids := clientIDs[start:min(start+chunk, len(clientIDs))]
+
+		args := make([]interface{}, len(ids))
+		for i, id := range ids {
+			args[i] = id
+		}
+		query := s.queries[queryClientUsage] + fmt.Sprintf(" AND c.id IN (%s)", placeholders(len(ids)))
+
+		clients, err := scanClientUsage(q.QueryContext(ctx, query, args...))
+		if err != nil {
+			return err
+		}
+
+		active := make(map[int]bool, len(clients))
+		for _, c := range clients {
+			active[c.ID] = true
+			x.put(c)
+			if c.GroupID != nil {
+				groups[*c.GroupID] = true
+			}
+		}
+		for _, id := range ids {
+			if !active[id] {
+				x.remove(id)
+			}
+		}
+	}
+
+	for id := range groups {
+		group, err := s.getGroup(ctx, q, id)
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			delete(x.groups, id)
+			continue
+		}
+		x.groups[id] = groupUsage{capacity: group.LeadCapacity, used: group.UsedCapacity}
+	}
+
+	return nil
+}
+
+// capacitySnapshot - what the capacity index holds, read from the database
+func (s *Storage) capacitySnapshot(ctx context.Context, q queryer) ([]candidate, []ClientGroup, error) {
+	stmt, err := s.stmt(ctx, q, queryClientUsage)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clients, err := scanClientUsage(stmt.QueryContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groups, err := s.getGroups(ctx, q, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return clients, groups, nil
+}
+
+func scanClientUsage(rows *sql.Rows, err error) ([]candidate, error) {
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clients: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []candidate
+	for rows.Next() {
+		var c candidate
+		var groupID sql.NullInt64
+
+		err := rows.Scan(
+			&c.ID,
+			scanTime(&c.StartDate),
+			scanTime(&c.EndDate),
+			&c.Priority,
+			&c.LeadCapacity,
+			&groupID,
+			&c.used,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		c.GroupID = nullableInt(groupID)
+
+		clients = append(clients, c)
+	}
+
+	return clients, rows.Err()
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCapacityIndexPicksSameClients - the capacity index assigns every lead to the client the SQL engine does, and
+// agrees with the database afterwards
+func TestCapacityIndexPicksSameClients(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	var picks [2][]int
+	for i, indexed := range []bool{false, true} {
+		s := newSQLiteRepository(t)
+		importClients(t, s, staggeredClients(60, from))
+		if indexed {
+			if err := s.EnableCapacityIndex(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for n := range 900 {
+			lead, err := s.AssignLead(ctx, staggeredLead(n, from))
+			switch {
+			case errors.Is(err, ErrNoClientsAvailable):
+				picks[i] = append(picks[i], 0)
+			case err != nil:
+				t.Fatalf("failed to assign lead: %v", err)
+			default:
+				picks[i] = append(picks[i], lead.ClientID)
+			}
+		}
+
+		if indexed {
+			assertIndexMatches(t, s)
+		}
+	}
+
+	for n := range picks[0] {
+		if picks[0][n] != picks[1][n] {
+			t.Fatalf("lead %d went to client %d with the SQL engine and to %d with the capacity index", n+1, picks[0][n], picks[1][n])
+		}
+	}
+	if !slices.Contains(picks[0], 0) {
+		t.Error("every lead found a client, the test doesn't cover running out of them")
+	}
+}
+
+// staggeredClients - `count` clients of mixed priorities and capacities whose time frames start every day of a year
+// from `from` and last from one to four quarters
+func staggeredClients(count int, from time.Time) []ImportRow {
+	priorities := []Priority{"HIGH", "MEDIUM", "LOW"}
+
+	rows := make([]ImportRow, count)
+	for i := range rows {
+		start := from.AddDate(0, 0, i%366)
+		rows[i] = ImportRow{
+			Row: i + 1,
+			Client: ClientRequest{
+				Name:         fmt.Sprintf("bench_client_%d", i+1),
+				StartDate:    Timestamp{Time: start},
+				EndDate:      Timestamp{Time: start.AddDate(0, 3*(1+i%4), 0)},
+				Priority:     priorities[i%len(priorities)],
+				LeadCapacity: 1 + i%20,
+			},
+		}
+	}
+
+	return rows
+}
+
+// staggeredLead - the lead `n` of a sequence whose one-day windows jump around the first 300 days from `from`
+func staggeredLead(n int, from time.Time) AssignLeadRequest {
+	start := from.AddDate(0, 0, (n*37)%300)
+
+	return AssignLeadRequest{
+		LeadStart: Timestamp{Time: start},
+		LeadEnd:   Timestamp{Time: start.AddDate(0, 0, 1)},
+	}
+}
+
+// importClients - creates the clients of all rows, the rows have to be valid
+func importClients(tb testing.TB, clients ClientRepository, rows []ImportRow) {
+	tb.Helper()
+
+	report, err := clients.ImportClients(context.Background(), rows, ImportAllOrNothing)
+	if err != nil {
+		tb.Fatalf("failed to create clients: %v", err)
+	}
+	if !report.Committed {
+		tb.Fatal("failed to create clients: invalid rows")
+	}
+}
+
+// assertIndexMatches - the capacity index has nothing different from the database
+func assertIndexMatches(tb testing.TB, s *Storage) {
+	tb.Helper()
+
+	report, err := s.CheckCapacityIndex(context.Background())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if len(report.Differences) > 0 {
+		tb.Fatalf("the capacity index differs from the database: %s", strings.Join(report.Differences, "; "))
+	}
+}
@@ -0,0 +1,323 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+type sortField struct {
+	expr  string                       // SQL expression over clients_page.sql columns
+	value func(c Client, used int) any // Value of the expression for the cursor
+}
+
+var clientSortFields = map[string]sortField{
+	"id": {
+		expr:  "c.id",
+		value: func(c Client, _ int) any { return c.ID },
+	},
+	"name": {
+		expr:  "c.name",
+		value: func(c Client, _ int) any { return c.Name },
+	},
+	"priority": {
+		expr:  "CASE c.priority WHEN 'HIGH' THEN 3 WHEN 'MEDIUM' THEN 2 ELSE 1 END",
+		value: func(c Client, _ int) any { return PriorityMap[c.Priority] },
+	},
+	"start_date": {
+		expr:  "c.start_date",
+		value: func(c Client, _ int) any { return timeArg(c.StartDate) },
+	},
+	"end_date": {
+		expr:  "c.end_date",
+		value: func(c Client, _ int) any { return timeArg(c.EndDate) },
+	},
+	"lead_capacity": {
+		expr:  "c.lead_capacity",
+		value: func(c Client, _ int) any { return c.LeadCapacity },
+	},
+	"free_capacity": {
+		expr:  "c.lead_capacity - COALESCE(u.used, 0)",
+		value: func(c Client, used int) any { return c.LeadCapacity - used },
+	},
+}
+
+// cursor - position after the last row of a page. Bound to the sort it was issued for
+type cursor struct {
+	Sort  string `json:"s"`
+	Value any    `json:"v"`
+	ID    any    `json:"id"` // Unique key of the row that breaks ties between equal values
+}
+
+func encodeCursor(c cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalidInput(CodeInvalidCursor, "invalid cursor: %w", err)
+	}
+
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, invalidInput(CodeInvalidCursor, "invalid cursor: %w", err)
+	}
+
+	return &c, nil
+}
+
+// decodeCursorFor - decodes the cursor and checks it was issued for the sort `sort`
+func decodeCursorFor(s string, sort string) (*cursor, error) {
+	c, err := decodeCursor(s)
+	if err != nil {
+		return nil, err
+	}
+	if c.Sort != sort {
+		return nil, invalidInput(CodeInvalidCursor, "cursor was issued for sort '%s'", c.Sort)
+	}
+
+	return c, nil
+}
+
+// pageLimit - number of rows of a page for the requested limit
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return defaultPageLimit
+	}
+
+	return min(limit, maxPageLimit)
+}
+
+// clientSort - sort field of the `sort` filter value and whether the order is descending. Clients are sorted by ID by default
+func clientSort(sort string) (sortField, bool, error) {
+	name, desc := strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+	if name == "" {
+		name = "id"
+	}
+
+	field, ok := clientSortFields[name]
+	if !ok {
+		return sortField{}, false, invalidInput(CodeInvalidSort, "unknown sort field '%s'", name)
+	}
+
+	return field, desc, nil
+}
+
+// ListClients - receives a page of clients matching the filter. Filtering, sorting and pagination are done in SQL,
+// leads are loaded only for the clients of the page
+func (s *Storage) ListClients(ctx context.Context, f ClientsFilter) (*ClientsPage, error) {
+	query, args, sort, err := s.clientsQuery(f)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := pageLimit(f.Limit)
+
+	query += " LIMIT ?"
+	args = append(args, limit+1) // One extra row tells whether there is a next page
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clients: %w", err)
+	}
+	defer rows.Close()
+
+	clients := []Client{}
+	var used []int
+
+	for rows.Next() {
+		client, clientUsed, err := scanClientsPageRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		client.Leads = []Lead{}
+
+		clients = append(clients, client)
+		used = append(used, clientUsed)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get clients: %w", err)
+	}
+
+	page := &ClientsPage{Meta: PageMeta{Limit: limit}}
+
+	if len(clients) > limit {
+		clients = clients[:limit]
+		last := clients[limit-1]
+
+		page.Meta.HasMore = true
+		page.Meta.NextCursor = encodeCursor(cursor{
+			Sort:  f.Sort,
+			Value: sort.value(last, used[limit-1]),
+			ID:    last.ID,
+		})
+	}
+
+	if err := s.attachLeads(ctx, clients); err != nil {
+		return nil, err
+	}
+
+	page.Data = clients
+
+	return page, nil
+}
+
+// clientsQuery - builds a filtered and sorted query over clients_page.sql, starting after the cursor if there is one
+func (s *Storage) clientsQuery(f ClientsFilter) (string, []interface{}, *sortField, error) {
+	baseQuery := s.queries[queryClientsPage]
+
+	sort, desc, err := clientSort(f.Sort)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	var conditions []string
+	var args []interface{}
+
+	if len(f.Priority) > 0 {
+		conditions = append(conditions, fmt.Sprintf("c.priority IN (%s)", placeholders(len(f.Priority))))
+		for _, p := range f.Priority {
+			args = append(args, p)
+		}
+	}
+	if !f.ActiveFrom.IsZero() {
+		conditions = append(conditions, "c.start_date <= ?")
+		args = append(args, timeArg(f.ActiveFrom.Time))
+	}
+	if !f.ActiveTo.IsZero() {
+		conditions = append(conditions, "c.end_date >= ?")
+		args = append(args, timeArg(f.ActiveTo.Time))
+	}
+	if f.HasCapacity != nil {
+		if *f.HasCapacity {
+			conditions = append(conditions, "COALESCE(u.used, 0) < c.lead_capacity")
+		} else {
+			conditions = append(conditions, "COALESCE(u.used, 0) >= c.lead_capacity")
+		}
+	}
+	if f.NamePrefix != "" {
+		conditions = append(conditions, s.db.dialect.prefixCondition("c.name"))
+		args = append(args, escapeLike(f.NamePrefix)+"%")
+	}
+
+	metaConditions, metaArgs, err := metadataConditions(s.db.dialect, "c.metadata", f.Metadata)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	conditions = append(conditions, metaConditions...)
+	args = append(args, metaArgs...)
+
+	if f.Cursor != "" {
+		after, err := decodeCursorFor(f.Cursor, f.Sort)
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND c.id > ?))", sort.expr, op))
+		args = append(args, after.Value, after.Value, after.ID)
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+
+	query := baseQuery
+	for _, condition := range conditions {
+		query += " AND " + condition
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, c.id ASC", sort.expr, direction)
+
+	return query, args, &sort, nil
+}
+
+// scanClientsPageRow - scans a row of clients_page.sql. Returns the client without leads and the number of its leads
+func scanClientsPageRow(row scanner) (Client, int, error) {
+	var client Client
+	var groupID sql.NullInt64
+	var metadata sql.NullString
+	var used int
+
+	err := row.Scan(
+		&client.ID,
+		&client.Name,
+		scanTime(&client.StartDate),
+		scanTime(&client.EndDate),
+		&client.Priority,
+		&client.LeadCapacity,
+		&groupID,
+		&metadata,
+		&used,
+	)
+	if err != nil {
+		return client, 0, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	client.GroupID = nullableInt(groupID)
+	client.Metadata = metadataValue(metadata)
+
+	return client, used, nil
+}
+
+// attachLeads - loads assigned leads of the given clients with a single query
+func (s *Storage) attachLeads(ctx context.Context, clients []Client) error {
+	if len(clients) == 0 {
+		return nil
+	}
+
+	index := make(map[int]*Client, len(clients))
+	args := []interface{}{LeadStatusAssigned}
+	for i := range clients {
+		index[clients[i].ID] = &clients[i]
+		args = append(args, clients[i].ID)
+	}
+
+	query := fmt.Sprintf(
+		`SELECT client_id, lead_id, start_date, end_date, metadata FROM leads WHERE status = ? AND client_id IN (%s) ORDER BY start_date, lead_id`,
+		placeholders(len(clients)),
+	)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get leads: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		lead := Lead{Status: LeadStatusAssigned}
+		var metadata sql.NullString
+
+		if err := rows.Scan(&lead.ClientID, &lead.LeadID, scanTime(&lead.LeadStart), scanTime(&lead.LeadEnd), &metadata); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		lead.Metadata = metadataValue(metadata)
+
+		client := index[lead.ClientID]
+		client.Leads = append(client.Leads, lead)
+	}
+
+	return rows.Err()
+}
+
+// placeholders - returns "?, ?, ?" for n arguments
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testListClientsPaging - every sort field in both directions pages through all active clients in the order of the
+// field, ties broken by ascending ID
+func testListClientsPaging(t *testing.T, r Repository) {
+	ctx := context.Background()
+
+	for _, c := range []struct {
+		name     string
+		priority Priority
+		start    string
+		end      string
+		capacity int
+	}{
+		{"delta", "LOW", "2024-01-01T00:00:00Z", "2024-01-31T00:00:00Z", 5},
+		{"alpha", "HIGH", "2024-01-03T00:00:00Z", "2024-01-31T00:00:00Z", 3},
+		{"charlie", "MEDIUM", "2024-01-01T00:00:00Z", "2024-01-20T00:00:00Z", 5},
+		{"alpha", "HIGH", "2024-01-02T00:00:00Z", "2024-01-20T00:00:00Z", 4},
+		{"bravo", "LOW", "2024-01-03T00:00:00Z", "2024-01-25T00:00:00Z", 3},
+		{"charlie", "MEDIUM", "2024-01-02T00:00:00Z", "2024-01-31T00:00:00Z", 4},
+		{"archived", "HIGH", "2024-01-01T00:00:00Z", "2024-01-31T00:00:00Z", 9},
+	} {
+		createClient(t, r, ClientRequest{
+			Name:         c.name,
+			StartDate:    Timestamp{Time: date(c.start)},
+			EndDate:      Timestamp{Time: date(c.end)},
+			Priority:     c.priority,
+			LeadCapacity: c.capacity,
+		})
+	}
+	if _, err := r.ArchiveClient(ctx, 7, LeadsKeep); err != nil {
+		t.Fatalf("ArchiveClient: %v", err)
+	}
+	// Leads make the free capacity differ from the capacity
+	for range 5 {
+		assignLead(t, r, leadRequest())
+	}
+
+	clients, err := r.GetClients(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetClients: %v", err)
+	}
+
+	fields := map[string]func(a, b Client) int{
+		"id":            func(a, b Client) int { return cmp.Compare(a.ID, b.ID) },
+		"name":          func(a, b Client) int { return strings.Compare(a.Name, b.Name) },
+		"priority":      func(a, b Client) int { return cmp.Compare(PriorityMap[a.Priority], PriorityMap[b.Priority]) },
+		"start_date":    func(a, b Client) int { return a.StartDate.Compare(b.StartDate) },
+		"end_date":      func(a, b Client) int { return a.EndDate.Compare(b.EndDate) },
+		"lead_capacity": func(a, b Client) int { return cmp.Compare(a.LeadCapacity, b.LeadCapacity) },
+		"free_capacity": func(a, b Client) int {
+			return cmp.Compare(a.LeadCapacity-len(a.Leads), b.LeadCapacity-len(b.Leads))
+		},
+	}
+
+	for name, compare := range fields {
+		for _, desc := range []bool{false, true} {
+			sort := name
+			if desc {
+				sort = "-" + name
+			}
+
+			want := slices.Clone(clients)
+			slices.SortFunc(want, func(a, b Client) int {
+				c := compare(a, b)
+				if desc {
+					c = -c
+				}
+				return cmp.Or(c, cmp.Compare(a.ID, b.ID))
+			})
+
+			if got := pageThroughClients(t, r, sort); !slices.Equal(got, clientIDs(want)) {
+				t.Errorf("sort %s pages = %v, want %v", sort, got, clientIDs(want))
+			}
+		}
+	}
+
+	page, err := r.ListClients(ctx, ClientsFilter{Sort: "name", Limit: 1})
+	if err != nil {
+		t.Fatalf("ListClients: %v", err)
+	}
+	if _, err := r.ListClients(ctx, ClientsFilter{Sort: "-name", Cursor: page.Meta.NextCursor}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("cursor of another sort error = %v, want ErrInvalidInput", err)
+	}
+	if _, err := r.ListClients(ctx, ClientsFilter{Sort: "leads"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("unknown sort field error = %v, want ErrInvalidInput", err)
+	}
+}
+
+// pageThroughClients - IDs of the clients of all pages of two clients sorted by `sort`
+func pageThroughClients(t *testing.T, r Repository, sort string) []int {
+	t.Helper()
+
+	var ids []int
+	f := ClientsFilter{Sort: sort, Limit: 2}
+	for range 10 {
+		page, err := r.ListClients(context.Background(), f)
+		if err != nil {
+			t.Fatalf("ListClients(%+v): %v", f, err)
+		}
+		ids = append(ids, clientIDs(page.Data)...)
+
+		if !page.Meta.HasMore {
+			return ids
+		}
+		f.Cursor = page.Meta.NextCursor
+	}
+
+	t.Fatalf("sort %s never reached the last page", sort)
+	return nil
+}
+
+func clientIDs(clients []Client) []int {
+	ids := make([]int, 0, len(clients))
+	for _, c := range clients {
+		ids = append(ids, c.ID)
+	}
+
+	return ids
+}
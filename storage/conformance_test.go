@@ -0,0 +1,449 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testPostgresDSN - database the conformance suite runs on PostgreSQL against, the PostgreSQL tests are skipped without it
+const testPostgresDSN = "TEST_POSTGRES_DSN"
+
+// repositoryFactory - creates an empty repository for a single test
+type repositoryFactory func(t *testing.T) Repository
+
+func TestMemoryConformance(t *testing.T) {
+	testConformance(t, func(t *testing.T) Repository {
+		return NewMemory()
+	})
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	testConformance(t, func(t *testing.T) Repository {
+		return newSQLiteRepository(t)
+	})
+}
+
+func TestSQLiteCapacityIndexConformance(t *testing.T) {
+	testConformance(t, func(t *testing.T) Repository {
+		s := newSQLiteRepository(t)
+		if err := s.EnableCapacityIndex(context.Background()); err != nil {
+			t.Fatalf("can't enable capacity index: %v", err)
+		}
+
+		return s
+	})
+}
+
+func TestPostgresConformance(t *testing.T) {
+	dsn := os.Getenv(testPostgresDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set", testPostgresDSN)
+	}
+
+	testConformance(t, func(t *testing.T) Repository {
+		return newPostgresRepository(t, dsn)
+	})
+}
+
+// serverSQLiteParams - go-sqlite3 parameters of the settings the server uses by default
+const serverSQLiteParams = "_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=true&_synchronous=NORMAL&_txlock=immediate"
+
+// newSQLiteRepository - migrated storage in a new database file with the settings the server uses by default
+func newSQLiteRepository(t *testing.T) *Storage {
+	t.Helper()
+
+	return migratedStorage(t, openSQLite(t, serverSQLiteParams), New)
+}
+
+// openSQLite - new database file opened with the go-sqlite3 parameters `params`, closed after the test
+func openSQLite(tb testing.TB, params string) *sql.DB {
+	tb.Helper()
+
+	dsn := filepath.Join(tb.TempDir(), "leads.db")
+	if params != "" {
+		dsn += "?" + params
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		tb.Fatalf("can't open database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// newPostgresRepository - migrated storage in a new schema of the database `dsn`, the schema is dropped after the test
+func newPostgresRepository(t *testing.T, dsn string) *Storage {
+	t.Helper()
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("can't open database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("conformance_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("can't create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("can't drop schema %s: %v", schema, err)
+		}
+	})
+
+	db, err := sql.Open("postgres", withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatalf("can't open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return migratedStorage(t, db, NewPostgres)
+}
+
+// withSearchPath - `dsn`, URL or key=value one, whose connections work in the schema
+func withSearchPath(dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return dsn // sql.Open reports it
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// migratedStorage - storage of the dialect `newStorage` over `db`, initialized, migrated and with prepared statements
+func migratedStorage(tb testing.TB, db *sql.DB, newStorage func(DB, SQLHelpersReader) (*Storage, error)) *Storage {
+	tb.Helper()
+	ctx := context.Background()
+
+	s, err := newStorage(db, NewEmbeddedSQL())
+	if err != nil {
+		tb.Fatalf("can't connect to storage: %v", err)
+	}
+	if err := s.Init(ctx); err != nil {
+		tb.Fatalf("can't init storage: %v", err)
+	}
+	if err := s.Migrations(ctx); err != nil {
+		tb.Fatalf("migrations failed: %v", err)
+	}
+	if err := s.Prepare(ctx); err != nil {
+		tb.Fatalf("can't prepare queries: %v", err)
+	}
+
+	return s
+}
+
+// testConformance - every backend behaves the same way, each test runs on a new repository of `newRepository`
+func testConformance(t *testing.T, newRepository repositoryFactory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, r Repository)
+	}{
+		{name: "assignment order", test: testAssignmentOrder},
+		{name: "client capacity", test: testClientCapacity},
+		{name: "clients order", test: testClientsOrder},
+		{name: "clients paging", test: testListClientsPaging},
+		{name: "time window", test: testTimeWindow},
+		{name: "leads list", test: testListLeads},
+		{name: "lead by ID", test: testGetLead},
+		{name: "clients stats", test: testClientsStats},
+		{name: "import all or nothing", test: testImportAllOrNothing},
+		{name: "import skipping invalid rows", test: testImportSkipInvalid},
+		{name: "import mode", test: testImportMode},
+		{name: "clients export", test: testEachClient},
+		{name: "leads export", test: testEachLead},
+		{name: "metadata schemas per tenant", test: testMetadataSchemasPerTenant},
+		{name: "metadata filter", test: testMetadataFilter},
+		{name: "client history", test: testClientHistory},
+		{name: "client as of a moment", test: testClientAsOf},
+		{name: "group pool", test: testGroupPool},
+		{name: "group membership", test: testGroupMembership},
+		{name: "groups", test: testGroups},
+		{name: "archive and restore", test: testArchiveAndRestore},
+		{name: "archive keeping leads", test: testArchiveKeep},
+		{name: "archive to pending queue", test: testArchivePending},
+		{name: "archive of unknown clients", test: testArchiveUnknown},
+		{name: "client IDs", test: testClientIDs},
+		{name: "concurrent client creation", test: testConcurrentClientCreation},
+		{name: "concurrent assignments", test: testConcurrentAssignments},
+		{name: "concurrent group assignments", test: testConcurrentGroupAssignments},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+// testAssignmentOrder - higher priority first, then larger percentage of free capacity, then lower ID
+func testAssignmentOrder(t *testing.T, r Repository) {
+	a := createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 2})
+	b := createClient(t, r, ClientRequest{Name: "b", Priority: "HIGH", LeadCapacity: 4})
+	c := createClient(t, r, ClientRequest{Name: "c", Priority: "MEDIUM", LeadCapacity: 10})
+
+	want := []int{a, b, b, a, b, b, c, c}
+	for i, clientID := range want {
+		lead := assignLead(t, r, leadRequest())
+		if lead.ClientID != clientID {
+			t.Fatalf("lead %d went to client %d, want %d", i+1, lead.ClientID, clientID)
+		}
+	}
+}
+
+func testClientCapacity(t *testing.T, r Repository) {
+	clientID := createClient(t, r, ClientRequest{Name: "a", Priority: "LOW", LeadCapacity: 2})
+
+	for range 2 {
+		assignLead(t, r, leadRequest())
+	}
+	assertNoClients(t, r, leadRequest())
+
+	clients, err := r.GetClients(context.Background(), &clientID)
+	if err != nil {
+		t.Fatalf("GetClients: %v", err)
+	}
+	if len(clients) != 1 || len(clients[0].Leads) != 2 {
+		t.Fatalf("clients = %+v, want one client with 2 leads", clients)
+	}
+}
+
+// testClientsOrder - clients come ordered by ID, their leads by start date
+func testClientsOrder(t *testing.T, r Repository) {
+	for _, name := range []string{"c", "a", "b", "d"} {
+		createClient(t, r, ClientRequest{Name: name, Priority: "HIGH", LeadCapacity: 3})
+	}
+	for _, day := range []string{"2024-01-20", "2024-01-05", "2024-01-12", "2024-01-02"} {
+		start := date(day + "T00:00:00Z")
+		assignLead(t, r, AssignLeadRequest{LeadStart: Timestamp{Time: start}, LeadEnd: Timestamp{Time: start.AddDate(0, 0, 1)}})
+	}
+
+	clients, err := r.GetClients(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("GetClients: %v", err)
+	}
+	if got := clientIDs(clients); !slices.Equal(got, []int{1, 2, 3, 4}) {
+		t.Errorf("clients = %v, want them ordered by ID", got)
+	}
+	for _, c := range clients {
+		if !slices.IsSortedFunc(c.Leads, func(a, b Lead) int { return a.LeadStart.Compare(b.LeadStart) }) {
+			t.Errorf("leads of client %d = %+v, want them ordered by start date", c.ID, c.Leads)
+		}
+	}
+}
+
+// testTimeWindow - the lead window has to lie inside the client's one, bounds included
+func testTimeWindow(t *testing.T, r Repository) {
+	createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 10})
+
+	assignLead(t, r, AssignLeadRequest{LeadStart: Timestamp{Time: date("2024-01-01T00:00:00Z")}, LeadEnd: Timestamp{Time: date("2024-01-31T00:00:00Z")}})
+	assignLead(t, r, AssignLeadRequest{LeadStart: Timestamp{Time: date("2024-01-01T02:00:00+02:00")}, LeadEnd: Timestamp{Time: date("2024-01-02T00:00:00Z")}})
+
+	assertNoClients(t, r, AssignLeadRequest{LeadStart: Timestamp{Time: date("2024-01-30T00:00:00Z")}, LeadEnd: Timestamp{Time: date("2024-02-01T00:00:00Z")}})
+	assertNoClients(t, r, AssignLeadRequest{LeadStart: Timestamp{Time: date("2023-12-31T23:59:59Z")}, LeadEnd: Timestamp{Time: date("2024-01-02T00:00:00Z")}})
+}
+
+// testClientIDs - archived clients keep their IDs, new clients never get them
+func testClientIDs(t *testing.T, r Repository) {
+	for _, name := range []string{"a", "b", "c"} {
+		createClient(t, r, ClientRequest{Name: name, Priority: "HIGH", LeadCapacity: 1})
+	}
+	if _, err := r.ArchiveClient(context.Background(), 2, LeadsKeep); err != nil {
+		t.Fatalf("ArchiveClient: %v", err)
+	}
+
+	if id := createClient(t, r, ClientRequest{Name: "d", Priority: "HIGH", LeadCapacity: 1}); id != 4 {
+		t.Errorf("new client ID = %d, want 4", id)
+	}
+}
+
+// testConcurrentClientCreation - clients created at once all get their own IDs
+func testConcurrentClientCreation(t *testing.T, r Repository) {
+	const n = 20
+
+	ids := make(chan int, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := r.CreateClient(context.Background(), ClientRequest{
+				Name:         fmt.Sprintf("client_%d", i),
+				StartDate:    Timestamp{Time: date("2024-01-01T00:00:00Z")},
+				EndDate:      Timestamp{Time: date("2024-01-31T00:00:00Z")},
+				Priority:     "HIGH",
+				LeadCapacity: 1,
+			})
+			if err != nil {
+				t.Errorf("CreateClient: %v", err)
+				return
+			}
+			ids <- c.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("client ID %d given twice", id)
+		}
+		seen[id] = true
+	}
+
+	clients, err := r.GetClients(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("GetClients: %v", err)
+	}
+	if len(clients) != n {
+		t.Errorf("%d clients stored, want %d", len(clients), n)
+	}
+}
+
+// testConcurrentAssignments - parallel assignments never take more than the capacity of a client
+func testConcurrentAssignments(t *testing.T, r Repository) {
+	ids := []int{
+		createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 5}),
+		createClient(t, r, ClientRequest{Name: "b", Priority: "MEDIUM", LeadCapacity: 3}),
+	}
+
+	assigned := assignConcurrently(t, r, 20)
+	if assigned != 8 {
+		t.Errorf("%d leads assigned, want 8", assigned)
+	}
+
+	for _, id := range ids {
+		clients, err := r.GetClients(context.Background(), &id)
+		if err != nil {
+			t.Fatalf("GetClients: %v", err)
+		}
+		if len(clients[0].Leads) != clients[0].LeadCapacity {
+			t.Errorf("client %d has %d leads, want %d", id, len(clients[0].Leads), clients[0].LeadCapacity)
+		}
+	}
+}
+
+// testConcurrentGroupAssignments - parallel assignments never take more than the pool of a group
+func testConcurrentGroupAssignments(t *testing.T, r Repository) {
+	group, err := r.CreateGroup(context.Background(), ClientGroupRequest{Name: "agency", LeadCapacity: 4})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		createClient(t, r, ClientRequest{Name: name, Priority: "HIGH", LeadCapacity: 3, GroupID: &group.ID})
+	}
+
+	if assigned := assignConcurrently(t, r, 20); assigned != 4 {
+		t.Errorf("%d leads assigned, want 4", assigned)
+	}
+	if group = getGroup(t, r, group.ID); group.UsedCapacity != 4 {
+		t.Errorf("group = %+v, want 4 used", group)
+	}
+}
+
+// assignConcurrently - assigns `n` leads at once. Returns the number of assigned ones, the others found no client
+func assignConcurrently(t *testing.T, r Repository, n int) int {
+	t.Helper()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		assigned int
+	)
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := r.AssignLead(context.Background(), leadRequest())
+			if errors.Is(err, ErrNoClientsAvailable) {
+				return
+			}
+			if err != nil {
+				t.Errorf("AssignLead: %v", err)
+				return
+			}
+
+			mu.Lock()
+			assigned++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return assigned
+}
+
+// createClient - creates the client working through January 2024 unless the request has its own dates. Returns its ID
+func createClient(t *testing.T, r Repository, c ClientRequest) int {
+	t.Helper()
+
+	if c.StartDate.IsZero() {
+		c.StartDate = Timestamp{Time: date("2024-01-01T00:00:00Z")}
+		c.EndDate = Timestamp{Time: date("2024-01-31T00:00:00Z")}
+	}
+
+	client, err := r.CreateClient(context.Background(), c)
+	if err != nil {
+		t.Fatalf("CreateClient: %v", err)
+	}
+
+	return client.ID
+}
+
+// leadRequest - a lead on 10 January 2024
+func leadRequest() AssignLeadRequest {
+	return AssignLeadRequest{
+		LeadStart: Timestamp{Time: date("2024-01-10T09:00:00Z")},
+		LeadEnd:   Timestamp{Time: date("2024-01-10T18:00:00Z")},
+	}
+}
+
+func assignLead(t *testing.T, r Repository, l AssignLeadRequest) *Lead {
+	t.Helper()
+
+	lead, err := r.AssignLead(context.Background(), l)
+	if err != nil {
+		t.Fatalf("AssignLead: %v", err)
+	}
+
+	return lead
+}
+
+func assertNoClients(t *testing.T, r Repository, l AssignLeadRequest) {
+	t.Helper()
+
+	if lead, err := r.AssignLead(context.Background(), l); !errors.Is(err, ErrNoClientsAvailable) {
+		t.Fatalf("AssignLead = %+v, %v, want ErrNoClientsAvailable", lead, err)
+	}
+}
+
+func getGroup(t *testing.T, r Repository, groupID int) *ClientGroup {
+	t.Helper()
+
+	groups, err := r.GetGroups(context.Background(), &groupID)
+	if err != nil || len(groups) != 1 {
+		t.Fatalf("GetGroups = %+v, %v, want the group %d", groups, err, groupID)
+	}
+
+	return &groups[0]
+}
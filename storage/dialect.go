@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dialect - what differs between the SQL databases Storage works with. Queries are written with `?` placeholders
+// and SQL both databases understand, dialect-specific parts come from here
+type dialect interface {
+	// rebind - the query with placeholders of the database
+	rebind(query string) string
+	// queryFile - path of the dialect's own version of a storage/queries file, "" when the shared one fits
+	queryFile(name string) string
+	// migrationsDir - directory with the migrations of the database schema
+	migrationsDir() string
+	// metadataCondition - condition matching the metadata value at the dot-separated `path` of the JSON `column`
+	metadataCondition(column, path, value string) (string, []interface{})
+	// prefixCondition - case-insensitive condition matching text columns starting with the argument (see escapeLike)
+	prefixCondition(column string) string
+	// tableExistsQuery - query telling whether the table named by the argument exists
+	tableExistsQuery() string
+	// lockWrites - serializes transactions that change data, so capacity checks and history versions don't race
+	lockWrites(ctx context.Context, tx *sql.Tx) error
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) rebind(query string) string {
+	return query
+}
+
+func (sqliteDialect) queryFile(string) string {
+	return ""
+}
+
+func (sqliteDialect) migrationsDir() string {
+	return SQLiteMigrationsDir
+}
+
+// metadataCondition - json_extract turns booleans into numbers, so json_type tells them apart: strings are 'text',
+// numbers 'integer' or 'real', booleans 'true' or 'false'
+func (sqliteDialect) metadataCondition(column, path, value string) (string, []interface{}) {
+	path = "$." + path
+
+	var matches []string
+	var args []interface{}
+	for _, expected := range metadataFilterValues(value) {
+		switch v := expected.(type) {
+		case bool:
+			matches = append(matches, fmt.Sprintf("json_type(%s, ?) = ?", column))
+			args = append(args, path, strconv.FormatBool(v))
+		case float64:
+			matches = append(matches, fmt.Sprintf("(json_type(%[1]s, ?) IN ('integer', 'real') AND json_extract(%[1]s, ?) = ?)", column))
+			args = append(args, path, path, v)
+		default:
+			matches = append(matches, fmt.Sprintf("(json_type(%[1]s, ?) = 'text' AND json_extract(%[1]s, ?) = ?)", column))
+			args = append(args, path, path, v)
+		}
+	}
+
+	return "(" + strings.Join(matches, " OR ") + ")", args
+}
+
+// prefixCondition - LIKE of SQLite already ignores the case
+func (sqliteDialect) prefixCondition(column string) string {
+	return column + ` LIKE ? ESCAPE '\'`
+}
+
+func (sqliteDialect) tableExistsQuery() string {
+	return `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)`
+}
+
+// lockWrites - SQLite has a single writer at a time anyway
+func (sqliteDialect) lockWrites(context.Context, *sql.Tx) error {
+	return nil
+}
+
+// dialectDB - DB running queries written with `?` placeholders on the database of the dialect
+type dialectDB struct {
+	DB
+	dialect dialect
+}
+
+func (db dialectDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, db.dialect.rebind(query), args...)
+}
+
+func (db dialectDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.dialect.rebind(query), args...)
+}
+
+func (db dialectDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.dialect.rebind(query), args...)
+}
+
+func (db dialectDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return db.DB.PrepareContext(ctx, db.dialect.rebind(query))
+}
+
+// BeginTx - starts a transaction that changes data. Such transactions of different requests run one after another
+func (db dialectDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*dialectTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.dialect.lockWrites(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	return &dialectTx{Tx: tx, dialect: db.dialect}, nil
+}
+
+type dialectTx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+func (tx *dialectTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.dialect.rebind(query), args...)
+}
+
+func (tx *dialectTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.dialect.rebind(query), args...)
+}
+
+func (tx *dialectTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.dialect.rebind(query), args...)
+}
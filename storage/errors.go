@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of storage errors. Check them with errors.Is, every *Error matches its kind
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrNoCapacity   = errors.New("no capacity")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrUnsupported  = errors.New("unsupported")
+)
+
+// ErrNoClientsAvailable - the assignment engine found no client able to take the lead
+var ErrNoClientsAvailable error = &Error{
+	Kind:    ErrNoCapacity,
+	Code:    CodeNoClientsAvailable,
+	Message: "there are no clients available to assign",
+}
+
+// ErrorCode - stable machine-readable reason of an error. Codes are never renamed, messages may change
+type ErrorCode = string
+
+const (
+	CodeClientNotFound         ErrorCode = "client_not_found"
+	CodeLeadNotFound           ErrorCode = "lead_not_found"
+	CodeGroupNotFound          ErrorCode = "group_not_found"
+	CodeMetadataSchemaNotFound ErrorCode = "metadata_schema_not_found"
+	CodeClientArchived         ErrorCode = "client_archived"
+	CodeNoClientsAvailable     ErrorCode = "no_clients_available"
+	CodeInvalidLeadsPolicy     ErrorCode = "invalid_leads_policy"
+	CodeInvalidImportMode      ErrorCode = "invalid_import_mode"
+	CodeInvalidSort            ErrorCode = "invalid_sort"
+	CodeInvalidCursor          ErrorCode = "invalid_cursor"
+	CodeInvalidMetadata        ErrorCode = "invalid_metadata"
+	CodeInvalidMetadataKey     ErrorCode = "invalid_metadata_key"
+	CodeInvalidMetadataSchema  ErrorCode = "invalid_metadata_schema"
+	CodeUnknownMetadataEntity  ErrorCode = "unknown_metadata_entity"
+	CodeUnknownGroup           ErrorCode = "unknown_group"
+	CodeBackupUnsupported      ErrorCode = "backup_unsupported"
+	CodeBackupExists           ErrorCode = "backup_exists"
+	CodeBackupNotFound         ErrorCode = "backup_not_found"
+	CodeInvalidBackup          ErrorCode = "invalid_backup"
+	CodeIncompatibleBackup     ErrorCode = "incompatible_backup"
+	CodeCapacityIndexDisabled  ErrorCode = "capacity_index_disabled"
+)
+
+// Error - storage error with its kind and code, so callers can react without parsing messages
+type Error struct {
+	Kind    error
+	Code    ErrorCode
+	Message string // Includes the message of the cause
+	Err     error  // Cause, if any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NotFound - the requested entity does not exist
+func NotFound(code ErrorCode, format string, args ...any) error {
+	return newError(ErrNotFound, code, format, args...)
+}
+
+func conflict(code ErrorCode, format string, args ...any) error {
+	return newError(ErrConflict, code, format, args...)
+}
+
+func invalidInput(code ErrorCode, format string, args ...any) error {
+	return newError(ErrInvalidInput, code, format, args...)
+}
+
+// unsupported - the storage backend can't do what was asked
+func unsupported(code ErrorCode, format string, args ...any) error {
+	return newError(ErrUnsupported, code, format, args...)
+}
+
+// newError - formats the message like fmt.Errorf, `%w` argument becomes the cause
+func newError(kind error, code ErrorCode, format string, args ...any) error {
+	wrapped := fmt.Errorf(format, args...)
+
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: wrapped.Error(),
+		Err:     errors.Unwrap(wrapped),
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// EachClient - streams every client matching the filter to `fn` straight from the database cursor.
+// Leads are not loaded, `used` is the number of leads assigned to the client. Pagination fields of the filter are ignored
+func (s *Storage) EachClient(ctx context.Context, f ClientsFilter, fn func(c Client, used int) error) error {
+	f.Cursor = ""
+
+	query, args, _, err := s.clientsQuery(f)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get clients: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		client, used, err := scanClientsPageRow(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(client, used); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// EachLead - streams every lead matching the filter to `fn` straight from the database cursor.
+// Pagination fields of the filter are ignored
+func (s *Storage) EachLead(ctx context.Context, f LeadsFilter, fn func(l Lead) error) error {
+	f.Cursor = ""
+
+	query, args, err := s.leadsQuery(f)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get leads: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		lead, err := scanLead(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(*lead); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
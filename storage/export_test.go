@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// errStop - error of the streaming callbacks that stops the stream
+var errStop = errors.New("stop")
+
+// testEachClient - clients matching the filter are streamed in its sort order with their used capacity, regardless
+// of the pagination fields
+func testEachClient(t *testing.T, r Repository) {
+	for _, name := range []string{"acme", "beta", "acorn"} {
+		createClient(t, r, ClientRequest{Name: name, Priority: "HIGH", LeadCapacity: 2})
+	}
+	for range 3 {
+		assignLead(t, r, leadRequest())
+	}
+
+	clients, err := r.GetClients(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("GetClients: %v", err)
+	}
+
+	var names []string
+	f := ClientsFilter{NamePrefix: "ac", Sort: "-name", Limit: 1, Cursor: "ignored"}
+	err = r.EachClient(context.Background(), f, func(c Client, used int) error {
+		names = append(names, c.Name)
+		if want := len(clients[c.ID-1].Leads); used != want {
+			t.Errorf("client %s used %d, want %d", c.Name, used, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachClient: %v", err)
+	}
+	if !slices.Equal(names, []string{"acorn", "acme"}) {
+		t.Errorf("clients = %v, want acorn and acme", names)
+	}
+
+	streamed := 0
+	err = r.EachClient(context.Background(), ClientsFilter{}, func(c Client, used int) error {
+		streamed++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || streamed != 1 {
+		t.Errorf("EachClient = %v after %d clients, want the error of the first one", err, streamed)
+	}
+}
+
+// testEachLead - leads matching the filter are streamed by start date, regardless of the pagination fields
+func testEachLead(t *testing.T, r Repository) {
+	createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 3})
+	createClient(t, r, ClientRequest{Name: "b", Priority: "LOW", LeadCapacity: 3})
+	for _, day := range []int{9, 3, 7, 5, 4} {
+		assignLead(t, r, leadOn(day))
+	}
+
+	clientID := 1
+	want := listLeads(t, r, LeadsFilter{ClientID: &clientID})
+
+	var got []Lead
+	err := r.EachLead(context.Background(), LeadsFilter{ClientID: &clientID, Limit: 1, Cursor: "ignored"}, func(l Lead) error {
+		got = append(got, l)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachLead: %v", err)
+	}
+	if len(want) != 3 || !slices.Equal(leadIDs(got), leadIDs(want)) {
+		t.Errorf("leads = %v, want %v", leadIDs(got), leadIDs(want))
+	}
+
+	streamed := 0
+	err = r.EachLead(context.Background(), LeadsFilter{}, func(l Lead) error {
+		streamed++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || streamed != 1 {
+		t.Errorf("EachLead = %v after %d leads, want the error of the first one", err, streamed)
+	}
+}
@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GetGroups - receives a list of client groups with their capacity usage. Optional parameter `groupID`.
+// When passed, will receive only selected group
+func (s *Storage) GetGroups(ctx context.Context, groupID *int) ([]ClientGroup, error) {
+	return s.getGroups(ctx, s.db, groupID)
+}
+
+func (s *Storage) getGroups(ctx context.Context, q queryer, groupID *int) ([]ClientGroup, error) {
+	query := s.queries[queryGroups]
+
+	var args []interface{}
+	if groupID != nil {
+		query += " WHERE g.id = ?"
+		args = append(args, *groupID)
+	}
+	query += " ORDER BY g.id"
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []ClientGroup
+	for rows.Next() {
+		var group ClientGroup
+		var members sql.NullString
+
+		if err := rows.Scan(&group.ID, &group.Name, &group.LeadCapacity, &group.UsedCapacity, &members); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		group.AvailableCapacity = max(group.LeadCapacity-group.UsedCapacity, 0)
+		group.Members = []int{}
+		if members.Valid {
+			for _, member := range strings.Split(members.String, ",") {
+				clientID, _ := strconv.Atoi(member)
+				group.Members = append(group.Members, clientID)
+			}
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+// CreateGroup - creates a new client group without members
+func (s *Storage) CreateGroup(ctx context.Context, g ClientGroupRequest) (*ClientGroup, error) {
+	unlock := s.lockCapacity()
+	defer unlock()
+
+	var groupID int
+	q := `INSERT INTO client_groups (name, lead_capacity) VALUES (?, ?) RETURNING id`
+	if err := s.db.QueryRowContext(ctx, q, g.Name, g.LeadCapacity).Scan(&groupID); err != nil {
+		return nil, fmt.Errorf("failed to create group: %w", err)
+	}
+	s.updateCapacity(ctx, nil, []int{groupID})
+
+	return &ClientGroup{
+		ID:                groupID,
+		Name:              g.Name,
+		LeadCapacity:      g.LeadCapacity,
+		AvailableCapacity: g.LeadCapacity,
+		Members:           []int{},
+	}, nil
+}
+
+// UpdateGroup - changes name and capacity of the group. Returns nil group when it does not exist
+func (s *Storage) UpdateGroup(ctx context.Context, groupID int, g ClientGroupRequest) (*ClientGroup, error) {
+	unlock := s.lockCapacity()
+	defer unlock()
+
+	q := `UPDATE client_groups SET name = ?, lead_capacity = ? WHERE id = ?`
+	if _, err := s.db.ExecContext(ctx, q, g.Name, g.LeadCapacity, groupID); err != nil {
+		return nil, fmt.Errorf("failed to update group: %w", err)
+	}
+	s.updateCapacity(ctx, nil, []int{groupID})
+
+	return s.getGroup(ctx, s.db, groupID)
+}
+
+// DeleteGroup - deletes the group, its members become standalone clients. Returns false when the group does not exist
+func (s *Storage) DeleteGroup(ctx context.Context, groupID int) (bool, error) {
+	unlock := s.lockCapacity()
+	defer unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("can't start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	members, err := groupMembers(ctx, tx, groupID)
+	if err != nil {
+		return false, err
+	}
+
+	states := make([]*clientState, len(members))
+	for i, clientID := range members {
+		if states[i], err = loadClientState(ctx, tx, clientID); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE clients SET group_id = NULL WHERE group_id = ?`, groupID); err != nil {
+		return false, fmt.Errorf("failed to release group members: %w", err)
+	}
+
+	for i, clientID := range members {
+		if err := recordClientChange(ctx, tx, clientID, ClientUpdated, states[i]); err != nil {
+			return false, err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM client_groups WHERE id = ?`, groupID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete group: %w", err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete group: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("can't commit transaction: %w", err)
+	}
+	s.updateCapacity(ctx, members, []int{groupID})
+
+	return deleted > 0, nil
+}
+
+// SetGroupMember - moves an active client into the group (or out of any group when `groupID` is nil).
+// Returns nil group when the group or the client does not exist
+func (s *Storage) SetGroupMember(ctx context.Context, groupID *int, clientID int) (*ClientGroup, error) {
+	return s.moveClient(ctx, clientID, nil, groupID)
+}
+
+// RemoveGroupMember - moves the client out of the group `groupID`. Returns nil group when the client is not an active
+// member of the group, e.g. it was moved to another group meanwhile
+func (s *Storage) RemoveGroupMember(ctx context.Context, groupID int, clientID int) (*ClientGroup, error) {
+	return s.moveClient(ctx, clientID, &groupID, nil)
+}
+
+// moveClient - moves an active client from the group `from` (from any group or none when it's nil) to the group `to`
+// (out of any group when it's nil). Membership is checked by the update itself, so a concurrent move is never undone.
+// Returns the group `to`, or `from` when `to` is nil. Returns nil group when a group or the client does not exist, or
+// the client is not a member of `from`
+func (s *Storage) moveClient(ctx context.Context, clientID int, from, to *int) (*ClientGroup, error) {
+	unlock := s.lockCapacity()
+	defer unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if to != nil {
+		group, err := s.getGroup(ctx, tx, *to)
+		if err != nil || group == nil {
+			return nil, err
+		}
+	}
+
+	old, err := loadClientState(ctx, tx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	q := `UPDATE clients SET group_id = ? WHERE id = ? AND archived_at IS NULL`
+	args := []any{to, clientID}
+	if from != nil {
+		q += ` AND group_id = ?`
+		args = append(args, *from)
+	}
+	res, err := tx.ExecContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update group members: %w", err)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update group members: %w", err)
+	}
+	if updated == 0 {
+		return nil, nil
+	}
+
+	if err := recordClientChange(ctx, tx, clientID, ClientUpdated, old); err != nil {
+		return nil, err
+	}
+
+	var group *ClientGroup
+	if result := cmp.Or(to, from); result != nil {
+		// Read the group after the move to include the member change and its leads
+		if group, err = s.getGroup(ctx, tx, *result); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("can't commit transaction: %w", err)
+	}
+	s.updateCapacity(ctx, []int{clientID}, nil)
+
+	return group, nil
+}
+
+func (s *Storage) getGroup(ctx context.Context, q queryer, groupID int) (*ClientGroup, error) {
+	groups, err := s.getGroups(ctx, q, &groupID)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	return &groups[0], nil
+}
+
+// groupMembers - IDs of all clients of the group, archived ones included
+func groupMembers(ctx context.Context, q queryer, groupID int) ([]int, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id FROM clients WHERE group_id = ? ORDER BY id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []int
+	for rows.Next() {
+		var clientID int
+		if err := rows.Scan(&clientID); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		members = append(members, clientID)
+	}
+
+	return members, rows.Err()
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// testGroups - groups are created, listed, updated and deleted, their members stay as standalone clients
+func testGroups(t *testing.T, r Repository) {
+	ctx := context.Background()
+
+	agency, err := r.CreateGroup(ctx, ClientGroupRequest{Name: "agency", LeadCapacity: 2})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if agency.Name != "agency" || agency.LeadCapacity != 2 || agency.AvailableCapacity != 2 || len(agency.Members) != 0 {
+		t.Errorf("CreateGroup = %+v, want an empty group of 2 leads", agency)
+	}
+	other, err := r.CreateGroup(ctx, ClientGroupRequest{Name: "other", LeadCapacity: 1})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+
+	unknown := 99
+	a := createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 3, GroupID: &agency.ID})
+	b := createClient(t, r, ClientRequest{Name: "b", Priority: "HIGH", LeadCapacity: 3, GroupID: &agency.ID})
+	if _, err := r.CreateClient(ctx, ClientRequest{Name: "c", Priority: "HIGH", LeadCapacity: 3, GroupID: &unknown}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("CreateClient in unknown group error = %v, want ErrInvalidInput", err)
+	}
+	assignLead(t, r, leadRequest())
+
+	groups, err := r.GetGroups(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetGroups: %v", err)
+	}
+	if len(groups) != 2 || groups[0].ID != agency.ID || groups[1].ID != other.ID {
+		t.Fatalf("GetGroups = %+v, want both groups", groups)
+	}
+	if g := groups[0]; !slices.Equal(g.Members, []int{a, b}) || g.UsedCapacity != 1 || g.AvailableCapacity != 1 {
+		t.Errorf("group = %+v, want members %d and %d with one lead", g, a, b)
+	}
+
+	clients, err := r.GetClients(ctx, &a)
+	if err != nil || len(clients) != 1 {
+		t.Fatalf("GetClients = %+v, %v", clients, err)
+	}
+	if clients[0].GroupID == nil || *clients[0].GroupID != agency.ID {
+		t.Errorf("client = %+v, want it in the group %d", clients[0], agency.ID)
+	}
+
+	updated, err := r.UpdateGroup(ctx, agency.ID, ClientGroupRequest{Name: "agency ltd", LeadCapacity: 5})
+	if err != nil {
+		t.Fatalf("UpdateGroup: %v", err)
+	}
+	if updated.Name != "agency ltd" || updated.LeadCapacity != 5 || updated.AvailableCapacity != 4 || len(updated.Members) != 2 {
+		t.Errorf("UpdateGroup = %+v, want the renamed group of 5 leads", updated)
+	}
+	if g, err := r.UpdateGroup(ctx, 99, ClientGroupRequest{Name: "none", LeadCapacity: 1}); g != nil || err != nil {
+		t.Errorf("UpdateGroup of unknown group = %+v, %v, want nil group", g, err)
+	}
+
+	if g, err := r.SetGroupMember(ctx, &unknown, a); g != nil || err != nil {
+		t.Errorf("SetGroupMember to unknown group = %+v, %v, want nil group", g, err)
+	}
+	if g, err := r.SetGroupMember(ctx, &other.ID, 99); g != nil || err != nil {
+		t.Errorf("SetGroupMember of unknown client = %+v, %v, want nil group", g, err)
+	}
+
+	if deleted, err := r.DeleteGroup(ctx, agency.ID); !deleted || err != nil {
+		t.Fatalf("DeleteGroup = %v, %v, want deleted", deleted, err)
+	}
+	if deleted, err := r.DeleteGroup(ctx, agency.ID); deleted || err != nil {
+		t.Errorf("DeleteGroup of deleted group = %v, %v, want not deleted", deleted, err)
+	}
+	if groups, err := r.GetGroups(ctx, &agency.ID); err != nil || len(groups) != 0 {
+		t.Errorf("GetGroups of deleted group = %+v, %v, want none", groups, err)
+	}
+
+	// Former members keep their leads and take new ones from their own capacity
+	clients, err = r.GetClients(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetClients: %v", err)
+	}
+	for _, c := range clients {
+		if c.GroupID != nil {
+			t.Errorf("client %d is still in the group %d", c.ID, *c.GroupID)
+		}
+	}
+	for range 5 {
+		assignLead(t, r, leadRequest())
+	}
+	assertNoClients(t, r, leadRequest())
+}
+
+// testGroupPool - leads of all members are taken from the group's capacity
+func testGroupPool(t *testing.T, r Repository) {
+	ctx := context.Background()
+
+	group, err := r.CreateGroup(ctx, ClientGroupRequest{Name: "agency", LeadCapacity: 3})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	a := createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 5, GroupID: &group.ID})
+	b := createClient(t, r, ClientRequest{Name: "b", Priority: "HIGH", LeadCapacity: 5, GroupID: &group.ID})
+	outside := createClient(t, r, ClientRequest{Name: "outside", Priority: "LOW", LeadCapacity: 5})
+
+	want := []int{a, b, a, outside}
+	for i, clientID := range want {
+		lead := assignLead(t, r, leadRequest())
+		if lead.ClientID != clientID {
+			t.Fatalf("lead %d went to client %d, want %d", i+1, lead.ClientID, clientID)
+		}
+	}
+
+	group = getGroup(t, r, group.ID)
+	if group.UsedCapacity != 3 || group.AvailableCapacity != 0 {
+		t.Errorf("group = %+v, want 3 used and nothing available", group)
+	}
+
+	// Leads of an archived member still take the pool
+	if _, err := r.ArchiveClient(ctx, b, LeadsKeep); err != nil {
+		t.Fatalf("ArchiveClient: %v", err)
+	}
+	if lead := assignLead(t, r, leadRequest()); lead.ClientID != outside {
+		t.Errorf("lead went to client %d, want %d", lead.ClientID, outside)
+	}
+	if group = getGroup(t, r, group.ID); group.UsedCapacity != 3 || len(group.Members) != 1 {
+		t.Errorf("group = %+v, want 3 used by one active member", group)
+	}
+
+	// A larger pool lets the members take leads again
+	if _, err := r.UpdateGroup(ctx, group.ID, ClientGroupRequest{Name: "agency", LeadCapacity: 4}); err != nil {
+		t.Fatalf("UpdateGroup: %v", err)
+	}
+	if lead := assignLead(t, r, leadRequest()); lead.ClientID != a {
+		t.Errorf("lead went to client %d, want %d", lead.ClientID, a)
+	}
+}
+
+func testGroupMembership(t *testing.T, r Repository) {
+	ctx := context.Background()
+
+	group, err := r.CreateGroup(ctx, ClientGroupRequest{Name: "agency", LeadCapacity: 1})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	other, err := r.CreateGroup(ctx, ClientGroupRequest{Name: "other", LeadCapacity: 1})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	clientID := createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 5})
+
+	if g, err := r.SetGroupMember(ctx, &group.ID, clientID); err != nil || g == nil || len(g.Members) != 1 {
+		t.Fatalf("SetGroupMember = %+v, %v, want the group with the client", g, err)
+	}
+	assignLead(t, r, leadRequest())
+	assertNoClients(t, r, leadRequest())
+
+	if g, err := r.RemoveGroupMember(ctx, other.ID, clientID); err != nil || g != nil {
+		t.Fatalf("RemoveGroupMember of another group = %+v, %v, want nil group", g, err)
+	}
+	if g, err := r.RemoveGroupMember(ctx, group.ID, clientID); err != nil || g == nil || len(g.Members) != 0 || g.UsedCapacity != 0 {
+		t.Fatalf("RemoveGroupMember = %+v, %v, want the group without members", g, err)
+	}
+
+	// Out of the group the client has its own capacity only
+	assignLead(t, r, leadRequest())
+}
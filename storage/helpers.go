@@ -1,11 +1,22 @@
 package storage
 
-import "os"
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
 
+// SQLHelpersReader - source of the SQL files. Paths are relative to the repository root, e.g. storage/queries/leads.sql
 type SQLHelpersReader interface {
 	ReadSQLFile(filepath string) (string, error)
+	// ListSQLFiles - names of the files in the directory `dir`, sorted
+	ListSQLFiles(dir string) ([]string, error)
 }
 
+// SQLHelpers - reads the SQL files from disk, so edits are picked up on restart without rebuilding.
+// Meant for development, the binary has to be started from the repository root
 type SQLHelpers struct{}
 
 func NewSQLHelper() *SQLHelpers {
@@ -19,3 +30,51 @@ func (s *SQLHelpers) ReadSQLFile(filepath string) (string, error) {
 	}
 	return string(bytes), nil
 }
+
+func (s *SQLHelpers) ListSQLFiles(dir string) ([]string, error) {
+	return listSQLFiles(os.DirFS("."), dir)
+}
+
+//go:embed queries/*.sql migrations/*.sql postgres/queries/*.sql postgres/migrations/*.sql
+var embeddedSQL embed.FS
+
+// EmbeddedSQL - the SQL files built into the binary
+type EmbeddedSQL struct{}
+
+func NewEmbeddedSQL() *EmbeddedSQL {
+	return &EmbeddedSQL{}
+}
+
+func (e *EmbeddedSQL) ReadSQLFile(filepath string) (string, error) {
+	bytes, err := fs.ReadFile(embeddedSQL, embeddedPath(filepath))
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+func (e *EmbeddedSQL) ListSQLFiles(dir string) ([]string, error) {
+	return listSQLFiles(embeddedSQL, embeddedPath(dir))
+}
+
+// embeddedPath - files are embedded relative to the storage package
+func embeddedPath(filepath string) string {
+	return strings.TrimPrefix(filepath, "storage/")
+}
+
+func listSQLFiles(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
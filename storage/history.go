@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const anonymousActor = "anonymous"
+
+type actorKey struct{}
+
+// WithActor - returns a context whose changes are recorded in the history as made by `actor`
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+
+	return anonymousActor
+}
+
+// clientState - values of a client row kept in the history
+type clientState struct {
+	Name         string     `json:"name"`
+	StartDate    time.Time  `json:"start_date"`
+	EndDate      time.Time  `json:"end_date"`
+	Priority     Priority   `json:"priority"`
+	LeadCapacity int        `json:"lead_capacity"`
+	GroupID      *int       `json:"group_id"`
+	Metadata     Metadata   `json:"metadata"`
+	ArchivedAt   *time.Time `json:"archived_at"`
+}
+
+// loadClientState - current values of the client row, archived or not. Returns nil state when the client does not exist
+func loadClientState(ctx context.Context, q queryer, clientID int) (*clientState, error) {
+	var state clientState
+	var groupID sql.NullInt64
+	var metadata sql.NullString
+	var archivedAt time.Time
+
+	query := `SELECT name, start_date, end_date, priority, lead_capacity, group_id, metadata, archived_at FROM clients WHERE id = ?`
+	err := q.QueryRowContext(ctx, query, clientID).Scan(
+		&state.Name,
+		scanTime(&state.StartDate),
+		scanTime(&state.EndDate),
+		&state.Priority,
+		&state.LeadCapacity,
+		&groupID,
+		&metadata,
+		scanTime(&archivedAt),
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client: %w", err)
+	}
+
+	state.GroupID = nullableInt(groupID)
+	state.Metadata = metadataValue(metadata)
+	if !archivedAt.IsZero() {
+		state.ArchivedAt = &archivedAt
+	}
+
+	return &state, nil
+}
+
+// recordClientChange - saves a new version of the client with its current values. `old` is nil for created clients
+func recordClientChange(ctx context.Context, q queryer, clientID int, operation ClientOperation, old *clientState) error {
+	current, err := loadClientState(ctx, q, clientID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("can't record history of client %d: client does not exist", clientID)
+	}
+
+	newValues, err := json.Marshal(current)
+	if err != nil {
+		return fmt.Errorf("can't record history of client %d: %w", clientID, err)
+	}
+
+	var oldValues interface{}
+	if old != nil {
+		raw, err := json.Marshal(old)
+		if err != nil {
+			return fmt.Errorf("can't record history of client %d: %w", clientID, err)
+		}
+		oldValues = string(raw)
+	}
+
+	query := `INSERT INTO client_history (client_id, version, operation, changed_at, changed_by, old_values, new_values)
+		VALUES (?, (SELECT COALESCE(MAX(version), 0) + 1 FROM client_history WHERE client_id = ?), ?, ?, ?, ?, ?)`
+	_, err = q.ExecContext(
+		ctx,
+		query,
+		clientID,
+		clientID,
+		operation,
+		time.Now().UTC().Format(historyTimeLayout),
+		actorFrom(ctx),
+		oldValues,
+		string(newValues),
+	)
+	if err != nil {
+		return fmt.Errorf("can't record history of client %d: %w", clientID, err)
+	}
+
+	return nil
+}
+
+// recordLeadChange - saves the owner and status the lead has from now on. `clientID` is 0 for leads without a client
+func recordLeadChange(ctx context.Context, q queryer, leadID string, clientID int, status LeadStatus) error {
+	var owner interface{}
+	if clientID != 0 {
+		owner = clientID
+	}
+
+	query := `INSERT INTO lead_history (lead_id, client_id, status, changed_at, changed_by) VALUES (?, ?, ?, ?, ?)`
+	_, err := q.ExecContext(ctx, query, leadID, owner, status, time.Now().UTC().Format(historyTimeLayout), actorFrom(ctx))
+	if err != nil {
+		return fmt.Errorf("can't record history of lead %s: %w", leadID, err)
+	}
+
+	return nil
+}
+
+// ClientHistory - receives all versions of the client, oldest first. Returns nil when the client has no history
+func (s *Storage) ClientHistory(ctx context.Context, clientID int) ([]ClientChange, error) {
+	query := `SELECT version, operation, changed_at, changed_by, old_values, new_values
+		FROM client_history WHERE client_id = ? ORDER BY version`
+	rows, err := s.db.QueryContext(ctx, query, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client history: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []ClientChange
+	for rows.Next() {
+		var change ClientChange
+		var oldValues sql.NullString
+		var newValues string
+
+		err := rows.Scan(&change.Version, &change.Operation, scanTime(&change.ChangedAt), &change.ChangedBy, &oldValues, &newValues)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if oldValues.Valid {
+			change.OldValues = json.RawMessage(oldValues.String)
+		}
+		change.NewValues = json.RawMessage(newValues)
+
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
+
+// ClientAsOf - receives the client as it was at the moment `asOf`, including the number of leads assigned to it then.
+// Returns nil client when it did not exist at that moment (or its history starts later)
+func (s *Storage) ClientAsOf(ctx context.Context, clientID int, asOf time.Time) (*ClientAsOf, error) {
+	at := asOf.UTC().Format(historyTimeLayout)
+	asOf, _ = time.Parse(historyTimeLayout, at) // The moment as precise as the history is
+
+	var version int
+	var values string
+
+	query := `SELECT version, new_values FROM client_history
+		WHERE client_id = ? AND changed_at <= ? ORDER BY version DESC LIMIT 1`
+	err := s.db.QueryRowContext(ctx, query, clientID, at).Scan(&version, &values)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client history: %w", err)
+	}
+
+	var state clientState
+	if err := json.Unmarshal([]byte(values), &state); err != nil {
+		return nil, fmt.Errorf("invalid history of client %d: %w", clientID, err)
+	}
+
+	countLeads, err := s.stmt(ctx, s.db, queryLeadCountAsOf)
+	if err != nil {
+		return nil, err
+	}
+
+	var leadCount int
+	if err := countLeads.QueryRowContext(ctx, at, clientID).Scan(&leadCount); err != nil {
+		return nil, fmt.Errorf("failed to count leads: %w", err)
+	}
+
+	return &ClientAsOf{
+		ID:           clientID,
+		Name:         state.Name,
+		StartDate:    state.StartDate,
+		EndDate:      state.EndDate,
+		Priority:     state.Priority,
+		LeadCapacity: state.LeadCapacity,
+		GroupID:      state.GroupID,
+		Metadata:     state.Metadata,
+		ArchivedAt:   state.ArchivedAt,
+		LeadCount:    leadCount,
+		Version:      version,
+		AsOf:         asOf,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testClientHistory - every change of a client is a new version with the actor, the old and the new values
+func testClientHistory(t *testing.T, r Repository) {
+	ctx := context.Background()
+
+	clientID := createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 2})
+	if _, err := r.UpdateClientMetadata(WithActor(ctx, "bob"), clientID, Metadata(`{"crm_id": "c-1"}`)); err != nil {
+		t.Fatalf("UpdateClientMetadata: %v", err)
+	}
+	group, err := r.CreateGroup(ctx, ClientGroupRequest{Name: "agency", LeadCapacity: 1})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if _, err := r.SetGroupMember(WithActor(ctx, "ann"), &group.ID, clientID); err != nil {
+		t.Fatalf("SetGroupMember: %v", err)
+	}
+	if _, err := r.ArchiveClient(WithActor(ctx, "ann"), clientID, LeadsKeep); err != nil {
+		t.Fatalf("ArchiveClient: %v", err)
+	}
+	if _, err := r.RestoreClient(ctx, clientID); err != nil {
+		t.Fatalf("RestoreClient: %v", err)
+	}
+
+	history, err := r.ClientHistory(ctx, clientID)
+	if err != nil {
+		t.Fatalf("ClientHistory: %v", err)
+	}
+
+	want := []struct {
+		operation ClientOperation
+		actor     string
+	}{
+		{ClientCreated, anonymousActor},
+		{ClientUpdated, "bob"},
+		{ClientUpdated, "ann"},
+		{ClientArchived, "ann"},
+		{ClientRestored, anonymousActor},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("history = %+v, want %d versions", history, len(want))
+	}
+	for i, change := range history {
+		if change.Version != i+1 || change.Operation != want[i].operation || change.ChangedBy != want[i].actor {
+			t.Errorf("version %d = %+v, want %s by %s", i+1, change, want[i].operation, want[i].actor)
+		}
+		if i > 0 && (change.ChangedAt.Before(history[i-1].ChangedAt) || !slices.Equal(change.OldValues, history[i-1].NewValues)) {
+			t.Errorf("version %d = %+v, want it after the version %d and starting from its values", i+1, change, i)
+		}
+	}
+	if history[0].OldValues != nil {
+		t.Errorf("created version = %+v, want no old values", history[0])
+	}
+
+	var values clientState
+	if err := json.Unmarshal(history[2].NewValues, &values); err != nil {
+		t.Fatal(err)
+	}
+	if values.GroupID == nil || *values.GroupID != group.ID || string(values.Metadata) != `{"crm_id":"c-1"}` || values.LeadCapacity != 2 {
+		t.Errorf("values of version 3 = %+v, want the client in the group with its metadata", values)
+	}
+
+	if history, err := r.ClientHistory(ctx, 99); history != nil || err != nil {
+		t.Errorf("ClientHistory of unknown client = %+v, %v, want none", history, err)
+	}
+}
+
+// testClientAsOf - the client as it was at a moment, with the number of leads it had then
+func testClientAsOf(t *testing.T, r Repository) {
+	ctx := context.Background()
+
+	// The history keeps milliseconds, moments a few of them apart are always told apart
+	moment := func() time.Time {
+		time.Sleep(5 * time.Millisecond)
+		defer time.Sleep(5 * time.Millisecond)
+		return time.Now()
+	}
+
+	beforeCreate := moment()
+	clientID := createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 2})
+	created := moment()
+	assignLead(t, r, leadRequest())
+	assigned := moment()
+	if _, err := r.UpdateClientMetadata(ctx, clientID, Metadata(`{"crm_id": "c-1"}`)); err != nil {
+		t.Fatalf("UpdateClientMetadata: %v", err)
+	}
+	updated := moment()
+	if _, err := r.ArchiveClient(ctx, clientID, LeadsPending); err != nil {
+		t.Fatalf("ArchiveClient: %v", err)
+	}
+	archived := moment()
+
+	if client, err := r.ClientAsOf(ctx, clientID, beforeCreate); client != nil || err != nil {
+		t.Errorf("ClientAsOf before creation = %+v, %v, want no client", client, err)
+	}
+
+	for _, c := range []struct {
+		name     string
+		at       time.Time
+		version  int
+		leads    int
+		metadata string
+		archived bool
+	}{
+		{"created", created, 1, 0, "", false},
+		{"lead assigned", assigned, 1, 1, "", false},
+		{"metadata updated", updated, 2, 1, `{"crm_id":"c-1"}`, false},
+		{"archived", archived, 3, 0, `{"crm_id":"c-1"}`, true},
+	} {
+		client, err := r.ClientAsOf(ctx, clientID, c.at)
+		if err != nil || client == nil {
+			t.Fatalf("ClientAsOf %s = %+v, %v", c.name, client, err)
+		}
+		if client.ID != clientID || client.Name != "a" || client.LeadCapacity != 2 || client.Version != c.version ||
+			client.LeadCount != c.leads || string(memoryMetadata(client.Metadata)) != c.metadata || (client.ArchivedAt != nil) != c.archived {
+			t.Errorf("ClientAsOf %s = %+v, want version %d with %d leads", c.name, client, c.version, c.leads)
+		}
+	}
+
+	if client, err := r.ClientAsOf(ctx, 99, archived); client != nil || err != nil {
+		t.Errorf("ClientAsOf of unknown client = %+v, %v, want no client", client, err)
+	}
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// ImportClients - creates clients of all valid rows in a single transaction. Rows must be already validated:
+// rows with ImportRowInvalid status are never imported, and in ImportAllOrNothing mode they cancel the whole import
+func (s *Storage) ImportClients(ctx context.Context, rows []ImportRow, mode ImportMode) (*ImportReport, error) {
+	if mode != ImportAllOrNothing && mode != ImportSkipInvalid {
+		return nil, invalidInput(CodeInvalidImportMode, "unknown import mode '%s'", mode)
+	}
+
+	report := &ImportReport{
+		Mode:  mode,
+		Total: len(rows),
+		Rows:  rows,
+	}
+
+	for i := range rows {
+		if rows[i].Status != ImportRowInvalid {
+			if err := s.validateMetadata(ctx, s.db, MetadataClient, rows[i].Client.Metadata); err != nil {
+				rows[i].Status = ImportRowInvalid
+				rows[i].Error = err.Error()
+			}
+		}
+
+		if rows[i].Status == ImportRowInvalid {
+			report.Invalid++
+		}
+	}
+
+	if mode == ImportAllOrNothing && report.Invalid > 0 {
+		for i := range rows {
+			if rows[i].Status != ImportRowInvalid {
+				rows[i].Status = ImportRowRejected
+			}
+		}
+
+		return report, nil
+	}
+
+	unlock := s.lockCapacity()
+	defer unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i := range rows {
+		if rows[i].Status == ImportRowInvalid {
+			continue
+		}
+
+		clientID, err := s.insertClient(ctx, tx, rows[i].Client)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", rows[i].Row, err)
+		}
+
+		rows[i].Status = ImportRowCreated
+		rows[i].ClientID = clientID
+		report.Imported++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("can't commit transaction: %w", err)
+	}
+
+	var created []int
+	for _, row := range rows {
+		if row.Status == ImportRowCreated {
+			created = append(created, row.ClientID)
+		}
+	}
+	s.updateCapacity(ctx, created, nil)
+
+	report.Committed = true
+
+	return report, nil
+}
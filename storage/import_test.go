@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// testImportAllOrNothing - one invalid row rejects the whole import
+func testImportAllOrNothing(t *testing.T, r Repository) {
+	rows := importRows(3)
+	rows[1].Status, rows[1].Error = ImportRowInvalid, "bad row"
+
+	report, err := r.ImportClients(context.Background(), rows, ImportAllOrNothing)
+	if err != nil {
+		t.Fatalf("ImportClients: %v", err)
+	}
+
+	if report.Committed || report.Imported != 0 || report.Invalid != 1 || report.Total != 3 {
+		t.Errorf("report = %+v, want nothing imported", report)
+	}
+	for i, status := range []ImportRowStatus{ImportRowRejected, ImportRowInvalid, ImportRowRejected} {
+		if report.Rows[i].Status != status || report.Rows[i].ClientID != 0 {
+			t.Errorf("row %d = %+v, want %s", i+1, report.Rows[i], status)
+		}
+	}
+
+	if clients, err := r.GetClients(context.Background(), nil); err != nil || len(clients) != 0 {
+		t.Errorf("GetClients = %d clients, %v, want none", len(clients), err)
+	}
+}
+
+// testImportSkipInvalid - valid rows are imported, invalid ones and the ones of other metadata are reported
+func testImportSkipInvalid(t *testing.T, r Repository) {
+	schema := json.RawMessage(`{"type": "object", "required": ["crm_id"]}`)
+	if _, err := r.SetMetadataSchema(context.Background(), MetadataClient, schema); err != nil {
+		t.Fatalf("SetMetadataSchema: %v", err)
+	}
+
+	rows := importRows(4)
+	rows[1].Status, rows[1].Error = ImportRowInvalid, "bad row"
+	rows[3].Client.Metadata = Metadata(`{"manager": "ann"}`)
+
+	report, err := r.ImportClients(context.Background(), rows, ImportSkipInvalid)
+	if err != nil {
+		t.Fatalf("ImportClients: %v", err)
+	}
+
+	if !report.Committed || report.Imported != 2 || report.Invalid != 2 || report.Total != 4 {
+		t.Errorf("report = %+v, want 2 rows imported", report)
+	}
+	for i, status := range []ImportRowStatus{ImportRowCreated, ImportRowInvalid, ImportRowCreated, ImportRowInvalid} {
+		if report.Rows[i].Status != status {
+			t.Errorf("row %d = %+v, want %s", i+1, report.Rows[i], status)
+		}
+	}
+	if report.Rows[3].Error == "" {
+		t.Errorf("row 4 = %+v, want the metadata error", report.Rows[3])
+	}
+
+	clients, err := r.GetClients(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("GetClients: %v", err)
+	}
+	if len(clients) != 2 || clients[0].ID != report.Rows[0].ClientID || clients[0].Name != "client_1" ||
+		clients[1].ID != report.Rows[2].ClientID || clients[1].Name != "client_3" {
+		t.Errorf("clients = %+v, want the ones of rows 1 and 3", clients)
+	}
+
+	// The imported clients take leads like any other client
+	if lead := assignLead(t, r, leadRequest()); lead.ClientID != clients[0].ID && lead.ClientID != clients[1].ID {
+		t.Errorf("lead = %+v, want it assigned to an imported client", lead)
+	}
+}
+
+// testImportMode - unknown modes are refused
+func testImportMode(t *testing.T, r Repository) {
+	if _, err := r.ImportClients(context.Background(), importRows(1), "some"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("ImportClients error = %v, want ErrInvalidInput", err)
+	}
+}
+
+// importRows - `n` valid rows of HIGH priority clients of January 2024 with CRM IDs
+func importRows(n int) []ImportRow {
+	rows := make([]ImportRow, n)
+	for i := range rows {
+		rows[i] = ImportRow{
+			Row: i + 1,
+			Client: ClientRequest{
+				Name:         fmt.Sprintf("client_%d", i+1),
+				StartDate:    Timestamp{Time: date("2024-01-01T00:00:00Z")},
+				EndDate:      Timestamp{Time: date("2024-01-31T00:00:00Z")},
+				Priority:     "HIGH",
+				LeadCapacity: 2,
+				Metadata:     Metadata(fmt.Sprintf(`{"crm_id": "crm-%d"}`, i+1)),
+			},
+		}
+	}
+
+	return rows
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const leadsSort = "start_date"
+
+// ListLeads - receives a page of leads matching the filter, ordered by start date
+func (s *Storage) ListLeads(ctx context.Context, f LeadsFilter) (*LeadsPage, error) {
+	query, args, err := s.leadsQuery(f)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := pageLimit(f.Limit)
+
+	query += " LIMIT ?"
+	args = append(args, limit+1) // One extra row tells whether there is a next page
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leads: %w", err)
+	}
+	defer rows.Close()
+
+	leads := []Lead{}
+	for rows.Next() {
+		lead, err := scanLead(rows)
+		if err != nil {
+			return nil, err
+		}
+		leads = append(leads, *lead)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get leads: %w", err)
+	}
+
+	page := &LeadsPage{Meta: PageMeta{Limit: limit}}
+
+	if len(leads) > limit {
+		leads = leads[:limit]
+		last := leads[limit-1]
+
+		page.Meta.HasMore = true
+		page.Meta.NextCursor = encodeCursor(cursor{
+			Sort:  leadsSort,
+			Value: timeArg(last.LeadStart),
+			ID:    last.LeadID,
+		})
+	}
+
+	page.Data = leads
+
+	return page, nil
+}
+
+// leadsQuery - builds a filtered query over leads.sql ordered by start date, starting after the cursor if there is one
+func (s *Storage) leadsQuery(f LeadsFilter) (string, []interface{}, error) {
+	baseQuery := s.queries[queryLeads]
+
+	var conditions []string
+	var args []interface{}
+
+	if f.ClientID != nil {
+		conditions = append(conditions, "l.client_id = ?")
+		args = append(args, *f.ClientID)
+	}
+	if f.Status != "" {
+		conditions = append(conditions, "l.status = ?")
+		args = append(args, f.Status)
+	}
+	if !f.From.IsZero() {
+		conditions = append(conditions, "l.start_date >= ?")
+		args = append(args, timeArg(f.From.Time))
+	}
+	if !f.To.IsZero() {
+		conditions = append(conditions, "l.end_date <= ?")
+		args = append(args, timeArg(f.To.Time))
+	}
+
+	metaConditions, metaArgs, err := metadataConditions(s.db.dialect, "l.metadata", f.Metadata)
+	if err != nil {
+		return "", nil, err
+	}
+	conditions = append(conditions, metaConditions...)
+	args = append(args, metaArgs...)
+
+	if f.Cursor != "" {
+		after, err := decodeCursorFor(f.Cursor, leadsSort)
+		if err != nil {
+			return "", nil, err
+		}
+
+		conditions = append(conditions, "(l.start_date > ? OR (l.start_date = ? AND l.lead_id > ?))")
+		args = append(args, after.Value, after.Value, after.ID)
+	}
+
+	query := baseQuery
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY l.start_date, l.lead_id"
+
+	return query, args, nil
+}
+
+// GetLead - receives a lead by its ID. Returns nil lead when it does not exist
+func (s *Storage) GetLead(ctx context.Context, leadID string) (*Lead, error) {
+	row := s.db.QueryRowContext(ctx, s.queries[queryLeads]+" WHERE l.lead_id = ?", leadID)
+
+	lead, err := scanLead(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return lead, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanLead - scans a row of leads.sql
+func scanLead(row scanner) (*Lead, error) {
+	var lead Lead
+	var clientID sql.NullInt64
+	var metadata sql.NullString
+
+	err := row.Scan(&lead.LeadID, &clientID, &lead.Status, scanTime(&lead.LeadStart), scanTime(&lead.LeadEnd), &metadata)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	// Leads from the pending queue have no client
+	lead.ClientID = int(clientID.Int64)
+	lead.Metadata = metadataValue(metadata)
+
+	return &lead, nil
+}
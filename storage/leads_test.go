@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testListLeads - leads are filtered by client, status and dates and paged through in the order of their start date,
+// ties broken by ID
+func testListLeads(t *testing.T, r Repository) {
+	ctx := context.Background()
+
+	createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 3})
+	createClient(t, r, ClientRequest{Name: "b", Priority: "LOW", LeadCapacity: 3})
+	for _, day := range []int{7, 3, 5, 5} {
+		assignLead(t, r, leadOn(day))
+	}
+	// The leads of the archived client wait in the pending queue
+	archived := createClient(t, r, ClientRequest{Name: "c", Priority: "HIGH", LeadCapacity: 2})
+	for _, day := range []int{11, 9} {
+		assignLead(t, r, leadOn(day))
+	}
+	if _, err := r.ArchiveClient(ctx, archived, LeadsPending); err != nil {
+		t.Fatalf("ArchiveClient: %v", err)
+	}
+
+	all := listLeads(t, r, LeadsFilter{})
+	if len(all) != 6 {
+		t.Fatalf("%d leads listed, want 6", len(all))
+	}
+	if !slices.IsSortedFunc(all, func(a, b Lead) int {
+		return cmp.Or(a.LeadStart.Compare(b.LeadStart), cmp.Compare(a.LeadID, b.LeadID))
+	}) {
+		t.Errorf("leads = %v, want them ordered by start date and ID", leadIDs(all))
+	}
+
+	clientID := 1
+	from, to := leadOn(5).LeadStart, leadOn(9).LeadEnd
+	for _, c := range []struct {
+		name   string
+		filter LeadsFilter
+		match  func(l Lead) bool
+	}{
+		{"client", LeadsFilter{ClientID: &clientID}, func(l Lead) bool { return l.ClientID == 1 }},
+		{"pending", LeadsFilter{Status: LeadStatusPending}, func(l Lead) bool { return l.Status == LeadStatusPending }},
+		{"assigned", LeadsFilter{Status: LeadStatusAssigned}, func(l Lead) bool { return l.Status == LeadStatusAssigned }},
+		{"dates", LeadsFilter{From: from, To: to}, func(l Lead) bool {
+			return !l.LeadStart.Before(from.Time) && !l.LeadEnd.After(to.Time)
+		}},
+	} {
+		want := slices.DeleteFunc(slices.Clone(all), func(l Lead) bool { return !c.match(l) })
+		if len(want) == 0 || len(want) == len(all) {
+			t.Fatalf("%s filter matches %d leads, the test needs some of them", c.name, len(want))
+		}
+
+		if got := listLeads(t, r, c.filter); !slices.Equal(leadIDs(got), leadIDs(want)) {
+			t.Errorf("%s filter = %v, want %v", c.name, leadIDs(got), leadIDs(want))
+		}
+	}
+
+	for _, l := range listLeads(t, r, LeadsFilter{Status: LeadStatusPending}) {
+		if l.ClientID != 0 {
+			t.Errorf("pending lead %s has client %d, want none", l.LeadID, l.ClientID)
+		}
+	}
+
+	var paged []Lead
+	f := LeadsFilter{Limit: 4}
+	for range 3 {
+		page, err := r.ListLeads(ctx, f)
+		if err != nil {
+			t.Fatalf("ListLeads(%+v): %v", f, err)
+		}
+		paged = append(paged, page.Data...)
+
+		if !page.Meta.HasMore {
+			break
+		}
+		f.Cursor = page.Meta.NextCursor
+	}
+	if !slices.Equal(leadIDs(paged), leadIDs(all)) {
+		t.Errorf("pages = %v, want %v", leadIDs(paged), leadIDs(all))
+	}
+
+	clients, err := r.ListClients(ctx, ClientsFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListClients: %v", err)
+	}
+	if _, err := r.ListLeads(ctx, LeadsFilter{Cursor: clients.Meta.NextCursor}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("cursor of clients error = %v, want ErrInvalidInput", err)
+	}
+}
+
+// testGetLead - a lead is received by its ID, unknown IDs give no lead
+func testGetLead(t *testing.T, r Repository) {
+	createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 1})
+	assigned := assignLead(t, r, leadRequest())
+
+	lead, err := r.GetLead(context.Background(), assigned.LeadID)
+	if err != nil {
+		t.Fatalf("GetLead: %v", err)
+	}
+	if lead == nil || lead.ClientID != 1 || lead.Status != LeadStatusAssigned || !lead.LeadStart.Equal(assigned.LeadStart) {
+		t.Errorf("lead = %+v, want %+v", lead, assigned)
+	}
+
+	if lead, err := r.GetLead(context.Background(), "unknown"); lead != nil || err != nil {
+		t.Errorf("GetLead of unknown lead = %+v, %v, want no lead", lead, err)
+	}
+}
+
+// leadOn - a lead on the `day` of January 2024
+func leadOn(day int) AssignLeadRequest {
+	start := time.Date(2024, time.January, day, 9, 0, 0, 0, time.UTC)
+	return AssignLeadRequest{
+		LeadStart: Timestamp{Time: start},
+		LeadEnd:   Timestamp{Time: start.Add(9 * time.Hour)},
+	}
+}
+
+func listLeads(t *testing.T, r Repository, f LeadsFilter) []Lead {
+	t.Helper()
+
+	page, err := r.ListLeads(context.Background(), f)
+	if err != nil {
+		t.Fatalf("ListLeads(%+v): %v", f, err)
+	}
+
+	return page.Data
+}
+
+func leadIDs(leads []Lead) []string {
+	ids := make([]string, 0, len(leads))
+	for _, l := range leads {
+		ids = append(ids, l.LeadID)
+	}
+
+	return ids
+}
@@ -0,0 +1,651 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Memory - storage keeping everything in the process memory, for tests and demo environments.
+// It has the semantics of Storage, every call is atomic like a transaction of the database. Data is lost on restart
+type Memory struct {
+	mu sync.Mutex
+
+	clients      map[int]*clientState
+	leads        map[string]*Lead
+	assigned     map[int]map[string]*Lead // Assigned leads by ID of their client
+	groups       map[int]*ClientGroupRequest
+	schemas      map[schemaKey]MetadataSchema
+	history      map[int][]ClientChange
+	leadHistory  []leadChange
+	lastClientID int
+	lastGroupID  int
+}
+
+// leadChange - owner and status the lead had from the moment `at`
+type leadChange struct {
+	leadID   string
+	clientID int
+	status   LeadStatus
+	at       time.Time
+}
+
+var _ Repository = (*Memory)(nil)
+
+func NewMemory() *Memory {
+	return &Memory{
+		clients:  make(map[int]*clientState),
+		leads:    make(map[string]*Lead),
+		assigned: make(map[int]map[string]*Lead),
+		groups:   make(map[int]*ClientGroupRequest),
+		schemas:  make(map[schemaKey]MetadataSchema),
+		history:  make(map[int][]ClientChange),
+	}
+}
+
+// GetClients - receives a list of clients. Optional parameter `clientID`. When passed, will receive only selected client.
+// Archived clients are never returned
+func (m *Memory) GetClients(ctx context.Context, clientID *int) ([]Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.activeClients(clientID), nil
+}
+
+// CreateClient - creates a new client
+func (m *Memory) CreateClient(ctx context.Context, c ClientRequest) (*Client, error) {
+	if err := m.validateMetadata(ctx, MetadataClient, c.Metadata); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkGroup(c.GroupID); err != nil {
+		return nil, err
+	}
+
+	clientID := m.insertClient(ctx, c)
+	client := m.client(clientID)
+
+	return &client, nil
+}
+
+// AssignLead - selects a suitable client with the same engine as Storage.AssignLead and assigns a new lead to it
+func (m *Memory) AssignLead(ctx context.Context, l AssignLeadRequest) (*Lead, error) {
+	if err := m.validateMetadata(ctx, MetadataLead, l.Metadata); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, err := m.pickClient(l)
+	if err != nil {
+		return nil, err
+	}
+
+	leadID, _ := uuid.NewUUID()
+
+	lead := &Lead{
+		LeadID:    leadID.String(),
+		LeadStart: l.LeadStart.UTC(),
+		LeadEnd:   l.LeadEnd.UTC(),
+		Metadata:  memoryMetadata(l.Metadata),
+	}
+	m.leads[lead.LeadID] = lead
+	m.assignTo(lead, client.ID)
+
+	return copyLead(lead), nil
+}
+
+// ArchiveClient - soft-archives the client and applies `policy` to its leads.
+// Returns nil result when there is no client with such ID and ErrConflict when it is already archived
+func (m *Memory) ArchiveClient(ctx context.Context, clientID int, policy LeadsPolicy) (*ArchiveResult, error) {
+	if policy != LeadsKeep && policy != LeadsReassign && policy != LeadsPending {
+		return nil, invalidInput(CodeInvalidLeadsPolicy, "unknown leads policy '%s'", policy)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.clients[clientID]
+	if !ok {
+		return nil, nil
+	}
+	if state.ArchivedAt != nil {
+		return nil, conflict(CodeClientArchived, "client %d is already archived since %s", clientID, state.ArchivedAt.Format(time.RFC3339))
+	}
+
+	old := copyClientState(state)
+	archivedAt := time.Now().UTC().Truncate(time.Second)
+	state.ArchivedAt = &archivedAt
+	m.recordClientChange(ctx, clientID, ClientArchived, old)
+
+	leads := m.clientLeads(clientID)
+
+	for i := range leads {
+		lead := m.leads[leads[i].LeadID]
+
+		switch policy {
+		case LeadsPending:
+			m.moveToPending(lead)
+		case LeadsReassign:
+			// The client is already archived, so the engine never picks it again
+			client, err := m.pickClient(AssignLeadRequest{LeadStart: Timestamp{Time: lead.LeadStart}, LeadEnd: Timestamp{Time: lead.LeadEnd}})
+			if errors.Is(err, ErrNoClientsAvailable) {
+				m.moveToPending(lead)
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			m.assignTo(lead, client.ID)
+		}
+
+		leads[i] = *copyLead(lead)
+	}
+
+	return &ArchiveResult{
+		ClientID: clientID,
+		Policy:   policy,
+		Leads:    leads,
+	}, nil
+}
+
+// RestoreClient - brings an archived client back. Leads that were reassigned or moved to the pending queue stay where they are.
+// Returns nil client when there is no client with such ID
+func (m *Memory) RestoreClient(ctx context.Context, clientID int) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.clients[clientID]
+	if !ok {
+		return nil, nil
+	}
+
+	if state.ArchivedAt != nil {
+		old := copyClientState(state)
+		state.ArchivedAt = nil
+		m.recordClientChange(ctx, clientID, ClientRestored, old)
+	}
+
+	client := m.client(clientID)
+
+	return &client, nil
+}
+
+// ClientHistory - receives all versions of the client, oldest first. Returns nil when the client has no history
+func (m *Memory) ClientHistory(ctx context.Context, clientID int) ([]ClientChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.history[clientID]), nil
+}
+
+// ClientAsOf - receives the client as it was at the moment `asOf`, including the number of leads assigned to it then.
+// Returns nil client when it did not exist at that moment
+func (m *Memory) ClientAsOf(ctx context.Context, clientID int, asOf time.Time) (*ClientAsOf, error) {
+	asOf = asOf.UTC().Truncate(time.Millisecond) // The moment as precise as the history is
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var change *ClientChange
+	for i, c := range m.history[clientID] {
+		if c.ChangedAt.After(asOf) {
+			break
+		}
+		change = &m.history[clientID][i]
+	}
+	if change == nil {
+		return nil, nil
+	}
+
+	var state clientState
+	if err := json.Unmarshal(change.NewValues, &state); err != nil {
+		return nil, fmt.Errorf("invalid history of client %d: %w", clientID, err)
+	}
+
+	// Latest change of every lead made until the moment
+	latest := make(map[string]leadChange)
+	for _, c := range m.leadHistory {
+		if c.at.After(asOf) {
+			break
+		}
+		latest[c.leadID] = c
+	}
+
+	leadCount := 0
+	for _, c := range latest {
+		if c.clientID == clientID && c.status == LeadStatusAssigned {
+			leadCount++
+		}
+	}
+
+	return &ClientAsOf{
+		ID:           clientID,
+		Name:         state.Name,
+		StartDate:    state.StartDate,
+		EndDate:      state.EndDate,
+		Priority:     state.Priority,
+		LeadCapacity: state.LeadCapacity,
+		GroupID:      state.GroupID,
+		Metadata:     state.Metadata,
+		ArchivedAt:   state.ArchivedAt,
+		LeadCount:    leadCount,
+		Version:      change.Version,
+		AsOf:         asOf,
+	}, nil
+}
+
+// UpdateClientMetadata - replaces metadata of an active client. Returns nil client when it does not exist
+func (m *Memory) UpdateClientMetadata(ctx context.Context, clientID int, metadata Metadata) (*Client, error) {
+	if err := m.validateMetadata(ctx, MetadataClient, metadata); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.clients[clientID]
+	if !ok || state.ArchivedAt != nil {
+		return nil, nil
+	}
+
+	old := copyClientState(state)
+	state.Metadata = memoryMetadata(metadata)
+	m.recordClientChange(ctx, clientID, ClientUpdated, old)
+
+	client := m.client(clientID)
+
+	return &client, nil
+}
+
+// UpdateLeadMetadata - replaces metadata of a lead. Returns nil lead when it does not exist
+func (m *Memory) UpdateLeadMetadata(ctx context.Context, leadID string, metadata Metadata) (*Lead, error) {
+	if err := m.validateMetadata(ctx, MetadataLead, metadata); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lead, ok := m.leads[leadID]
+	if !ok {
+		return nil, nil
+	}
+	lead.Metadata = memoryMetadata(metadata)
+
+	return copyLead(lead), nil
+}
+
+// ImportClients - creates clients of all valid rows at once, see Storage.ImportClients
+func (m *Memory) ImportClients(ctx context.Context, rows []ImportRow, mode ImportMode) (*ImportReport, error) {
+	if mode != ImportAllOrNothing && mode != ImportSkipInvalid {
+		return nil, invalidInput(CodeInvalidImportMode, "unknown import mode '%s'", mode)
+	}
+
+	report := &ImportReport{
+		Mode:  mode,
+		Total: len(rows),
+		Rows:  rows,
+	}
+
+	for i := range rows {
+		if rows[i].Status != ImportRowInvalid {
+			if err := m.validateMetadata(ctx, MetadataClient, rows[i].Client.Metadata); err != nil {
+				rows[i].Status = ImportRowInvalid
+				rows[i].Error = err.Error()
+			}
+		}
+
+		if rows[i].Status == ImportRowInvalid {
+			report.Invalid++
+		}
+	}
+
+	if mode == ImportAllOrNothing && report.Invalid > 0 {
+		for i := range rows {
+			if rows[i].Status != ImportRowInvalid {
+				rows[i].Status = ImportRowRejected
+			}
+		}
+
+		return report, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Nothing is created when a row can't be, like in the rolled back transaction of the database
+	for _, row := range rows {
+		if row.Status == ImportRowInvalid {
+			continue
+		}
+		if err := m.checkGroup(row.Client.GroupID); err != nil {
+			return nil, fmt.Errorf("row %d: %w", row.Row, err)
+		}
+	}
+
+	for i := range rows {
+		if rows[i].Status == ImportRowInvalid {
+			continue
+		}
+
+		rows[i].Status = ImportRowCreated
+		rows[i].ClientID = m.insertClient(ctx, rows[i].Client)
+		report.Imported++
+	}
+
+	report.Committed = true
+
+	return report, nil
+}
+
+// GetLead - receives a lead by its ID. Returns nil lead when it does not exist
+func (m *Memory) GetLead(ctx context.Context, leadID string) (*Lead, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lead, ok := m.leads[leadID]
+	if !ok {
+		return nil, nil
+	}
+
+	return copyLead(lead), nil
+}
+
+// Backup - there is no database file to back up
+func (m *Memory) Backup(ctx context.Context, path string) (*BackupInfo, error) {
+	return nil, unsupported(CodeBackupUnsupported, "data is kept in memory, there is no database to back up")
+}
+
+// CheckCapacityIndex - the clients in memory need no index
+func (m *Memory) CheckCapacityIndex(ctx context.Context) (*CapacityIndexReport, error) {
+	return nil, unsupported(CodeCapacityIndexDisabled, "data is kept in memory, there is no capacity index")
+}
+
+// insertClient -stores the client and records its first version in the history. Returns ID of the client
+func (m *Memory) insertClient(ctx context.Context, c ClientRequest) int {
+	m.lastClientID++
+
+	m.clients[m.lastClientID] = &clientState{
+		Name:         c.Name,
+		StartDate:    c.StartDate.UTC(),
+		EndDate:      c.EndDate.UTC(),
+		Priority:     c.Priority,
+		LeadCapacity: c.LeadCapacity,
+		GroupID:      copyInt(c.GroupID),
+		Metadata:     memoryMetadata(c.Metadata),
+	}
+	m.recordClientChange(ctx, m.lastClientID, ClientCreated, nil)
+
+	return m.lastClientID
+}
+
+// checkGroup - the group a client is put into exists
+func (m *Memory) checkGroup(groupID *int) error {
+	if groupID == nil {
+		return nil
+	}
+	if _, ok := m.groups[*groupID]; !ok {
+		return invalidInput(CodeUnknownGroup, "client group %d does not exist", *groupID)
+	}
+
+	return nil
+}
+
+// pickClient - the assignment engine of Storage.pickClient over the clients in memory.
+// Picked client comes without its leads, the engine needs only their number
+func (m *Memory) pickClient(l AssignLeadRequest) (*Client, error) {
+	// Leads of archived members still take the capacity of the group
+	groupUsed := make(map[int]int)
+	for id, state := range m.clients {
+		if state.GroupID != nil {
+			groupUsed[*state.GroupID] += len(m.assigned[id])
+		}
+	}
+
+	var availableClients []candidate
+
+	for id, state := range m.clients {
+		if state.ArchivedAt != nil {
+			continue
+		}
+
+		used := len(m.assigned[id])
+		if used >= state.LeadCapacity {
+			continue
+		}
+		if state.GroupID != nil && groupUsed[*state.GroupID] >= m.groups[*state.GroupID].LeadCapacity {
+			continue
+		}
+
+		client := m.clientFields(id)
+		if unsuitableTime(client, l) {
+			continue
+		}
+
+		availableClients = append(availableClients, candidate{Client: client, used: used})
+	}
+
+	sort.Slice(availableClients, sortByPriorityAndCapacity(availableClients))
+
+	if len(availableClients) == 0 {
+		return nil, ErrNoClientsAvailable
+	}
+
+	return &availableClients[0].Client, nil
+}
+
+// activeClients - active clients with their leads ordered by ID, or only the client `clientID` when it's passed
+func (m *Memory) activeClients(clientID *int) []Client {
+	var clients []Client
+
+	for _, id := range m.clientIDs() {
+		if clientID != nil && id != *clientID {
+			continue
+		}
+		if m.clients[id].ArchivedAt != nil {
+			continue
+		}
+
+		clients = append(clients, m.client(id))
+	}
+
+	return clients
+}
+
+// client - the client `clientID` with its assigned leads
+func (m *Memory) client(clientID int) Client {
+	client := m.clientFields(clientID)
+	client.Leads = m.clientLeads(clientID)
+
+	return client
+}
+
+// clientFields - the client `clientID` without its leads
+func (m *Memory) clientFields(clientID int) Client {
+	state := m.clients[clientID]
+
+	return Client{
+		ID:           clientID,
+		Name:         state.Name,
+		StartDate:    state.StartDate,
+		EndDate:      state.EndDate,
+		Priority:     state.Priority,
+		LeadCapacity: state.LeadCapacity,
+		GroupID:      copyInt(state.GroupID),
+		Metadata:     state.Metadata,
+	}
+}
+
+// clientLeads - assigned leads of the client ordered by start date
+func (m *Memory) clientLeads(clientID int) []Lead {
+	leads := make([]Lead, 0, len(m.assigned[clientID]))
+	for _, lead := range m.assigned[clientID] {
+		leads = append(leads, *copyLead(lead))
+	}
+	slices.SortFunc(leads, func(a, b Lead) int {
+		return compareLeads(&a, &b)
+	})
+
+	return leads
+}
+
+// clientIDs - IDs of all clients, archived ones included, in ascending order
+func (m *Memory) clientIDs() []int {
+	ids := make([]int, 0, len(m.clients))
+	for id := range m.clients {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+// sortedLeads - all leads ordered by start date and ID, the order of the leads list
+func (m *Memory) sortedLeads() []*Lead {
+	leads := make([]*Lead, 0, len(m.leads))
+	for _, lead := range m.leads {
+		leads = append(leads, lead)
+	}
+
+	slices.SortFunc(leads, compareLeads)
+
+	return leads
+}
+
+// compareLeads - orders leads by start date and ID
+func compareLeads(a, b *Lead) int {
+	if c := a.LeadStart.Compare(b.LeadStart); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a.LeadID, b.LeadID)
+}
+
+// usedCapacity - number of assigned leads per client
+func (m *Memory) usedCapacity() map[int]int {
+	used := make(map[int]int, len(m.assigned))
+	for clientID, leads := range m.assigned {
+		used[clientID] = len(leads)
+	}
+
+	return used
+}
+
+// assignTo - assigns the lead to the client `clientID`, taking it from the client it had
+func (m *Memory) assignTo(lead *Lead, clientID int) {
+	m.unassign(lead)
+
+	lead.ClientID = clientID
+	lead.Status = LeadStatusAssigned
+	if m.assigned[clientID] == nil {
+		m.assigned[clientID] = make(map[string]*Lead)
+	}
+	m.assigned[clientID][lead.LeadID] = lead
+
+	m.recordLeadChange(lead.LeadID, clientID, LeadStatusAssigned)
+}
+
+func (m *Memory) moveToPending(lead *Lead) {
+	m.unassign(lead)
+
+	lead.ClientID = 0
+	lead.Status = LeadStatusPending
+
+	m.recordLeadChange(lead.LeadID, 0, LeadStatusPending)
+}
+
+// unassign - removes the lead from the assigned leads of its client
+func (m *Memory) unassign(lead *Lead) {
+	if lead.Status != LeadStatusAssigned {
+		return
+	}
+
+	delete(m.assigned[lead.ClientID], lead.LeadID)
+	if len(m.assigned[lead.ClientID]) == 0 {
+		delete(m.assigned, lead.ClientID)
+	}
+}
+
+// recordClientChange - saves a new version of the client with its current values. `old` is nil for created clients
+func (m *Memory) recordClientChange(ctx context.Context, clientID int, operation ClientOperation, old *clientState) {
+	// Marshaling of clientState can't fail
+	newValues, _ := json.Marshal(m.clients[clientID])
+
+	var oldValues json.RawMessage
+	if old != nil {
+		oldValues, _ = json.Marshal(old)
+	}
+
+	m.history[clientID] = append(m.history[clientID], ClientChange{
+		Version:   len(m.history[clientID]) + 1,
+		Operation: operation,
+		ChangedAt: historyNow(),
+		ChangedBy: actorFrom(ctx),
+		OldValues: oldValues,
+		NewValues: newValues,
+	})
+}
+
+// recordLeadChange - saves the owner and status the lead has from now on. `clientID` is 0 for leads without a client
+func (m *Memory) recordLeadChange(leadID string, clientID int, status LeadStatus) {
+	m.leadHistory = append(m.leadHistory, leadChange{
+		leadID:   leadID,
+		clientID: clientID,
+		status:   status,
+		at:       historyNow(),
+	})
+}
+
+// historyNow - the current moment as precise as the history is
+func historyNow() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// memoryMetadata - metadata the way the database keeps it: compacted, empty one is nil
+func memoryMetadata(metadata Metadata) Metadata {
+	value, ok := metadataArg(metadata).(string)
+	if !ok {
+		return nil
+	}
+
+	return Metadata(value)
+}
+
+func copyClientState(state *clientState) *clientState {
+	c := *state
+	c.GroupID = copyInt(state.GroupID)
+	if state.ArchivedAt != nil {
+		archivedAt := *state.ArchivedAt
+		c.ArchivedAt = &archivedAt
+	}
+
+	return &c
+}
+
+func copyLead(lead *Lead) *Lead {
+	l := *lead
+	return &l
+}
+
+func copyInt(v *int) *int {
+	if v == nil {
+		return nil
+	}
+
+	i := *v
+	return &i
+}
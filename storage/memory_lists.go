@@ -0,0 +1,618 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ListClients - receives a page of clients matching the filter, see Storage.ListClients
+func (m *Memory) ListClients(ctx context.Context, f ClientsFilter) (*ClientsPage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clients, used, sort, err := m.clientsMatching(f)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := pageLimit(f.Limit)
+	page := &ClientsPage{Meta: PageMeta{Limit: limit}}
+
+	if len(clients) > limit {
+		clients = clients[:limit]
+		last := clients[limit-1]
+
+		page.Meta.HasMore = true
+		page.Meta.NextCursor = encodeCursor(cursor{
+			Sort:  f.Sort,
+			Value: sort.value(last, used[limit-1]),
+			ID:    last.ID,
+		})
+	}
+
+	for i := range clients {
+		clients[i].Leads = m.clientLeads(clients[i].ID)
+	}
+
+	page.Data = clients
+
+	return page, nil
+}
+
+// EachClient - passes every client matching the filter to `fn`, see Storage.EachClient.
+// `fn` must not call the storage: it's locked until EachClient returns
+func (m *Memory) EachClient(ctx context.Context, f ClientsFilter, fn func(c Client, used int) error) error {
+	f.Cursor = ""
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clients, used, _, err := m.clientsMatching(f)
+	if err != nil {
+		return err
+	}
+
+	for i, client := range clients {
+		if err := fn(client, used[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clientsMatching - active clients matching the filter without leads, sorted and starting after the cursor if there is one,
+// with the numbers of their leads
+func (m *Memory) clientsMatching(f ClientsFilter) ([]Client, []int, *sortField, error) {
+	sort, desc, err := clientSort(f.Sort)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := checkMetadataFilter(f.Metadata); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var after *cursor
+	if f.Cursor != "" {
+		if after, err = decodeCursorFor(f.Cursor, f.Sort); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	usedCapacity := m.usedCapacity()
+
+	type match struct {
+		client Client
+		used   int
+		value  any
+	}
+	var matches []match
+
+	for _, id := range m.clientIDs() {
+		state := m.clients[id]
+		if state.ArchivedAt != nil {
+			continue
+		}
+
+		client := Client{
+			ID:           id,
+			Name:         state.Name,
+			StartDate:    state.StartDate,
+			EndDate:      state.EndDate,
+			Priority:     state.Priority,
+			LeadCapacity: state.LeadCapacity,
+			GroupID:      copyInt(state.GroupID),
+			Metadata:     state.Metadata,
+			Leads:        []Lead{},
+		}
+		used := usedCapacity[id]
+
+		if len(f.Priority) > 0 && !slices.Contains(f.Priority, client.Priority) {
+			continue
+		}
+		if !f.ActiveFrom.IsZero() && client.StartDate.After(f.ActiveFrom.Time) {
+			continue
+		}
+		if !f.ActiveTo.IsZero() && client.EndDate.Before(f.ActiveTo.Time) {
+			continue
+		}
+		if f.HasCapacity != nil && *f.HasCapacity != (used < client.LeadCapacity) {
+			continue
+		}
+		// LIKE of SQLite ignores the case
+		if f.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(client.Name), strings.ToLower(f.NamePrefix)) {
+			continue
+		}
+		if !metadataMatches(client.Metadata, f.Metadata) {
+			continue
+		}
+
+		value := sort.value(client, used)
+		if after != nil {
+			order := compareSortValues(value, after.Value)
+			if desc {
+				order = -order
+			}
+			if order < 0 || order == 0 && compareSortValues(id, after.ID) <= 0 {
+				continue
+			}
+		}
+
+		matches = append(matches, match{client: client, used: used, value: value})
+	}
+
+	slices.SortStableFunc(matches, func(a, b match) int {
+		order := compareSortValues(a.value, b.value)
+		if desc {
+			order = -order
+		}
+		if order != 0 {
+			return order
+		}
+
+		return cmp.Compare(a.client.ID, b.client.ID)
+	})
+
+	clients := make([]Client, len(matches))
+	used := make([]int, len(matches))
+	for i, match := range matches {
+		clients[i] = match.client
+		used[i] = match.used
+	}
+
+	return clients, used, &sort, nil
+}
+
+// ListLeads - receives a page of leads matching the filter, ordered by start date
+func (m *Memory) ListLeads(ctx context.Context, f LeadsFilter) (*LeadsPage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	leads, err := m.leadsMatching(f)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := pageLimit(f.Limit)
+	page := &LeadsPage{Meta: PageMeta{Limit: limit}}
+
+	if len(leads) > limit {
+		leads = leads[:limit]
+		last := leads[limit-1]
+
+		page.Meta.HasMore = true
+		page.Meta.NextCursor = encodeCursor(cursor{
+			Sort:  leadsSort,
+			Value: timeArg(last.LeadStart),
+			ID:    last.LeadID,
+		})
+	}
+
+	page.Data = leads
+
+	return page, nil
+}
+
+// EachLead - passes every lead matching the filter to `fn`, see Storage.EachLead.
+// `fn` must not call the storage: it's locked until EachLead returns
+func (m *Memory) EachLead(ctx context.Context, f LeadsFilter, fn func(l Lead) error) error {
+	f.Cursor = ""
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	leads, err := m.leadsMatching(f)
+	if err != nil {
+		return err
+	}
+
+	for _, lead := range leads {
+		if err := fn(lead); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// leadsMatching - leads matching the filter ordered by start date, starting after the cursor if there is one
+func (m *Memory) leadsMatching(f LeadsFilter) ([]Lead, error) {
+	if err := checkMetadataFilter(f.Metadata); err != nil {
+		return nil, err
+	}
+
+	var after *cursor
+	if f.Cursor != "" {
+		var err error
+		if after, err = decodeCursorFor(f.Cursor, leadsSort); err != nil {
+			return nil, err
+		}
+	}
+
+	leads := []Lead{}
+	for _, lead := range m.sortedLeads() {
+		// Leads from the pending queue have no client
+		if f.ClientID != nil && (lead.ClientID == 0 || lead.ClientID != *f.ClientID) {
+			continue
+		}
+		if f.Status != "" && lead.Status != f.Status {
+			continue
+		}
+		if !f.From.IsZero() && lead.LeadStart.Before(f.From.Time) {
+			continue
+		}
+		if !f.To.IsZero() && lead.LeadEnd.After(f.To.Time) {
+			continue
+		}
+		if !metadataMatches(lead.Metadata, f.Metadata) {
+			continue
+		}
+		if after != nil {
+			order := compareSortValues(timeArg(lead.LeadStart), after.Value)
+			if order < 0 || order == 0 && compareSortValues(lead.LeadID, after.ID) <= 0 {
+				continue
+			}
+		}
+
+		leads = append(leads, *copyLead(lead))
+	}
+
+	return leads, nil
+}
+
+// GetGroups - receives a list of client groups with their capacity usage. Optional parameter `groupID`.
+// When passed, will receive only selected group
+func (m *Memory) GetGroups(ctx context.Context, groupID *int) ([]ClientGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.groupList(groupID), nil
+}
+
+// CreateGroup - creates a new client group without members
+func (m *Memory) CreateGroup(ctx context.Context, g ClientGroupRequest) (*ClientGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastGroupID++
+	m.groups[m.lastGroupID] = &g
+
+	return m.group(m.lastGroupID), nil
+}
+
+// UpdateGroup - changes name and capacity of the group. Returns nil group when it does not exist
+func (m *Memory) UpdateGroup(ctx context.Context, groupID int, g ClientGroupRequest) (*ClientGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.groups[groupID]; !ok {
+		return nil, nil
+	}
+	m.groups[groupID] = &g
+
+	return m.group(groupID), nil
+}
+
+// DeleteGroup - deletes the group, its members become standalone clients. Returns false when the group does not exist
+func (m *Memory) DeleteGroup(ctx context.Context, groupID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range m.clientIDs() {
+		state := m.clients[id]
+		if state.GroupID == nil || *state.GroupID != groupID {
+			continue
+		}
+
+		old := copyClientState(state)
+		state.GroupID = nil
+		m.recordClientChange(ctx, id, ClientUpdated, old)
+	}
+
+	if _, ok := m.groups[groupID]; !ok {
+		return false, nil
+	}
+	delete(m.groups, groupID)
+
+	return true, nil
+}
+
+// SetGroupMember - moves an active client into the group (or out of any group when `groupID` is nil).
+// Returns nil group when the group or the client does not exist
+func (m *Memory) SetGroupMember(ctx context.Context, groupID *int, clientID int) (*ClientGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if groupID != nil {
+		if _, ok := m.groups[*groupID]; !ok {
+			return nil, nil
+		}
+	}
+
+	state, ok := m.clients[clientID]
+	if !ok || state.ArchivedAt != nil {
+		return nil, nil
+	}
+
+	old := copyClientState(state)
+	state.GroupID = copyInt(groupID)
+	m.recordClientChange(ctx, clientID, ClientUpdated, old)
+
+	if groupID == nil {
+		return nil, nil
+	}
+
+	return m.group(*groupID), nil
+}
+
+// RemoveGroupMember - moves the client out of the group `groupID`. Returns nil group when the client is not an active
+// member of the group
+func (m *Memory) RemoveGroupMember(ctx context.Context, groupID int, clientID int) (*ClientGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.clients[clientID]
+	if !ok || state.ArchivedAt != nil || state.GroupID == nil || *state.GroupID != groupID {
+		return nil, nil
+	}
+
+	old := copyClientState(state)
+	state.GroupID = nil
+	m.recordClientChange(ctx, clientID, ClientUpdated, old)
+
+	return m.group(groupID), nil
+}
+
+// groupList - all groups ordered by ID, or only the group `groupID` when it's passed
+func (m *Memory) groupList(groupID *int) []ClientGroup {
+	ids := make([]int, 0, len(m.groups))
+	for id := range m.groups {
+		if groupID == nil || id == *groupID {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	var groups []ClientGroup
+	for _, id := range ids {
+		groups = append(groups, *m.group(id))
+	}
+
+	return groups
+}
+
+// group - the group with its active members and the leads of all members, archived ones included
+func (m *Memory) group(groupID int) *ClientGroup {
+	g := m.groups[groupID]
+	group := &ClientGroup{
+		ID:           groupID,
+		Name:         g.Name,
+		LeadCapacity: g.LeadCapacity,
+		Members:      []int{},
+	}
+
+	usedCapacity := m.usedCapacity()
+	for _, id := range m.clientIDs() {
+		state := m.clients[id]
+		if state.GroupID == nil || *state.GroupID != groupID {
+			continue
+		}
+
+		group.UsedCapacity += usedCapacity[id]
+		if state.ArchivedAt == nil {
+			group.Members = append(group.Members, id)
+		}
+	}
+
+	group.AvailableCapacity = max(group.LeadCapacity-group.UsedCapacity, 0)
+
+	return group
+}
+
+// schemaKey - metadata schemas are kept per tenant and entity
+type schemaKey struct {
+	tenant string
+	entity MetadataEntity
+}
+
+// GetMetadataSchema - receives the JSON Schema of the entity metadata of the context's tenant. Returns nil schema
+// when there is none
+func (m *Memory) GetMetadataSchema(ctx context.Context, entity MetadataEntity) (*MetadataSchema, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schema, ok := m.schemas[schemaKey{tenantFrom(ctx), entity}]
+	if !ok {
+		return nil, nil
+	}
+
+	return &schema, nil
+}
+
+// SetMetadataSchema - sets the JSON Schema of the entity metadata of the context's tenant. Metadata saved before is
+// not validated again
+func (m *Memory) SetMetadataSchema(ctx context.Context, entity MetadataEntity, schema json.RawMessage) (*MetadataSchema, error) {
+	if entity != MetadataClient && entity != MetadataLead {
+		return nil, invalidInput(CodeUnknownMetadataEntity, "unknown metadata entity '%s'", entity)
+	}
+
+	if _, err := compileMetadataSchema(entity, schema); err != nil {
+		return nil, err
+	}
+
+	compacted := memoryMetadata(schema)
+	if compacted == nil {
+		return nil, invalidInput(CodeInvalidMetadataSchema, "invalid metadata schema: schema is empty")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The database keeps the update time to the second
+	saved := MetadataSchema{
+		Tenant:    tenantFrom(ctx),
+		Entity:    entity,
+		Schema:    json.RawMessage(compacted),
+		UpdatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	m.schemas[schemaKey{saved.Tenant, entity}] = saved
+
+	return &saved, nil
+}
+
+// DeleteMetadataSchema - removes the JSON Schema of the entity metadata of the context's tenant. Returns false when
+// there was none
+func (m *Memory) DeleteMetadataSchema(ctx context.Context, entity MetadataEntity) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := schemaKey{tenantFrom(ctx), entity}
+	_, ok := m.schemas[key]
+	delete(m.schemas, key)
+
+	return ok, nil
+}
+
+// validateMetadata - checks that metadata is a JSON object matching the schema of the entity of the context's tenant,
+// if there is one
+func (m *Memory) validateMetadata(ctx context.Context, entity MetadataEntity, metadata Metadata) error {
+	value, err := decodeMetadata(entity, metadata)
+	if err != nil || value == nil {
+		return err
+	}
+
+	m.mu.Lock()
+	schema, ok := m.schemas[schemaKey{tenantFrom(ctx), entity}]
+	m.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return checkMetadataSchema(entity, schema.Schema, value)
+}
+
+// ClientsStats - capacity utilization of every active client and totals per priority
+func (m *Memory) ClientsStats(ctx context.Context) (*ClientsStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	stats := newClientsStats(now)
+	usedCapacity := m.usedCapacity()
+
+	for _, id := range m.clientIDs() {
+		state := m.clients[id]
+		if state.ArchivedAt != nil {
+			continue
+		}
+
+		stats.add(ClientStats{
+			ClientID:     id,
+			Name:         state.Name,
+			Priority:     state.Priority,
+			LeadCapacity: state.LeadCapacity,
+			UsedCapacity: usedCapacity[id],
+		}, state.StartDate, state.EndDate, now)
+	}
+
+	return stats, nil
+}
+
+// checkMetadataFilter - keys of the metadata filter are valid paths
+func checkMetadataFilter(filter map[string]string) error {
+	for path := range filter {
+		if !metadataPath.MatchString(path) {
+			return invalidInput(CodeInvalidMetadataKey, "invalid metadata key '%s'", path)
+		}
+	}
+
+	return nil
+}
+
+// metadataMatches - metadata has all values of the filter, compared like metadataConditions does in SQL
+func metadataMatches(metadata Metadata, filter map[string]string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+
+	object, err := decodeMetadata(MetadataClient, metadata)
+	if err != nil || object == nil {
+		return false
+	}
+
+	for path, expected := range filter {
+		var value interface{} = object
+		for _, key := range strings.Split(path, ".") {
+			nested, ok := value.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			value = nested[key]
+		}
+
+		if !slices.ContainsFunc(metadataFilterValues(expected), func(v interface{}) bool {
+			return metadataValueMatches(value, v)
+		}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// metadataValueMatches - decoded JSON value is the typed filter value: strings match strings, numbers match numbers
+// by value and booleans match booleans. Null, objects and arrays match nothing
+func metadataValueMatches(value, expected interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		s, ok := expected.(string)
+		return ok && v == s
+	case json.Number:
+		number, err := v.Float64()
+		expectedNumber, ok := expected.(float64)
+		return err == nil && ok && number == expectedNumber
+	case bool:
+		b, ok := expected.(bool)
+		return ok && v == b
+	}
+
+	return false
+}
+
+// compareSortValues - compares values of a sort field or a cursor like SQLite does: numbers of any type by value,
+// numbers before strings, strings byte by byte
+func compareSortValues(a, b any) int {
+	x, xNumber := sortNumber(a)
+	y, yNumber := sortNumber(b)
+
+	switch {
+	case xNumber && yNumber:
+		return cmp.Compare(x, y)
+	case xNumber:
+		return -1
+	case yNumber:
+		return 1
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func sortNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
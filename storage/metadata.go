@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// DefaultTenant - tenant of the requests that don't name one
+const DefaultTenant = "default"
+
+// metadataPath - dot-separated path of a metadata key, e.g. `crm.id`
+var metadataPath = regexp.MustCompile(`^[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)*$`)
+
+type tenantKey struct{}
+
+// WithTenant - returns a context whose metadata is validated against the schemas of `tenant`
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+func tenantFrom(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok && tenant != "" {
+		return tenant
+	}
+
+	return DefaultTenant
+}
+
+// GetMetadataSchema - receives the JSON Schema of the entity metadata of the context's tenant. Returns nil schema
+// when there is none
+func (s *Storage) GetMetadataSchema(ctx context.Context, entity MetadataEntity) (*MetadataSchema, error) {
+	return s.getMetadataSchema(ctx, s.db, entity)
+}
+
+func (s *Storage) getMetadataSchema(ctx context.Context, q queryer, entity MetadataEntity) (*MetadataSchema, error) {
+	schema := MetadataSchema{Tenant: tenantFrom(ctx), Entity: entity}
+	var raw string
+
+	query := `SELECT schema, updated_at FROM metadata_schemas WHERE tenant = ? AND entity = ?`
+	err := q.QueryRowContext(ctx, query, schema.Tenant, entity).Scan(&raw, scanTime(&schema.UpdatedAt))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata schema: %w", err)
+	}
+
+	schema.Schema = json.RawMessage(raw)
+
+	return &schema, nil
+}
+
+// SetMetadataSchema - sets the JSON Schema of the entity metadata of the context's tenant. Metadata saved before is
+// not validated again
+func (s *Storage) SetMetadataSchema(ctx context.Context, entity MetadataEntity, schema json.RawMessage) (*MetadataSchema, error) {
+	if entity != MetadataClient && entity != MetadataLead {
+		return nil, invalidInput(CodeUnknownMetadataEntity, "unknown metadata entity '%s'", entity)
+	}
+
+	if _, err := compileMetadataSchema(entity, schema); err != nil {
+		return nil, err
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, schema); err != nil {
+		return nil, invalidInput(CodeInvalidMetadataSchema, "invalid metadata schema: %w", err)
+	}
+
+	query := `INSERT INTO metadata_schemas (tenant, entity, schema, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (tenant, entity) DO UPDATE SET schema = excluded.schema, updated_at = excluded.updated_at`
+	_, err := s.db.ExecContext(ctx, query, tenantFrom(ctx), entity, compacted.String(), timeArg(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save metadata schema: %w", err)
+	}
+
+	return s.GetMetadataSchema(ctx, entity)
+}
+
+// DeleteMetadataSchema - removes the JSON Schema of the entity metadata of the context's tenant. Returns false when
+// there was none
+func (s *Storage) DeleteMetadataSchema(ctx context.Context, entity MetadataEntity) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM metadata_schemas WHERE tenant = ? AND entity = ?`, tenantFrom(ctx), entity)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete metadata schema: %w", err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete metadata schema: %w", err)
+	}
+
+	return deleted > 0, nil
+}
+
+// UpdateClientMetadata - replaces metadata of an active client. Returns nil client when it does not exist
+func (s *Storage) UpdateClientMetadata(ctx context.Context, clientID int, metadata Metadata) (*Client, error) {
+	if err := s.validateMetadata(ctx, s.db, MetadataClient, metadata); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	old, err := loadClientState(ctx, tx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE clients SET metadata = ? WHERE id = ? AND archived_at IS NULL`
+	res, err := tx.ExecContext(ctx, query, metadataArg(metadata), clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update client metadata: %w", err)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update client metadata: %w", err)
+	}
+	if updated == 0 {
+		return nil, nil
+	}
+
+	if err := recordClientChange(ctx, tx, clientID, ClientUpdated, old); err != nil {
+		return nil, err
+	}
+
+	clients, err := s.getClients(ctx, tx, &clientID)
+	if err != nil || len(clients) == 0 {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("can't commit transaction: %w", err)
+	}
+
+	return &clients[0], nil
+}
+
+// UpdateLeadMetadata - replaces metadata of a lead. Returns nil lead when it does not exist
+func (s *Storage) UpdateLeadMetadata(ctx context.Context, leadID string, metadata Metadata) (*Lead, error) {
+	if err := s.validateMetadata(ctx, s.db, MetadataLead, metadata); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE leads SET metadata = ? WHERE lead_id = ?`
+	if _, err := s.db.ExecContext(ctx, query, metadataArg(metadata), leadID); err != nil {
+		return nil, fmt.Errorf("failed to update lead metadata: %w", err)
+	}
+
+	return s.GetLead(ctx, leadID)
+}
+
+// validateMetadata - checks that metadata is a JSON object matching the schema of the entity of the context's tenant,
+// if there is one
+func (s *Storage) validateMetadata(ctx context.Context, q queryer, entity MetadataEntity, metadata Metadata) error {
+	value, err := decodeMetadata(entity, metadata)
+	if err != nil || value == nil {
+		return err
+	}
+
+	schema, err := s.getMetadataSchema(ctx, q, entity)
+	if err != nil || schema == nil {
+		return err
+	}
+
+	return checkMetadataSchema(entity, schema.Schema, value)
+}
+
+// decodeMetadata - decodes metadata that must be a JSON object. Returns nil value for empty metadata
+func decodeMetadata(entity MetadataEntity, metadata Metadata) (map[string]interface{}, error) {
+	if metadataArg(metadata) == nil {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(metadata))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, invalidInput(CodeInvalidMetadata, "invalid %s metadata: %w", entity, err)
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, invalidInput(CodeInvalidMetadata, "invalid %s metadata: must be a JSON object", entity)
+	}
+
+	return object, nil
+}
+
+// checkMetadataSchema - checks decoded metadata against the JSON Schema of the entity
+func checkMetadataSchema(entity MetadataEntity, schema json.RawMessage, value map[string]interface{}) error {
+	compiled, err := compileMetadataSchema(entity, schema)
+	if err != nil {
+		return err
+	}
+
+	if err := compiled.Validate(value); err != nil {
+		return invalidInput(CodeInvalidMetadata, "invalid %s metadata: %w", entity, err)
+	}
+
+	return nil
+}
+
+func compileMetadataSchema(entity MetadataEntity, schema json.RawMessage) (*jsonschema.Schema, error) {
+	compiled, err := jsonschema.CompileString("mem://schemas/"+entity+"-metadata.json", string(schema))
+	if err != nil {
+		return nil, invalidInput(CodeInvalidMetadataSchema, "invalid metadata schema: %w", err)
+	}
+
+	return compiled, nil
+}
+
+// metadataConditions - SQL conditions matching metadata values of the `column` with JSON functions of the database.
+// Values match strings, and also numbers or booleans when they look like ones (see metadataFilterValues)
+func metadataConditions(d dialect, column string, filter map[string]string) ([]string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+
+	for path, value := range filter {
+		if !metadataPath.MatchString(path) {
+			return nil, nil, invalidInput(CodeInvalidMetadataKey, "invalid metadata key '%s'", path)
+		}
+
+		condition, conditionArgs := d.metadataCondition(column, path, value)
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+
+	return conditions, args, nil
+}
+
+// metadataFilterValues - values a metadata filter value matches: the value as a string, and also as a number or a
+// boolean when it looks like one. So `00123` finds both the string "00123" and the number 123, `true` both the
+// boolean and the string "true". Values are typed: the string is a string, the number a float64, the boolean a bool,
+// and each of them matches JSON values of its own type only, `1` never finds the boolean true
+func metadataFilterValues(value string) []interface{} {
+	switch value {
+	case "true":
+		return []interface{}{value, true}
+	case "false":
+		return []interface{}{value, false}
+	}
+
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return []interface{}{value, number}
+	}
+
+	return []interface{}{value}
+}
+
+// metadataArg - metadata as a query argument. Empty metadata is stored as NULL
+func metadataArg(metadata Metadata) interface{} {
+	trimmed := bytes.TrimSpace(metadata)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, trimmed); err != nil {
+		return string(trimmed)
+	}
+
+	return compacted.String()
+}
+
+func metadataValue(v sql.NullString) Metadata {
+	if !v.Valid {
+		return nil
+	}
+
+	return Metadata(v.String)
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testMetadataSchemasPerTenant - every tenant has its own schemas, metadata written by a tenant must match its ones
+func testMetadataSchemasPerTenant(t *testing.T, r Repository) {
+	ctx := context.Background()
+	acme := WithTenant(ctx, "acme")
+
+	before := time.Now().Add(-time.Second)
+	schema, err := r.SetMetadataSchema(acme, MetadataClient, json.RawMessage(`{"type": "object", "required": ["crm_id"]}`))
+	if err != nil {
+		t.Fatalf("SetMetadataSchema: %v", err)
+	}
+	if schema.Tenant != "acme" || schema.Entity != MetadataClient || string(schema.Schema) != `{"type":"object","required":["crm_id"]}` {
+		t.Errorf("SetMetadataSchema = %+v, want the compacted client schema of acme", schema)
+	}
+	if schema.UpdatedAt.Before(before) || schema.UpdatedAt.After(time.Now()) || schema.UpdatedAt.Location() != time.UTC {
+		t.Errorf("schema updated at %v, want now in UTC", schema.UpdatedAt)
+	}
+
+	if got, err := r.GetMetadataSchema(acme, MetadataClient); err != nil || got == nil || !got.UpdatedAt.Equal(schema.UpdatedAt) {
+		t.Errorf("GetMetadataSchema = %+v, %v, want %+v", got, err, schema)
+	}
+	for _, c := range []struct {
+		ctx    context.Context
+		entity MetadataEntity
+	}{
+		{ctx, MetadataClient},
+		{WithTenant(ctx, "other"), MetadataClient},
+		{acme, MetadataLead},
+	} {
+		if got, err := r.GetMetadataSchema(c.ctx, c.entity); got != nil || err != nil {
+			t.Errorf("GetMetadataSchema of %s %s = %+v, %v, want none", tenantFrom(c.ctx), c.entity, got, err)
+		}
+	}
+
+	client := ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 2, Metadata: Metadata(`{"manager": "ann"}`)}
+	if _, err := r.CreateClient(acme, client); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("CreateClient of acme error = %v, want ErrInvalidInput", err)
+	}
+	clientID := createClient(t, r, client)
+	if _, err := r.UpdateClientMetadata(acme, clientID, Metadata(`{"manager": "bob"}`)); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("UpdateClientMetadata of acme error = %v, want ErrInvalidInput", err)
+	}
+	updated, err := r.UpdateClientMetadata(acme, clientID, Metadata(`{"crm_id": "c-1"}`))
+	if err != nil || updated == nil || string(updated.Metadata) != `{"crm_id":"c-1"}` {
+		t.Errorf("UpdateClientMetadata of acme = %+v, %v, want the new metadata", updated, err)
+	}
+	if updated, err := r.UpdateClientMetadata(acme, 99, Metadata(`{"crm_id": "c-2"}`)); updated != nil || err != nil {
+		t.Errorf("UpdateClientMetadata of unknown client = %+v, %v, want no client", updated, err)
+	}
+
+	if _, err := r.SetMetadataSchema(ctx, MetadataLead, json.RawMessage(`{"type": "object", "required": ["source"]}`)); err != nil {
+		t.Fatalf("SetMetadataSchema: %v", err)
+	}
+	lead := assignLead(t, r, AssignLeadRequest{
+		LeadStart: leadRequest().LeadStart,
+		LeadEnd:   leadRequest().LeadEnd,
+		Metadata:  Metadata(`{"source": "web"}`),
+	})
+	if _, err := r.UpdateLeadMetadata(ctx, lead.LeadID, Metadata(`{}`)); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("UpdateLeadMetadata error = %v, want ErrInvalidInput", err)
+	}
+	if updated, err := r.UpdateLeadMetadata(acme, lead.LeadID, Metadata(`{}`)); err != nil || updated == nil {
+		t.Errorf("UpdateLeadMetadata of acme = %+v, %v, want the lead", updated, err)
+	}
+
+	if deleted, err := r.DeleteMetadataSchema(acme, MetadataClient); !deleted || err != nil {
+		t.Errorf("DeleteMetadataSchema = %v, %v, want deleted", deleted, err)
+	}
+	if deleted, err := r.DeleteMetadataSchema(acme, MetadataLead); deleted || err != nil {
+		t.Errorf("DeleteMetadataSchema of the default tenant's entity = %v, %v, want not deleted", deleted, err)
+	}
+	if got, err := r.GetMetadataSchema(ctx, MetadataLead); got == nil || err != nil {
+		t.Errorf("GetMetadataSchema of the default tenant = %+v, %v, want the lead schema", got, err)
+	}
+	if _, err := r.CreateClient(acme, client); err != nil {
+		t.Errorf("CreateClient of acme without schema: %v", err)
+	}
+
+	if _, err := r.SetMetadataSchema(ctx, "group", json.RawMessage(`{}`)); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("SetMetadataSchema of unknown entity error = %v, want ErrInvalidInput", err)
+	}
+	if _, err := r.SetMetadataSchema(ctx, MetadataClient, json.RawMessage(`{"type": 1}`)); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("SetMetadataSchema of invalid schema error = %v, want ErrInvalidInput", err)
+	}
+}
+
+// testMetadataFilter - filter values match strings, and numbers or booleans they look like, never values of other types
+func testMetadataFilter(t *testing.T, r Repository) {
+	for _, metadata := range []string{
+		`{"vip": true}`,
+		`{"vip": 1}`,
+		`{"vip": "1"}`,
+		`{"vip": "true"}`,
+		`{"vip": false}`,
+		`{"vip": 0}`,
+		`{"vip": {"level": 1}}`,
+		`{"crm": {"id": "00123"}}`,
+		`{"crm": {"id": 123.0}}`,
+		`{"crm": {"id": "123"}}`,
+		`{"vip": null}`,
+	} {
+		createClient(t, r, ClientRequest{Name: metadata, Priority: "HIGH", LeadCapacity: 1, Metadata: Metadata(metadata)})
+	}
+	createClient(t, r, ClientRequest{Name: "none", Priority: "HIGH", LeadCapacity: 1})
+
+	for _, c := range []struct {
+		key   string
+		value string
+		want  []int
+	}{
+		{"vip", "true", []int{1, 4}},
+		{"vip", "1", []int{2, 3}},
+		{"vip", "false", []int{5}},
+		{"vip", "0", []int{6}},
+		{"vip", "1.0", []int{2}},
+		{"vip", "null", nil},
+		{"vip.level", "1", []int{7}},
+		{"crm.id", "00123", []int{8, 9}},
+		{"crm.id", "123", []int{9, 10}},
+		{"crm.id", "abc", nil},
+	} {
+		page, err := r.ListClients(context.Background(), ClientsFilter{Metadata: map[string]string{c.key: c.value}})
+		if err != nil {
+			t.Fatalf("ListClients: %v", err)
+		}
+		if got := clientIDs(page.Data); !slices.Equal(got, c.want) {
+			t.Errorf("metadata[%s]=%s finds clients %v, want %v", c.key, c.value, got, c.want)
+		}
+	}
+
+	if _, err := r.ListClients(context.Background(), ClientsFilter{Metadata: map[string]string{"a-b": "1"}}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("invalid metadata key error = %v, want ErrInvalidInput", err)
+	}
+
+	for _, metadata := range []string{`{"vip": true}`, `{"vip": 1}`} {
+		assignLead(t, r, AssignLeadRequest{LeadStart: leadRequest().LeadStart, LeadEnd: leadRequest().LeadEnd, Metadata: Metadata(metadata)})
+	}
+	leads := listLeads(t, r, LeadsFilter{Metadata: map[string]string{"vip": "1"}})
+	if len(leads) != 1 || string(leads[0].Metadata) != `{"vip":1}` {
+		t.Errorf("metadata[vip]=1 finds leads %+v, want the one of the number", leads)
+	}
+}
+
+// TestMetadataSchemasMigration - the schemas set before tenants existed belong to the default tenant
+func TestMetadataSchemasMigration(t *testing.T) {
+	ctx := context.Background()
+	const beforeTenants = 20240715000000
+
+	db := openSQLite(t, serverSQLiteParams)
+	s, err := New(db, NewEmbeddedSQL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.MigrateUp(ctx, beforeTenants); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO metadata_schemas (entity, schema, updated_at) VALUES ('client', '{"type":"object"}', '2024-07-01 10:00:00')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.MigrateUp(ctx, LatestVersion); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if err := s.Prepare(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := s.GetMetadataSchema(ctx, MetadataClient)
+	if err != nil || schema == nil {
+		t.Fatalf("GetMetadataSchema = %+v, %v, want the schema", schema, err)
+	}
+	if schema.Tenant != DefaultTenant || !schema.UpdatedAt.Equal(date("2024-07-01T10:00:00Z")) {
+		t.Errorf("schema = %+v, want the one of the default tenant updated at 10:00", schema)
+	}
+
+	// Rolling back keeps the schemas of the default tenant only, in the old format
+	if _, err := s.SetMetadataSchema(WithTenant(ctx, "acme"), MetadataLead, json.RawMessage(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.MigrateDown(ctx, beforeTenants); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	var entities []string
+	rows, err := db.Query(`SELECT entity || ' ' || updated_at FROM metadata_schemas`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entity string
+		if err := rows.Scan(&entity); err != nil {
+			t.Fatal(err)
+		}
+		entities = append(entities, entity)
+	}
+	if !slices.Equal(entities, []string{"client 2024-07-01 10:00:00"}) {
+		t.Errorf("schemas after rollback = %v, want the client one", entities)
+	}
+}
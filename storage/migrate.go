@@ -0,0 +1,448 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// LatestVersion - MigrateUp target applying all migrations
+const LatestVersion int64 = math.MaxInt64
+
+// Directories of the migrations of each database, relative to the repository root
+const (
+	SQLiteMigrationsDir   = "storage/migrations"
+	PostgresMigrationsDir = "storage/postgres/migrations"
+)
+
+// Migration - numbered change of the database schema. It's made of the files <version>_<name>.up.sql and
+// <version>_<name>.down.sql in the migrations directory of the dialect, the latter rolls the change back
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	// Down - empty when the migration can't be rolled back
+	Down string
+	// Checksum - SHA-256 of Up. Applied migrations keep the checksum they were applied with
+	Checksum string
+}
+
+// MigrationStatus - a migration and whether the database has it applied
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	// AppliedAt - nil when the migration is pending
+	AppliedAt *time.Time
+	// Changed - the up file differs from the one that has been applied
+	Changed bool
+	// Missing - the migration has been applied, but its files are gone
+	Missing bool
+}
+
+// appliedMigration - row of schema_migrations
+type appliedMigration struct {
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// retiredMigrations - names of the migrations removed from the tree by version. Databases that applied them forget
+// them, their changes stay. 20240706000000_mocks inserted test clients, which are the demo fixture set now
+var retiredMigrations = map[int64]string{
+	20240706000000: "mocks",
+}
+
+// legacyMigrationFile - name recorded by the migrations table of the databases migrated before versioning
+var legacyMigrationFile = regexp.MustCompile(`^(?:\d+_)?(\w+)\.sql$`)
+
+// Init - creates the tables keeping track of migrations. Databases migrated before versioning have the migrations they
+// ran recorded in the new tables
+func (s *Storage) Init(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, s.queries[queryInitDB]); err != nil {
+		return fmt.Errorf("can't create table: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can't start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockMigrations(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := s.adoptLegacyMigrations(ctx, tx); err != nil {
+		return err
+	}
+
+	for version := range retiredMigrations {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, version); err != nil {
+			return fmt.Errorf("failed to forget migration %d: %w", version, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("can't commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// adoptLegacyMigrations - records the migrations listed by the old `migrations` table as applied, with the checksums
+// of the current files, and drops the table. Its names are matched by the part after the version
+func (s *Storage) adoptLegacyMigrations(ctx context.Context, tx *dialectTx) error {
+	var exists bool
+	if err := tx.QueryRowContext(ctx, s.db.dialect.tableExistsQuery(), "migrations").Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look for the migrations table: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	migrations, err := s.loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]Migration)
+	for _, m := range migrations {
+		byName[m.Name] = m
+	}
+	retired := make(map[string]bool)
+	for _, name := range retiredMigrations {
+		retired[name] = true
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT timestamp FROM migrations`)
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var adopted []Migration
+	for rows.Next() {
+		var file string
+		if err := rows.Scan(&file); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		match := legacyMigrationFile.FindStringSubmatch(file)
+		if match == nil {
+			return fmt.Errorf("unknown applied migration %s", file)
+		}
+		if retired[match[1]] {
+			continue
+		}
+		m, ok := byName[match[1]]
+		if !ok {
+			return fmt.Errorf("unknown applied migration %s", file)
+		}
+		adopted = append(adopted, m)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	rows.Close()
+
+	for _, m := range adopted {
+		if err := recordMigration(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DROP TABLE migrations`); err != nil {
+		return fmt.Errorf("failed to drop the migrations table: %w", err)
+	}
+
+	return nil
+}
+
+// Migrations - applies all pending migrations
+func (s *Storage) Migrations(ctx context.Context) error {
+	_, err := s.MigrateUp(ctx, LatestVersion)
+	return err
+}
+
+// MigrateUp - applies the pending migrations up to the version `target` in version order, each in its own transaction.
+// Returns the migrations it has applied. Fails before applying anything when an applied migration has been changed
+func (s *Storage) MigrateUp(ctx context.Context, target int64) ([]Migration, error) {
+	migrations, applied, err := s.checkedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, m := range migrations {
+		if m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		ran, err := s.runMigration(ctx, m, true)
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			done = append(done, m)
+		}
+	}
+
+	return done, nil
+}
+
+// MigrateDown - rolls back the applied migrations newer than the version `target`, the newest first, each in its own
+// transaction. Target 0 rolls back all of them. Returns the migrations it has rolled back
+func (s *Storage) MigrateDown(ctx context.Context, target int64) ([]Migration, error) {
+	migrations, applied, err := s.checkedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]Migration)
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	var versions []int64
+	for version := range applied {
+		if version > target {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	done := []Migration{}
+	for _, version := range versions {
+		m, ok := byVersion[version]
+		if !ok {
+			return done, fmt.Errorf("can't roll back migration %d_%s: its files are gone", version, applied[version].Name)
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("migration %d_%s can't be rolled back: its down file is missing or empty", m.Version, m.Name)
+		}
+
+		ran, err := s.runMigration(ctx, m, false)
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			done = append(done, m)
+		}
+	}
+
+	return done, nil
+}
+
+// MigrationStatus - all migrations, both known from the files and applied to the database, in version order
+func (s *Storage) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := s.loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []MigrationStatus{}
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			appliedAt := a.AppliedAt
+			status.AppliedAt = &appliedAt
+			status.Changed = a.Checksum != m.Checksum
+			delete(applied, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for version, a := range applied {
+		appliedAt := a.AppliedAt
+		statuses = append(statuses, MigrationStatus{Version: version, Name: a.Name, AppliedAt: &appliedAt, Missing: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// checkedMigrations - the migration files and the applied migrations, when none of the latter has been changed
+func (s *Storage) checkedMigrations(ctx context.Context) ([]Migration, map[int64]appliedMigration, error) {
+	migrations, err := s.loadMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, m := range migrations {
+		if a, ok := applied[m.Version]; ok && a.Checksum != m.Checksum {
+			return nil, nil, fmt.Errorf(
+				"migration %d_%s has been changed after it was applied: checksum %s, applied %s",
+				m.Version, m.Name, m.Checksum, a.Checksum,
+			)
+		}
+	}
+
+	return migrations, applied, nil
+}
+
+// loadMigrations - migrations of the dialect in version order
+func (s *Storage) loadMigrations() ([]Migration, error) {
+	dir := s.db.dialect.migrationsDir()
+	files, err := s.h.ListSQLFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	hasUp := make(map[int64]bool)
+	for _, file := range files {
+		match := migrationFile.FindStringSubmatch(file)
+		if match == nil {
+			return nil, fmt.Errorf("unexpected file %s in %s: migrations are named <version>_<name>.up.sql or <version>_<name>.down.sql", file, dir)
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version of migration %s: %w", file, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrations %d_%s and %d_%s have the same version", version, m.Name, version, match[2])
+		}
+
+		content, err := s.h.ReadSQLFile(dir + "/" + file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SQL file: %w", err)
+		}
+
+		if match[3] == "up" {
+			m.Up = content
+			hasUp[version] = true
+		} else {
+			m.Down = content
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for version, m := range byVersion {
+		if !hasUp[version] {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+func (s *Storage) appliedMigrations(ctx context.Context) (map[int64]appliedMigration, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var version int64
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.Name, &a.Checksum, scanTime(&a.AppliedAt)); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		applied[version] = a
+	}
+
+	return applied, rows.Err()
+}
+
+// runMigration - applies the migration or rolls it back in a transaction. Returns false without running it when another
+// instance has done it in the meantime
+func (s *Storage) runMigration(ctx context.Context, m Migration, up bool) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("can't start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockMigrations(ctx, tx); err != nil {
+		return false, err
+	}
+
+	var applied bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)`, m.Version).Scan(&applied)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migration %d_%s: %w", m.Version, m.Name, err)
+	}
+	if applied == up {
+		return false, nil
+	}
+
+	if up {
+		if _, err := tx.Tx.ExecContext(ctx, m.Up); err != nil {
+			return false, fmt.Errorf("failed to run migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		if err := recordMigration(ctx, tx, m); err != nil {
+			return false, err
+		}
+	} else {
+		if _, err := tx.Tx.ExecContext(ctx, m.Down); err != nil {
+			return false, fmt.Errorf("failed to roll back migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, m.Version); err != nil {
+			return false, fmt.Errorf("failed to forget migration %d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("can't commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// lockMigrations - makes the migration transactions of other instances wait until this one ends. Must come first in
+// the transaction, SQLite can't wait for a lock once the transaction has read something
+func lockMigrations(ctx context.Context, tx *dialectTx) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE schema_migrations_lock SET locked_at = ?`, timeArg(time.Now())); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+
+	return nil
+}
+
+func recordMigration(ctx context.Context, q queryer, m Migration) error {
+	_, err := q.ExecContext(
+		ctx,
+		`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+		m.Version, m.Name, m.Checksum, timeArg(time.Now()),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save migration %d_%s: %w", m.Version, m.Name, err)
+	}
+
+	return nil
+}
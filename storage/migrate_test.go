@@ -0,0 +1,330 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// testMigrationFiles - SQL files of the tree with the migrations of the map instead of the SQLite ones
+type testMigrationFiles struct {
+	EmbeddedSQL
+	files fstest.MapFS
+}
+
+func (m *testMigrationFiles) ReadSQLFile(filepath string) (string, error) {
+	if path.Dir(filepath) != SQLiteMigrationsDir {
+		return m.EmbeddedSQL.ReadSQLFile(filepath)
+	}
+
+	file, ok := m.files[path.Base(filepath)]
+	if !ok {
+		return "", fs.ErrNotExist
+	}
+	return string(file.Data), nil
+}
+
+func (m *testMigrationFiles) ListSQLFiles(dir string) ([]string, error) {
+	if dir != SQLiteMigrationsDir {
+		return m.EmbeddedSQL.ListSQLFiles(dir)
+	}
+
+	return listSQLFiles(m.files, ".")
+}
+
+// testMigrations - accounts, their balance and an audit table, the latter can't be rolled back
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"3_audit.up.sql":      {Data: []byte(`CREATE TABLE audit (account_id INTEGER REFERENCES accounts (id))`)},
+		"2_balance.down.sql":  {Data: []byte(`ALTER TABLE accounts DROP COLUMN balance`)},
+		"2_balance.up.sql":    {Data: []byte(`ALTER TABLE accounts ADD COLUMN balance INTEGER`)},
+		"1_accounts.down.sql": {Data: []byte(`DROP TABLE accounts`)},
+		"1_accounts.up.sql":   {Data: []byte(`CREATE TABLE accounts (id INTEGER PRIMARY KEY)`)},
+	}
+}
+
+// migrationsStorage - storage of the database `db` with the migrations `files`, its migration tables created
+func migrationsStorage(t *testing.T, db *sql.DB, files fstest.MapFS) *Storage {
+	t.Helper()
+
+	s, err := New(db, &testMigrationFiles{files: files})
+	if err != nil {
+		t.Fatalf("can't connect to storage: %v", err)
+	}
+	if err := s.Init(context.Background()); err != nil {
+		t.Fatalf("can't init storage: %v", err)
+	}
+
+	return s
+}
+
+func migrationVersions(migrations []Migration) []int64 {
+	versions := []int64{}
+	for _, m := range migrations {
+		versions = append(versions, m.Version)
+	}
+
+	return versions
+}
+
+// appliedVersions - versions recorded in schema_migrations, in version order
+func appliedVersions(t *testing.T, s *Storage) []int64 {
+	t.Helper()
+
+	statuses, err := s.MigrationStatus(context.Background())
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+
+	versions := []int64{}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			versions = append(versions, status.Version)
+		}
+	}
+
+	return versions
+}
+
+func TestMigrateUpDown(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t, serverSQLiteParams)
+	s := migrationsStorage(t, db, testMigrations())
+
+	// The balance can only be added once the accounts exist, so the migrations must run in version order
+	done, err := s.MigrateUp(ctx, 2)
+	if err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if got := migrationVersions(done); !slices.Equal(got, []int64{1, 2}) {
+		t.Errorf("MigrateUp to 2 applied %v, want 1 and 2", got)
+	}
+	if _, err := db.Exec(`INSERT INTO accounts (id, balance) VALUES (1, 10)`); err != nil {
+		t.Errorf("accounts have no balance: %v", err)
+	}
+
+	statuses, err := s.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if len(statuses) != 3 || statuses[0].Name != "accounts" || statuses[2].Name != "audit" || statuses[2].AppliedAt != nil {
+		t.Errorf("statuses = %+v, want the audit one pending", statuses)
+	}
+
+	done, err = s.MigrateUp(ctx, LatestVersion)
+	if err != nil || !slices.Equal(migrationVersions(done), []int64{3}) {
+		t.Errorf("MigrateUp = %v, %v, want 3 applied", migrationVersions(done), err)
+	}
+	if done, err := s.MigrateUp(ctx, LatestVersion); err != nil || len(done) != 0 {
+		t.Errorf("MigrateUp again = %v, %v, want nothing applied", migrationVersions(done), err)
+	}
+
+	if _, err := s.MigrateDown(ctx, 1); err == nil || !strings.Contains(err.Error(), "3_audit can't be rolled back") {
+		t.Errorf("MigrateDown error = %v, want the missing down file", err)
+	}
+	if _, err := db.Exec(`DROP TABLE audit`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DELETE FROM schema_migrations WHERE version = 3`); err != nil {
+		t.Fatal(err)
+	}
+
+	done, err = s.MigrateDown(ctx, 0)
+	if err != nil || !slices.Equal(migrationVersions(done), []int64{2, 1}) {
+		t.Errorf("MigrateDown = %v, %v, want 2 then 1 rolled back", migrationVersions(done), err)
+	}
+	if got := appliedVersions(t, s); len(got) != 0 {
+		t.Errorf("applied migrations = %v, want none", got)
+	}
+	if _, err := db.Exec(`SELECT 1 FROM accounts`); err == nil {
+		t.Error("accounts table exists after rolling everything back")
+	}
+}
+
+func TestMigrateChangedMigration(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t, serverSQLiteParams)
+	files := testMigrations()
+	delete(files, "3_audit.up.sql")
+	if _, err := migrationsStorage(t, db, files).MigrateUp(ctx, LatestVersion); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	// The applied balance migration is edited while the audit one is added
+	files = testMigrations()
+	files["2_balance.up.sql"] = &fstest.MapFile{Data: []byte(`ALTER TABLE accounts ADD COLUMN balance TEXT`)}
+	s := migrationsStorage(t, db, files)
+
+	if _, err := s.MigrateUp(ctx, LatestVersion); err == nil || !strings.Contains(err.Error(), "2_balance has been changed") {
+		t.Errorf("MigrateUp error = %v, want the changed migration refused", err)
+	}
+	if _, err := s.MigrateDown(ctx, 0); err == nil || !strings.Contains(err.Error(), "2_balance has been changed") {
+		t.Errorf("MigrateDown error = %v, want the changed migration refused", err)
+	}
+	if got := appliedVersions(t, s); !slices.Equal(got, []int64{1, 2}) {
+		t.Errorf("applied migrations = %v, want 1 and 2 untouched", got)
+	}
+
+	statuses, err := s.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	for _, status := range statuses {
+		if status.Changed != (status.Version == 2) {
+			t.Errorf("status = %+v, want only the balance one changed", status)
+		}
+	}
+}
+
+// TestMigrationsLock - an instance waits for the migration transaction of another one, then skips the migrations it
+// has applied
+func TestMigrationsLock(t *testing.T) {
+	for name, params := range map[string]string{
+		"server settings": serverSQLiteParams,
+		// Deferred transactions take no lock when they start, updating the lock table first makes them wait before reading
+		"deferred transactions": "_journal_mode=WAL&_busy_timeout=5000",
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			open := func() *sql.DB {
+				db, err := sql.Open("sqlite3", dir+"/leads.db?"+params)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { db.Close() })
+				return db
+			}
+			first := migrationsStorage(t, open(), testMigrations())
+			second := migrationsStorage(t, open(), testMigrations())
+
+			tx, err := first.db.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+			if err := lockMigrations(ctx, tx); err != nil {
+				t.Fatal(err)
+			}
+			migrations, err := first.loadMigrations()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tx.ExecContext(ctx, migrations[0].Up); err != nil {
+				t.Fatal(err)
+			}
+			if err := recordMigration(ctx, tx, migrations[0]); err != nil {
+				t.Fatal(err)
+			}
+
+			type result struct {
+				done []Migration
+				err  error
+			}
+			migrated := make(chan result)
+			go func() {
+				done, err := second.MigrateUp(ctx, LatestVersion)
+				migrated <- result{done, err}
+			}()
+
+			select {
+			case r := <-migrated:
+				t.Fatalf("MigrateUp = %v, %v while another instance was migrating", migrationVersions(r.done), r.err)
+			case <-time.After(200 * time.Millisecond):
+			}
+
+			if err := tx.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			if r := <-migrated; r.err != nil || !slices.Equal(migrationVersions(r.done), []int64{2, 3}) {
+				t.Errorf("MigrateUp = %v, %v, want the migrations after the one of the other instance", migrationVersions(r.done), r.err)
+			}
+		})
+	}
+}
+
+// TestAdoptLegacyMigrations - the migrations listed by the table of the databases migrated before versioning are
+// recorded as applied, with the current checksums
+func TestAdoptLegacyMigrations(t *testing.T) {
+	ctx := context.Background()
+	legacy := func(t *testing.T, names ...string) *sql.DB {
+		db := openSQLite(t, serverSQLiteParams)
+		if _, err := db.Exec(`CREATE TABLE migrations (timestamp TEXT); CREATE TABLE accounts (id INTEGER PRIMARY KEY)`); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if _, err := db.Exec(`INSERT INTO migrations (timestamp) VALUES (?)`, name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return db
+	}
+	migrationsTable := func(t *testing.T, db *sql.DB) bool {
+		var exists bool
+		if err := db.QueryRow(sqliteDialect{}.tableExistsQuery(), "migrations").Scan(&exists); err != nil {
+			t.Fatal(err)
+		}
+		return exists
+	}
+
+	t.Run("adopted", func(t *testing.T) {
+		db := legacy(t, "accounts.sql", "1720224000_mocks.sql")
+		if _, err := db.Exec(sqliteInitDB(t)); err != nil {
+			t.Fatal(err)
+		}
+		// A database that applied the retired migration with versioning forgets it too
+		_, err := db.Exec(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (20240706000000, 'mocks', '', '')`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s := migrationsStorage(t, db, testMigrations())
+
+		if migrationsTable(t, db) {
+			t.Error("the migrations table is kept")
+		}
+		statuses, err := s.MigrationStatus(ctx)
+		if err != nil {
+			t.Fatalf("MigrationStatus: %v", err)
+		}
+		if len(statuses) != 3 || statuses[0].AppliedAt == nil || statuses[0].Changed || statuses[1].AppliedAt != nil {
+			t.Errorf("statuses = %+v, want only the accounts one applied", statuses)
+		}
+		if done, err := s.MigrateUp(ctx, LatestVersion); err != nil || !slices.Equal(migrationVersions(done), []int64{2, 3}) {
+			t.Errorf("MigrateUp = %v, %v, want the other migrations applied", migrationVersions(done), err)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		db := legacy(t, "accounts.sql", "payments.sql")
+		s, err := New(db, &testMigrationFiles{files: testMigrations()})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Init(ctx); err == nil || !strings.Contains(err.Error(), "unknown applied migration payments.sql") {
+			t.Errorf("Init error = %v, want the unknown migration", err)
+		}
+		if !migrationsTable(t, db) || len(appliedVersions(t, s)) != 0 {
+			t.Error("a failed adoption changed the database")
+		}
+	})
+}
+
+// sqliteInitDB - query creating the migration tables of SQLite
+func sqliteInitDB(t *testing.T) string {
+	t.Helper()
+
+	query, err := NewEmbeddedSQL().ReadSQLFile("storage/queries/init_db.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return query
+}
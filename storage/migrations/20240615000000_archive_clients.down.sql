@@ -0,0 +1,17 @@
+-- Leads in the pending queue have no client and can't be kept. Archived clients become active again
+CREATE TABLE leads_old (
+    lead_id TEXT NOT NULL PRIMARY KEY,
+    client_id INTEGER NOT NULL,
+    start_date TEXT,
+    end_date TEXT,
+    FOREIGN KEY (client_id) REFERENCES clients(id)
+);
+
+INSERT INTO leads_old (lead_id, client_id, start_date, end_date)
+SELECT lead_id, client_id, start_date, end_date FROM leads WHERE client_id IS NOT NULL;
+
+DROP TABLE leads;
+
+ALTER TABLE leads_old RENAME TO leads;
+
+ALTER TABLE clients DROP COLUMN archived_at;
@@ -0,0 +1,18 @@
+ALTER TABLE clients ADD COLUMN archived_at TEXT;
+
+-- Leads moved to the pending queue have no client, so client_id becomes nullable
+CREATE TABLE leads_new (
+    lead_id TEXT NOT NULL PRIMARY KEY,
+    client_id INTEGER,
+    status TEXT CHECK(status IN ('ASSIGNED', 'PENDING')) NOT NULL DEFAULT 'ASSIGNED',
+    start_date TEXT,
+    end_date TEXT,
+    FOREIGN KEY (client_id) REFERENCES clients(id)
+);
+
+INSERT INTO leads_new (lead_id, client_id, status, start_date, end_date)
+SELECT lead_id, client_id, 'ASSIGNED', start_date, end_date FROM leads;
+
+DROP TABLE leads;
+
+ALTER TABLE leads_new RENAME TO leads;
@@ -0,0 +1,3 @@
+ALTER TABLE clients DROP COLUMN group_id;
+
+DROP TABLE IF EXISTS client_groups;
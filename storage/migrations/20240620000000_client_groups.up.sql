@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS client_groups (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    lead_capacity INTEGER NOT NULL
+);
+
+ALTER TABLE clients ADD COLUMN group_id INTEGER REFERENCES client_groups(id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS metadata_schemas;
+
+ALTER TABLE leads DROP COLUMN metadata;
+
+ALTER TABLE clients DROP COLUMN metadata;
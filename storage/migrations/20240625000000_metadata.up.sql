@@ -0,0 +1,10 @@
+ALTER TABLE clients ADD COLUMN metadata TEXT CHECK(metadata IS NULL OR json_type(metadata) = 'object');
+
+ALTER TABLE leads ADD COLUMN metadata TEXT CHECK(metadata IS NULL OR json_type(metadata) = 'object');
+
+-- Optional JSON Schema the metadata of an entity must match
+CREATE TABLE IF NOT EXISTS metadata_schemas (
+    entity TEXT NOT NULL PRIMARY KEY CHECK(entity IN ('client', 'lead')),
+    schema TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS lead_history;
+
+DROP TABLE IF EXISTS client_history;
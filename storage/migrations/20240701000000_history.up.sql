@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS client_history (
+    id INTEGER PRIMARY KEY,
+    client_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    operation TEXT NOT NULL,
+    changed_at TEXT NOT NULL,
+    changed_by TEXT NOT NULL,
+    old_values TEXT,
+    new_values TEXT NOT NULL,
+    UNIQUE (client_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS client_history_changed_at ON client_history (client_id, changed_at);
+
+CREATE TABLE IF NOT EXISTS lead_history (
+    id INTEGER PRIMARY KEY,
+    lead_id TEXT NOT NULL,
+    client_id INTEGER,
+    status TEXT NOT NULL,
+    changed_at TEXT NOT NULL,
+    changed_by TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS lead_history_changed_at ON lead_history (changed_at, lead_id);
+
+-- Rows that existed before the history was kept are recorded as of the migration time
+INSERT INTO client_history (client_id, version, operation, changed_at, changed_by, old_values, new_values)
+SELECT
+    id,
+    1,
+    'snapshot',
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    'migration',
+    NULL,
+    json_object(
+        'name', name,
+        'start_date', start_date,
+        'end_date', end_date,
+        'priority', priority,
+        'lead_capacity', lead_capacity,
+        'group_id', group_id,
+        'metadata', json(metadata),
+        'archived_at', archived_at
+    )
+FROM clients;
+
+INSERT INTO lead_history (lead_id, client_id, status, changed_at, changed_by)
+SELECT lead_id, client_id, status, strftime('%Y-%m-%d %H:%M:%f', 'now'), 'migration'
+FROM leads;
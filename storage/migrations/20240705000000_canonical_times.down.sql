@@ -0,0 +1,27 @@
+-- Back to UTC in the `YYYY-MM-DD HH:MM:SS` format, history timestamps with milliseconds
+UPDATE clients SET
+    start_date = COALESCE(strftime('%Y-%m-%d %H:%M:%S', start_date), start_date),
+    end_date = COALESCE(strftime('%Y-%m-%d %H:%M:%S', end_date), end_date),
+    archived_at = COALESCE(strftime('%Y-%m-%d %H:%M:%S', archived_at), archived_at);
+
+UPDATE leads SET
+    start_date = COALESCE(strftime('%Y-%m-%d %H:%M:%S', start_date), start_date),
+    end_date = COALESCE(strftime('%Y-%m-%d %H:%M:%S', end_date), end_date);
+
+UPDATE client_history SET
+    changed_at = COALESCE(strftime('%Y-%m-%d %H:%M:%f', changed_at), changed_at),
+    new_values = json_set(
+        new_values,
+        '$.start_date', COALESCE(strftime('%Y-%m-%d %H:%M:%S', json_extract(new_values, '$.start_date')), json_extract(new_values, '$.start_date')),
+        '$.end_date', COALESCE(strftime('%Y-%m-%d %H:%M:%S', json_extract(new_values, '$.end_date')), json_extract(new_values, '$.end_date')),
+        '$.archived_at', COALESCE(strftime('%Y-%m-%d %H:%M:%S', json_extract(new_values, '$.archived_at')), json_extract(new_values, '$.archived_at'))
+    ),
+    old_values = CASE WHEN old_values IS NULL THEN NULL ELSE json_set(
+        old_values,
+        '$.start_date', COALESCE(strftime('%Y-%m-%d %H:%M:%S', json_extract(old_values, '$.start_date')), json_extract(old_values, '$.start_date')),
+        '$.end_date', COALESCE(strftime('%Y-%m-%d %H:%M:%S', json_extract(old_values, '$.end_date')), json_extract(old_values, '$.end_date')),
+        '$.archived_at', COALESCE(strftime('%Y-%m-%d %H:%M:%S', json_extract(old_values, '$.archived_at')), json_extract(old_values, '$.archived_at'))
+    ) END;
+
+UPDATE lead_history SET
+    changed_at = COALESCE(strftime('%Y-%m-%d %H:%M:%f', changed_at), changed_at);
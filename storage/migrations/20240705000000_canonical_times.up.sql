@@ -0,0 +1,28 @@
+-- Dates are stored as RFC 3339 in UTC with a fixed width (2024-07-05T00:00:00Z), history timestamps with milliseconds.
+-- Values written before were UTC in the `YYYY-MM-DD HH:MM:SS` format. Values SQLite can't parse are kept as they are
+UPDATE clients SET
+    start_date = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', start_date), start_date),
+    end_date = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', end_date), end_date),
+    archived_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', archived_at), archived_at);
+
+UPDATE leads SET
+    start_date = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', start_date), start_date),
+    end_date = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', end_date), end_date);
+
+UPDATE client_history SET
+    changed_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%fZ', changed_at), changed_at),
+    new_values = json_set(
+        new_values,
+        '$.start_date', COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', json_extract(new_values, '$.start_date')), json_extract(new_values, '$.start_date')),
+        '$.end_date', COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', json_extract(new_values, '$.end_date')), json_extract(new_values, '$.end_date')),
+        '$.archived_at', COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', json_extract(new_values, '$.archived_at')), json_extract(new_values, '$.archived_at'))
+    ),
+    old_values = CASE WHEN old_values IS NULL THEN NULL ELSE json_set(
+        old_values,
+        '$.start_date', COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', json_extract(old_values, '$.start_date')), json_extract(old_values, '$.start_date')),
+        '$.end_date', COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', json_extract(old_values, '$.end_date')), json_extract(old_values, '$.end_date')),
+        '$.archived_at', COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', json_extract(old_values, '$.archived_at')), json_extract(old_values, '$.archived_at'))
+    ) END;
+
+UPDATE lead_history SET
+    changed_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%fZ', changed_at), changed_at);
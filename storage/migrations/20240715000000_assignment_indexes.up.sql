@@ -0,0 +1,6 @@
+-- The assignment engine counts the assigned leads of each client and of each group, and looks for clients whose time
+-- frame contains the lead. Leads are listed by start date
+CREATE INDEX IF NOT EXISTS leads_client_status ON leads (client_id, status);
+CREATE INDEX IF NOT EXISTS leads_start_date ON leads (start_date, lead_id);
+CREATE INDEX IF NOT EXISTS clients_time_frame ON clients (start_date, end_date);
+CREATE INDEX IF NOT EXISTS clients_group ON clients (group_id);
@@ -0,0 +1,14 @@
+-- Only the schemas of the default tenant are kept
+CREATE TABLE metadata_schemas_by_entity (
+    entity TEXT NOT NULL PRIMARY KEY CHECK(entity IN ('client', 'lead')),
+    schema TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+INSERT INTO metadata_schemas_by_entity (entity, schema, updated_at)
+SELECT entity, schema, COALESCE(strftime('%Y-%m-%d %H:%M:%S', updated_at), updated_at) FROM metadata_schemas
+WHERE tenant = 'default';
+
+DROP TABLE metadata_schemas;
+
+ALTER TABLE metadata_schemas_by_entity RENAME TO metadata_schemas;
@@ -0,0 +1,16 @@
+-- Every tenant has its own metadata schemas. The schemas set before belong to the default tenant, their update times
+-- move from the `YYYY-MM-DD HH:MM:SS` format to the canonical RFC 3339 one
+CREATE TABLE metadata_schemas_by_tenant (
+    tenant TEXT NOT NULL,
+    entity TEXT NOT NULL CHECK(entity IN ('client', 'lead')),
+    schema TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (tenant, entity)
+);
+
+INSERT INTO metadata_schemas_by_tenant (tenant, entity, schema, updated_at)
+SELECT 'default', entity, schema, COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', updated_at), updated_at) FROM metadata_schemas;
+
+DROP TABLE metadata_schemas;
+
+ALTER TABLE metadata_schemas_by_tenant RENAME TO metadata_schemas;
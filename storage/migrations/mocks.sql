@@ -1,5 +0,0 @@
-INSERT INTO `clients` (id, name, start_date, end_date, priority, lead_capacity)
-VALUES  (1, "client_1", "2024-04-10 00:00:00", "2023-07-10 00:00:00", "MEDIUM", 10),
-        (2, "client_2", "2024-05-10 00:00:00", "2023-08-10 00:00:00", "LOW", 5),
-        (3, "client_3", "2024-06-10 00:00:00", "2023-09-10 00:00:00", "HIGH", 2),
-        (4, "client_4", "2024-07-10 00:00:00", "2023-10-10 00:00:00", "HIGH", 4);
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq" // Needs for PostgreSQL start
+)
+
+// NewPostgres - storage over a PostgreSQL database. Its schema lives in storage/postgres/migrations,
+// queries SQLite and PostgreSQL can't share in storage/postgres/queries
+func NewPostgres(db DB, helpers SQLHelpersReader) (*Storage, error) {
+	return newStorage(db, helpers, postgresDialect{})
+}
+
+type postgresDialect struct{}
+
+// writeLockKey - key of the advisory lock taken by transactions that change data
+const writeLockKey = 7_125_311
+
+// rebind - replaces `?` outside of string literals with $1, $2...
+func (postgresDialect) rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	quoted := false
+
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func (postgresDialect) queryFile(name string) string {
+	return "storage/postgres/queries/" + name
+}
+
+func (postgresDialect) migrationsDir() string {
+	return PostgresMigrationsDir
+}
+
+// metadataCondition - jsonb_typeof keeps strings, numbers and booleans apart, jsonb equality compares numbers by value
+func (postgresDialect) metadataCondition(column, path, value string) (string, []interface{}) {
+	keys := "{" + strings.ReplaceAll(path, ".", ",") + "}"
+	extract := fmt.Sprintf("(%s::jsonb #> ?::text[])", column)
+
+	var matches []string
+	var args []interface{}
+	for _, expected := range metadataFilterValues(value) {
+		jsonType := "string"
+		switch expected.(type) {
+		case bool:
+			jsonType = "boolean"
+		case float64:
+			jsonType = "number"
+		}
+		raw, _ := json.Marshal(expected)
+
+		matches = append(matches, fmt.Sprintf("(jsonb_typeof(%[1]s) = '%[2]s' AND %[1]s = ?::jsonb)", extract, jsonType))
+		args = append(args, keys, keys, string(raw))
+	}
+
+	return "(" + strings.Join(matches, " OR ") + ")", args
+}
+
+func (postgresDialect) prefixCondition(column string) string {
+	return column + ` ILIKE ? ESCAPE '\'`
+}
+
+func (postgresDialect) tableExistsQuery() string {
+	return `SELECT to_regclass(?) IS NOT NULL`
+}
+
+func (postgresDialect) lockWrites(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, writeLockKey); err != nil {
+		return fmt.Errorf("can't lock database for writing: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS lead_history;
+
+DROP TABLE IF EXISTS client_history;
+
+DROP TABLE IF EXISTS metadata_schemas;
+
+DROP TABLE IF EXISTS leads;
+
+DROP TABLE IF EXISTS clients;
+
+DROP TABLE IF EXISTS client_groups;
@@ -0,0 +1,59 @@
+-- Schema the SQLite migrations up to 20240705000000_canonical_times have built
+CREATE TABLE IF NOT EXISTS client_groups (
+    id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL,
+    lead_capacity INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS clients (
+    id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name TEXT,
+    start_date TIMESTAMPTZ,
+    end_date TIMESTAMPTZ,
+    priority TEXT CHECK(priority IN ('LOW', 'MEDIUM', 'HIGH')) DEFAULT 'MEDIUM',
+    lead_capacity INTEGER,
+    archived_at TIMESTAMPTZ,
+    group_id INTEGER REFERENCES client_groups(id),
+    metadata JSON CHECK(metadata IS NULL OR json_typeof(metadata) = 'object')
+);
+
+CREATE TABLE IF NOT EXISTS leads (
+    lead_id TEXT NOT NULL PRIMARY KEY,
+    client_id INTEGER REFERENCES clients(id),
+    status TEXT CHECK(status IN ('ASSIGNED', 'PENDING')) NOT NULL DEFAULT 'ASSIGNED',
+    start_date TIMESTAMPTZ,
+    end_date TIMESTAMPTZ,
+    metadata JSON CHECK(metadata IS NULL OR json_typeof(metadata) = 'object')
+);
+
+-- Optional JSON Schema the metadata of an entity must match
+CREATE TABLE IF NOT EXISTS metadata_schemas (
+    entity TEXT NOT NULL PRIMARY KEY CHECK(entity IN ('client', 'lead')),
+    schema TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS client_history (
+    id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    client_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    operation TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL,
+    changed_by TEXT NOT NULL,
+    old_values JSON,
+    new_values JSON NOT NULL,
+    UNIQUE (client_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS client_history_changed_at ON client_history (client_id, changed_at);
+
+CREATE TABLE IF NOT EXISTS lead_history (
+    id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    lead_id TEXT NOT NULL,
+    client_id INTEGER,
+    status TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL,
+    changed_by TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS lead_history_changed_at ON lead_history (changed_at, lead_id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS clients_group;
+
+DROP INDEX IF EXISTS clients_time_frame;
+
+DROP INDEX IF EXISTS leads_start_date;
+
+DROP INDEX IF EXISTS leads_client_status;
@@ -0,0 +1,9 @@
+-- Only the schemas of the default tenant are kept
+DELETE FROM metadata_schemas WHERE tenant <> 'default';
+
+ALTER TABLE metadata_schemas ALTER COLUMN updated_at TYPE TEXT USING to_char(updated_at AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS');
+
+ALTER TABLE metadata_schemas DROP CONSTRAINT metadata_schemas_pkey;
+ALTER TABLE metadata_schemas ADD PRIMARY KEY (entity);
+
+ALTER TABLE metadata_schemas DROP COLUMN tenant;
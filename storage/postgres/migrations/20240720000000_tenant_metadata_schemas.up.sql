@@ -0,0 +1,9 @@
+-- Every tenant has its own metadata schemas. The schemas set before belong to the default tenant, their update times
+-- were UTC in the `YYYY-MM-DD HH:MM:SS` format
+ALTER TABLE metadata_schemas ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE metadata_schemas ALTER COLUMN tenant DROP DEFAULT;
+
+ALTER TABLE metadata_schemas DROP CONSTRAINT metadata_schemas_pkey;
+ALTER TABLE metadata_schemas ADD PRIMARY KEY (tenant, entity);
+
+ALTER TABLE metadata_schemas ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING (updated_at || ' UTC')::timestamptz;
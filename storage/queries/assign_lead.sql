@@ -1,2 +1,2 @@
-INSERT INTO leads (lead_id, client_id, start_date, end_date)
-VALUES (?, ?, ?, ?);
\ No newline at end of file
+INSERT INTO leads (lead_id, client_id, start_date, end_date, metadata)
+VALUES (?, ?, ?, ?, ?);
\ No newline at end of file
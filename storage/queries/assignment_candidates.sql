@@ -0,0 +1,44 @@
+-- Active clients whose time frame contains the lead (lead start and end are the arguments) and who have free capacity,
+-- as well as their group. Leads are counted per client through the leads_client_status index, so the cost depends on
+-- the leads of the candidates, which their capacities bound, rather than on the size of the leads table
+WITH candidates AS (
+    SELECT
+        c.id,
+        c.name,
+        c.start_date,
+        c.end_date,
+        c.priority,
+        c.lead_capacity,
+        c.group_id,
+        c.metadata,
+        (SELECT COUNT(*) FROM leads AS l WHERE l.client_id = c.id AND l.status = 'ASSIGNED') AS used
+    FROM clients AS c
+    WHERE c.archived_at IS NULL AND c.start_date <= ? AND c.end_date >= ?
+),
+group_usage AS (
+    SELECT
+        g.id,
+        g.lead_capacity,
+        (
+            SELECT COUNT(*)
+            FROM clients AS m
+            JOIN leads AS l ON l.client_id = m.id AND l.status = 'ASSIGNED'
+            WHERE m.group_id = g.id
+        ) AS used
+    FROM client_groups AS g
+    WHERE g.id IN (SELECT group_id FROM candidates)
+)
+SELECT
+    c.id,
+    c.name,
+    c.start_date,
+    c.end_date,
+    c.priority,
+    c.lead_capacity,
+    c.group_id,
+    c.metadata,
+    c.used
+FROM candidates AS c
+LEFT JOIN group_usage AS g ON g.id = c.group_id
+WHERE c.used < c.lead_capacity AND (g.id IS NULL OR g.used < g.lead_capacity)
+ORDER BY c.id
@@ -0,0 +1,17 @@
+SELECT
+    c.id,
+    c.name,
+    c.start_date as start_date,
+    c.end_date as end_date,
+    c.priority,
+    c.lead_capacity,
+    c.group_id,
+    c.metadata,
+    l.lead_id,
+    l.start_date as lead_start,
+    l.end_date as lead_end,
+    l.metadata as lead_metadata
+FROM clients AS c
+LEFT JOIN leads as l on c.id = l.client_id AND l.status = 'ASSIGNED'
+WHERE c.archived_at IS NULL AND c.id = ?
+ORDER BY l.start_date, l.lead_id
\ No newline at end of file
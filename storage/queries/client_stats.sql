@@ -0,0 +1,13 @@
+SELECT
+    c.id,
+    c.name,
+    c.priority,
+    c.start_date,
+    c.end_date,
+    c.lead_capacity,
+    COUNT(l.lead_id) AS used
+FROM clients AS c
+LEFT JOIN leads AS l ON l.client_id = c.id AND l.status = 'ASSIGNED'
+WHERE c.archived_at IS NULL
+GROUP BY c.id
+ORDER BY c.id
\ No newline at end of file
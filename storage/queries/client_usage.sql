@@ -0,0 +1,11 @@
+-- Active clients with the number of leads assigned to them, what the capacity index keeps
+SELECT
+    c.id,
+    c.start_date,
+    c.end_date,
+    c.priority,
+    c.lead_capacity,
+    c.group_id,
+    (SELECT COUNT(*) FROM leads AS l WHERE l.client_id = c.id AND l.status = 'ASSIGNED') AS used
+FROM clients AS c
+WHERE c.archived_at IS NULL
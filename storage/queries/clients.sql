@@ -5,8 +5,13 @@ SELECT
     c.end_date as end_date,
     c.priority,
     c.lead_capacity,
+    c.group_id,
+    c.metadata,
     l.lead_id,
     l.start_date as lead_start,
-    l.end_date as lead_end
+    l.end_date as lead_end,
+    l.metadata as lead_metadata
 FROM clients AS c
-LEFT JOIN leads as l on c.id = l.client_id
\ No newline at end of file
+LEFT JOIN leads as l on c.id = l.client_id AND l.status = 'ASSIGNED'
+WHERE c.archived_at IS NULL
+ORDER BY c.id, l.start_date, l.lead_id
\ No newline at end of file
@@ -0,0 +1,19 @@
+WITH usage AS (
+    SELECT client_id, COUNT(*) AS used
+    FROM leads
+    WHERE status = 'ASSIGNED'
+    GROUP BY client_id
+)
+SELECT
+    c.id,
+    c.name,
+    c.start_date,
+    c.end_date,
+    c.priority,
+    c.lead_capacity,
+    c.group_id,
+    c.metadata,
+    COALESCE(u.used, 0) AS used
+FROM clients AS c
+LEFT JOIN usage AS u ON u.client_id = c.id
+WHERE c.archived_at IS NULL
\ No newline at end of file
@@ -0,0 +1,16 @@
+SELECT
+    g.id,
+    g.name,
+    g.lead_capacity,
+    (
+        SELECT COUNT(*)
+        FROM leads AS l
+        JOIN clients AS c ON c.id = l.client_id
+        WHERE c.group_id = g.id AND l.status = 'ASSIGNED'
+    ) AS used,
+    (
+        SELECT group_concat(c.id)
+        FROM clients AS c
+        WHERE c.group_id = g.id AND c.archived_at IS NULL
+    ) AS members
+FROM client_groups AS g
\ No newline at end of file
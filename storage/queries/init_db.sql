@@ -1,20 +1,15 @@
-CREATE TABLE IF NOT EXISTS clients (
-    id INTEGER PRIMARY KEY,
-    name TEXT,
-    start_date TEXT,
-    end_date TEXT,
-    priority TEXT CHECK(priority IN ('LOW', 'MEDIUM', 'HIGH')) DEFAULT 'MEDIUM',
-    lead_capacity INTEGER
+-- Migrations applied to the database
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    applied_at TEXT NOT NULL
 );
 
-CREATE TABLE IF NOT EXISTS leads (
-    lead_id TEXT NOT NULL PRIMARY KEY,
-    client_id INTEGER NOT NULL,
-    start_date TEXT,
-    end_date TEXT,
-    FOREIGN KEY (client_id) REFERENCES clients(id)
+-- Its only row is updated first in every migration transaction, so instances started together migrate one at a time
+CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+    id INTEGER NOT NULL PRIMARY KEY,
+    locked_at TEXT
 );
 
-CREATE TABLE IF NOT EXISTS migrations (
-    timestamp TEXT
-)
\ No newline at end of file
+INSERT INTO schema_migrations_lock (id) VALUES (1) ON CONFLICT DO NOTHING;
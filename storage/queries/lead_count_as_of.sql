@@ -0,0 +1,10 @@
+SELECT COUNT(*)
+FROM (
+    SELECT
+        client_id,
+        status,
+        ROW_NUMBER() OVER (PARTITION BY lead_id ORDER BY id DESC) AS position
+    FROM lead_history
+    WHERE changed_at <= ?
+) AS latest
+WHERE position = 1 AND client_id = ? AND status = 'ASSIGNED'
\ No newline at end of file
@@ -0,0 +1,8 @@
+SELECT
+    l.lead_id,
+    l.client_id,
+    l.status,
+    l.start_date,
+    l.end_date,
+    l.metadata
+FROM leads AS l
\ No newline at end of file
@@ -1,2 +1,3 @@
-INSERT INTO clients (id, name, start_date, end_date, priority, lead_capacity)
-    VALUES (?, ?, ?, ?, ?, ?);
\ No newline at end of file
+INSERT INTO clients (name, start_date, end_date, priority, lead_capacity, group_id, metadata)
+    VALUES (?, ?, ?, ?, ?, ?, ?)
+    RETURNING id;
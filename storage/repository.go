@@ -0,0 +1,83 @@
+package storage
+
+//go:generate go run leads/internal/fakegen -source repository.go -import leads/storage -package storagefake -o storagefake/repository.go
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// ClientRepository - clients, their history and import
+type ClientRepository interface {
+	CreateClient(ctx context.Context, c ClientRequest) (*Client, error)
+	// GetClients - receives the client `clientID` or all of them when it's nil. Returns no clients when it does not exist
+	GetClients(ctx context.Context, clientID *int) ([]Client, error)
+	ListClients(ctx context.Context, f ClientsFilter) (*ClientsPage, error)
+	ArchiveClient(ctx context.Context, clientID int, policy LeadsPolicy) (*ArchiveResult, error)
+	RestoreClient(ctx context.Context, clientID int) (*Client, error)
+	ClientHistory(ctx context.Context, clientID int) ([]ClientChange, error)
+	ClientAsOf(ctx context.Context, clientID int, asOf time.Time) (*ClientAsOf, error)
+	UpdateClientMetadata(ctx context.Context, clientID int, metadata Metadata) (*Client, error)
+	ImportClients(ctx context.Context, rows []ImportRow, mode ImportMode) (*ImportReport, error)
+	EachClient(ctx context.Context, f ClientsFilter, fn func(c Client, used int) error) error
+}
+
+// LeadRepository - leads and their assignment to clients
+type LeadRepository interface {
+	AssignLead(ctx context.Context, l AssignLeadRequest) (*Lead, error)
+	ListLeads(ctx context.Context, f LeadsFilter) (*LeadsPage, error)
+	GetLead(ctx context.Context, leadID string) (*Lead, error)
+	UpdateLeadMetadata(ctx context.Context, leadID string, metadata Metadata) (*Lead, error)
+	EachLead(ctx context.Context, f LeadsFilter, fn func(l Lead) error) error
+}
+
+// GroupRepository - client groups and their members
+type GroupRepository interface {
+	// GetGroups - receives the group `groupID` or all of them when it's nil
+	GetGroups(ctx context.Context, groupID *int) ([]ClientGroup, error)
+	CreateGroup(ctx context.Context, g ClientGroupRequest) (*ClientGroup, error)
+	UpdateGroup(ctx context.Context, groupID int, g ClientGroupRequest) (*ClientGroup, error)
+	DeleteGroup(ctx context.Context, groupID int) (bool, error)
+	// SetGroupMember - moves the client to the group `groupID` or out of any group when it's nil
+	SetGroupMember(ctx context.Context, groupID *int, clientID int) (*ClientGroup, error)
+	// RemoveGroupMember - moves the client out of the group. Returns nil group when it's not a member of the group
+	RemoveGroupMember(ctx context.Context, groupID int, clientID int) (*ClientGroup, error)
+}
+
+// MetadataRepository - JSON schemas of the client and lead metadata
+type MetadataRepository interface {
+	GetMetadataSchema(ctx context.Context, entity MetadataEntity) (*MetadataSchema, error)
+	SetMetadataSchema(ctx context.Context, entity MetadataEntity, schema json.RawMessage) (*MetadataSchema, error)
+	DeleteMetadataSchema(ctx context.Context, entity MetadataEntity) (bool, error)
+}
+
+// StatsRepository - capacity utilization reports
+type StatsRepository interface {
+	ClientsStats(ctx context.Context) (*ClientsStats, error)
+}
+
+// BackupRepository - snapshots of the database
+type BackupRepository interface {
+	// Backup - writes a consistent snapshot of the database to the new file `path`
+	Backup(ctx context.Context, path string) (*BackupInfo, error)
+}
+
+// CapacityIndexRepository - the in-process index of client capacity used by the assignment engine
+type CapacityIndexRepository interface {
+	// CheckCapacityIndex - compares the index with the database and rebuilds it when they differ
+	CheckCapacityIndex(ctx context.Context) (*CapacityIndexReport, error)
+}
+
+// Repository - everything the API needs from a storage backend
+type Repository interface {
+	ClientRepository
+	LeadRepository
+	GroupRepository
+	MetadataRepository
+	StatsRepository
+	BackupRepository
+	CapacityIndexRepository
+}
+
+var _ Repository = (*Storage)(nil)
@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
 	"sort"
 	"time"
 
@@ -16,309 +15,412 @@ type DB interface {
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	Conn(ctx context.Context) (*sql.Conn, error)
 	Ping() error
 }
 
+// queryer - common part of DB and *sql.Tx, so queries can run both inside and outside a transaction
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type Storage struct {
-	db DB
-	h  SQLHelpersReader
+	db      dialectDB
+	h       SQLHelpersReader
+	queries map[string]string
+	stmts   statements
+	// capacity - nil until EnableCapacityIndex
+	capacity *capacityIndex
 }
 
+// New - storage over a SQLite database
 func New(db DB, helpers SQLHelpersReader) (*Storage, error) {
+	return newStorage(db, helpers, sqliteDialect{})
+}
+
+func newStorage(db DB, helpers SQLHelpersReader, d dialect) (*Storage, error) {
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("can't connect to database: %w", err)
 	}
 
-	return &Storage{
-		db: db,
+	s := &Storage{
+		db: dialectDB{DB: db, dialect: d},
 		h:  helpers,
-	}, nil
-}
-
-// Init - initializes DB entities
-func (s *Storage) Init(ctx context.Context) error {
-	initDb, err := s.h.ReadSQLFile("storage/queries/init_db.sql")
-	if err != nil {
-		return fmt.Errorf("failed to read SQL file: %w", err)
-	}
-
-	_, err = s.db.ExecContext(ctx, initDb)
-	if err != nil {
-		return fmt.Errorf("can't create table: %w", err)
-	}
-
-	return nil
-}
-
-// Migrations - starts the migration process and saves results in DB
-func (s *Storage) Migrations(ctx context.Context) error {
-	// Map with names of already executed migrations
-	migrations := make(map[string]string)
-
-	rows, err := s.db.QueryContext(ctx, `SELECT * FROM migrations`)
-	if err != nil {
-		return fmt.Errorf("failed to read migrations: %w", err)
 	}
-
-	for rows.Next() {
-		var file string
-		if err := rows.Scan(&file); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		migrations[file] = file
+	if err := s.loadQueries(); err != nil {
+		return nil, err
 	}
 
-	files, err := os.ReadDir("storage/migrations")
-	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
-	}
-
-	for _, file := range files {
-		_, ok := migrations[file.Name()]
-
-		// Call migration and save it to the database in case if it has not been previously executed
-		if !ok {
-			// Read and execute migration code
-			migration, err := s.h.ReadSQLFile("storage/migrations/" + file.Name())
-			if err != nil {
-				return fmt.Errorf("failed to read SQL file: %w", err)
-			}
-
-			_, err = s.db.ExecContext(ctx, migration)
-			if err != nil {
-				return fmt.Errorf("failed to run migration %s: %w", file.Name(), err)
-			}
-
-			q := `INSERT INTO migrations (timestamp) VALUES (?)`
-			_, err = s.db.ExecContext(ctx, q, file.Name()) // Save migration in DB
-			if err != nil {
-				return fmt.Errorf("failed to find migration %s: %w", file.Name(), err)
-			}
-		}
-	}
-
-	return nil
+	return s, nil
 }
 
-// GetClients - receives a list of clients. Optional parameter `clientID`. When passed, will receive only selected client
+// GetClients - receives a list of clients. Optional parameter `clientID`. When passed, will receive only selected client.
+// Archived clients are never returned
 func (s *Storage) GetClients(ctx context.Context, clientID *int) ([]Client, error) {
-	baseQuery, err := s.h.ReadSQLFile("storage/queries/clients.sql")
-	if err != nil {
-		return nil, fmt.Errorf("failed to read SQL file: %w", err)
-	}
+	return s.getClients(ctx, s.db, clientID)
+}
 
-	var query string
-	var args []interface{}
+// getClients - active clients ordered by ID with their leads ordered by start date, or only the client `clientID`
+func (s *Storage) getClients(ctx context.Context, q queryer, clientID *int) ([]Client, error) {
+	name, args := queryClients, []interface{}{}
 	if clientID != nil {
-		query = fmt.Sprintf("%s WHERE c.id = ?", baseQuery)
-		args = append(args, *clientID)
-	} else {
-		query = baseQuery
+		name, args = queryClient, []interface{}{*clientID}
 	}
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	stmt, err := s.stmt(ctx, q, name)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get clients: %w", err)
 	}
-
 	defer rows.Close()
 
-	clientMap := make(map[int]*Client)
+	// Rows of a client come one after another, the clients keep the order of the query
+	var clients []Client
+	index := make(map[int]int)
 
 	for rows.Next() {
 		var clientID int
 		var clientName string
-		var startDate, endDate string
+		var startDate, endDate time.Time
 		var priority Priority
 		var leadCapacity int
-		var leadID, leadStart, leadEnd sql.NullString
+		var groupID sql.NullInt64
+		var metadata sql.NullString
+		var leadID, leadMetadata sql.NullString
+		var leadStart, leadEnd time.Time
 
 		err := rows.Scan(
 			&clientID,
 			&clientName,
-			&startDate,
-			&endDate,
+			scanTime(&startDate),
+			scanTime(&endDate),
 			&priority,
 			&leadCapacity,
+			&groupID,
+			&metadata,
 			&leadID,
-			&leadStart,
-			&leadEnd,
+			scanTime(&leadStart),
+			scanTime(&leadEnd),
+			&leadMetadata,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
-		client, exists := clientMap[clientID]
+		i, exists := index[clientID]
 		if !exists {
-			client = &Client{
+			clients = append(clients, Client{
 				ID:           clientID,
 				Name:         clientName,
 				StartDate:    startDate,
 				EndDate:      endDate,
 				Priority:     priority,
 				LeadCapacity: leadCapacity,
+				GroupID:      nullableInt(groupID),
+				Metadata:     metadataValue(metadata),
 				Leads:        []Lead{},
-			}
-			clientMap[clientID] = client
+			})
+			i = len(clients) - 1
+			index[clientID] = i
 		}
 
 		if leadID.Valid {
-			client.Leads = append(client.Leads, Lead{
+			clients[i].Leads = append(clients[i].Leads, Lead{
 				ClientID:  clientID,
 				LeadID:    leadID.String,
-				LeadStart: leadStart.String,
-				LeadEnd:   leadEnd.String,
+				Status:    LeadStatusAssigned,
+				LeadStart: leadStart,
+				LeadEnd:   leadEnd,
+				Metadata:  metadataValue(leadMetadata),
 			})
 		}
 	}
-
-	defer rows.Close()
-
-	var clients []Client
-	for _, client := range clientMap {
-		clients = append(clients, *client)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get clients: %w", err)
 	}
 
 	return clients, nil
 }
 
-func (s *Storage) CreateClient(ctx context.Context, c ClientRequest) error {
-	createClientQuery, err := s.h.ReadSQLFile("storage/queries/new_client.sql")
+// CreateClient - creates a new client. Client ID is assigned by the database
+func (s *Storage) CreateClient(ctx context.Context, c ClientRequest) (*Client, error) {
+	if err := s.validateMetadata(ctx, s.db, MetadataClient, c.Metadata); err != nil {
+		return nil, err
+	}
+
+	unlock := s.lockCapacity()
+	defer unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	clientID, err := s.insertClient(ctx, tx, c)
 	if err != nil {
-		return fmt.Errorf("failed to read SQL file: %w", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("can't commit transaction: %w", err)
 	}
+	s.updateCapacity(ctx, []int{clientID}, nil)
+
+	return &Client{
+		ID:           clientID,
+		Name:         c.Name,
+		StartDate:    c.StartDate.Time,
+		EndDate:      c.EndDate.Time,
+		Priority:     c.Priority,
+		LeadCapacity: c.LeadCapacity,
+		GroupID:      c.GroupID,
+		Metadata:     c.Metadata,
+		Leads:        []Lead{},
+	}, nil
+}
 
-	userID, err := s.generateUserID(ctx)
+// insertClient - inserts the client row and records its first version in the history
+func (s *Storage) insertClient(ctx context.Context, q queryer, c ClientRequest) (int, error) {
+	if c.GroupID != nil {
+		var exists bool
+		if err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM client_groups WHERE id = ?)`, *c.GroupID).Scan(&exists); err != nil {
+			return 0, fmt.Errorf("failed to check client group: %w", err)
+		}
+		if !exists {
+			return 0, invalidInput(CodeUnknownGroup, "client group %d does not exist", *c.GroupID)
+		}
+	}
+
+	createClient, err := s.stmt(ctx, q, queryNewClient)
 	if err != nil {
-		return fmt.Errorf("failed to generate client ID: %w", err)
+		return 0, err
 	}
 
-	_, err = s.db.ExecContext(
+	var clientID int
+	err = createClient.QueryRowContext(
 		ctx,
-		createClientQuery,
-		userID,
 		c.Name,
-		c.StartDate,
-		c.EndDate,
+		timeArg(c.StartDate.Time),
+		timeArg(c.EndDate.Time),
 		c.Priority,
 		c.LeadCapacity,
-	)
+		c.GroupID,
+		metadataArg(c.Metadata),
+	).Scan(&clientID)
 	if err != nil {
-		return fmt.Errorf("failed to get clients: %w", err)
+		return 0, fmt.Errorf("failed to create client: %w", err)
 	}
 
-	return nil
+	if err := recordClientChange(ctx, q, clientID, ClientCreated, nil); err != nil {
+		return 0, err
+	}
+
+	return clientID, nil
 }
 
 // AssignLead - Selects a suitable client for assignment. Assigns a Lead to him and returns ID of this client.
 func (s *Storage) AssignLead(ctx context.Context, l AssignLeadRequest) (*Lead, error) {
-	clients, err := s.GetClients(ctx, nil)
+	if err := s.validateMetadata(ctx, s.db, MetadataLead, l.Metadata); err != nil {
+		return nil, err
+	}
+
+	unlock := s.lockCapacity()
+	defer unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get clients: %w", err)
+		return nil, fmt.Errorf("can't start transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	var availableClients []Client
+	pick := s.pickClient
+	if s.capacity != nil {
+		pick = s.pickIndexedClient
+	}
 
-	for _, client := range clients {
-		if noCapacity(client) {
-			continue
-		}
-		if unsuitableTime(client, l) {
-			continue
-		}
+	priorityUser, err := pick(ctx, tx, l)
+	if err != nil {
+		return nil, err
+	}
 
-		availableClients = append(availableClients, client)
+	leadID, _ := uuid.NewUUID()
+
+	assignLead, err := s.stmt(ctx, tx, queryAssignLead)
+	if err != nil {
+		return nil, err
 	}
 
-	sortedClients := make([]Client, len(availableClients))
-	copy(sortedClients, availableClients)
+	_, err = assignLead.ExecContext(
+		ctx,
+		leadID.String(),
+		priorityUser.ID,
+		timeArg(l.LeadStart.Time),
+		timeArg(l.LeadEnd.Time),
+		metadataArg(l.Metadata),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't create lead: %w", err)
+	}
 
-	sort.Slice(sortedClients, sortByPriorityAndCapacity(sortedClients))
+	if err := recordLeadChange(ctx, tx, leadID.String(), priorityUser.ID, LeadStatusAssigned); err != nil {
+		return nil, err
+	}
 
-	if len(sortedClients) == 0 {
-		return nil, fmt.Errorf("there are no clients available to assign")
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("can't commit transaction: %w", err)
 	}
 
-	priorityUser := sortedClients[0]
+	if s.capacity != nil {
+		s.capacity.assigned(priorityUser.ID)
+	}
 
-	if &priorityUser != nil {
-		assignLeadQuery, err := s.h.ReadSQLFile("storage/queries/assign_lead.sql")
-		if err != nil {
-			return nil, fmt.Errorf("failed to read SQL file: %w", err)
-		}
+	return &Lead{
+		LeadID:    leadID.String(),
+		ClientID:  priorityUser.ID,
+		Status:    LeadStatusAssigned,
+		LeadStart: l.LeadStart.Time,
+		LeadEnd:   l.LeadEnd.Time,
+		Metadata:  l.Metadata,
+	}, nil
+}
 
-		leadID, _ := uuid.NewUUID()
+// pickClient - the assignment engine. Initially filters clients by their availability (own capacity and the capacity pool
+// of their group) and suitable time frames, then sorts them by priority and percentage of free capacity and returns the best one
+func (s *Storage) pickClient(ctx context.Context, q queryer, l AssignLeadRequest) (*Client, error) {
+	candidates, err := s.assignmentCandidates(ctx, q, l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clients: %w", err)
+	}
 
-		_, err = s.db.ExecContext(
-			ctx,
-			assignLeadQuery,
-			leadID.String(),
-			priorityUser.ID,
-			l.LeadStart,
-			l.LeadEnd,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("can't create lead: %w", err)
+	// The query compares the stored text of the dates, withinWindow has the last word
+	var available []candidate
+	for _, c := range candidates {
+		if unsuitableTime(c.Client, l) {
+			continue
 		}
 
-		return &Lead{
-			LeadID:    leadID.String(),
-			ClientID:  priorityUser.ID,
-			LeadStart: l.LeadStart,
-			LeadEnd:   l.LeadEnd,
-		}, nil
+		available = append(available, c)
 	}
 
-	return nil, nil
+	sort.Slice(available, sortByPriorityAndCapacity(available))
+
+	if len(available) == 0 {
+		return nil, ErrNoClientsAvailable
+	}
+
+	return &available[0].Client, nil
 }
 
-func (s *Storage) generateUserID(ctx context.Context) (*int, error) {
-	var count int
-	countQuery := "select count(*) from clients;"
+// assignmentCandidates - active clients with free capacity, own and of their group, whose time frames contain the lead,
+// in ID order. Their leads are counted by the database instead of being read
+func (s *Storage) assignmentCandidates(ctx context.Context, q queryer, l AssignLeadRequest) ([]candidate, error) {
+	stmt, err := s.stmt(ctx, q, queryCandidates)
+	if err != nil {
+		return nil, err
+	}
 
-	err := s.db.QueryRowContext(ctx, countQuery).Scan(&count)
+	rows, err := stmt.QueryContext(ctx, timeArg(l.LeadStart.Time), timeArg(l.LeadEnd.Time))
 	if err != nil {
-		return nil, fmt.Errorf("can't count clients: %w", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	clientID := count + 1
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		var groupID sql.NullInt64
+		var metadata sql.NullString
 
-	return &clientID, nil
+		err := rows.Scan(
+			&c.ID,
+			&c.Name,
+			scanTime(&c.StartDate),
+			scanTime(&c.EndDate),
+			&c.Priority,
+			&c.LeadCapacity,
+			&groupID,
+			&metadata,
+			&c.used,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		c.GroupID = nullableInt(groupID)
+		c.Metadata = metadataValue(metadata)
+
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
 }
 
-func noCapacity(client Client) bool {
-	return len(client.Leads) >= client.LeadCapacity
+func nullableInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+
+	i := int(v.Int64)
+	return &i
 }
 
+// unsuitableTime - the lead window does not fit into the client's time frame. Bounds are inclusive
 func unsuitableTime(client Client, lead AssignLeadRequest) bool {
-	const layout = "2006-01-01 00:00:00"
-
-	clientStart, err1 := time.Parse(layout, client.StartDate)
-	clientEnd, err2 := time.Parse(layout, client.StartDate)
-	leadStart, err3 := time.Parse(layout, lead.LeadStart)
-	leadEnd, err4 := time.Parse(layout, lead.LeadEnd)
+	return !withinWindow(client.StartDate, client.EndDate, lead.LeadStart.Time, lead.LeadEnd.Time)
+}
 
-	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
-		return true
+// withinWindow - [start, end] lies inside [windowStart, windowEnd]. Inverted or unset bounds contain nothing
+func withinWindow(windowStart, windowEnd, start, end time.Time) bool {
+	if windowStart.IsZero() || windowEnd.IsZero() || start.IsZero() || end.IsZero() {
+		return false
 	}
-
-	if clientStart.After(leadStart) || clientEnd.Before(leadEnd) {
-		return true
+	if windowEnd.Before(windowStart) || end.Before(start) {
+		return false
 	}
 
-	return false
+	return !start.Before(windowStart) && !end.After(windowEnd)
 }
 
-func sortByPriorityAndCapacity(clients []Client) func(i, j int) bool {
+// candidate - client the assignment engine may pick, with the number of leads assigned to it
+type candidate struct {
+	Client
+	used int
+}
+
+// sortByPriorityAndCapacity - ranks higher priorities first, then larger percentages of free capacity, then lower IDs
+func sortByPriorityAndCapacity(candidates []candidate) func(i, j int) bool {
 	return func(i, j int) bool {
-		if clients[i].Priority != clients[j].Priority {
-			return PriorityMap[clients[i].Priority] > PriorityMap[clients[j].Priority]
-		}
+		return ranksAbove(candidates[i], candidates[j])
+	}
+}
 
-		freeLeadsPercentageI := ((clients[i].LeadCapacity - len(clients[i].Leads)) * 100) / clients[i].LeadCapacity
-		freeLeadsPercentageJ := ((clients[j].LeadCapacity - len(clients[j].Leads)) * 100) / clients[j].LeadCapacity
+// ranksAbove - the assignment engine prefers the client `a` to `b`
+func ranksAbove(a, b candidate) bool {
+	if a.Priority != b.Priority {
+		return PriorityMap[a.Priority] > PriorityMap[b.Priority]
+	}
 
-		return freeLeadsPercentageI > freeLeadsPercentageJ
+	freeLeadsPercentageA := freeLeadsPercentage(a.LeadCapacity, a.used)
+	freeLeadsPercentageB := freeLeadsPercentage(b.LeadCapacity, b.used)
+	if freeLeadsPercentageA != freeLeadsPercentageB {
+		return freeLeadsPercentageA > freeLeadsPercentageB
 	}
+
+	return a.ID < b.ID
+}
+
+// freeLeadsPercentage - percentage of capacity that is still free. Clients without capacity have nothing free
+func freeLeadsPercentage(capacity, used int) int {
+	if capacity <= 0 {
+		return 0
+	}
+
+	return ((capacity - used) * 100) / capacity
 }
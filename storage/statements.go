@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+)
+
+// Files of storage/queries. Storage reads all of them once when it's created
+const (
+	queryInitDB        = "init_db.sql"
+	queryClients       = "clients.sql"
+	queryClient        = "client.sql"
+	queryClientsPage   = "clients_page.sql"
+	queryNewClient     = "new_client.sql"
+	queryAssignLead    = "assign_lead.sql"
+	queryLeads         = "leads.sql"
+	queryGroups        = "groups.sql"
+	queryClientStats   = "client_stats.sql"
+	queryLeadCountAsOf = "lead_count_as_of.sql"
+	queryCandidates    = "assignment_candidates.sql"
+	queryClientUsage   = "client_usage.sql"
+)
+
+// statementFiles - queries prepared by Prepare. init_db.sql creates the tables the others refer to, so it only runs
+var statementFiles = []string{
+	queryClients,
+	queryClient,
+	queryClientsPage,
+	queryNewClient,
+	queryAssignLead,
+	queryLeads,
+	queryGroups,
+	queryClientStats,
+	queryLeadCountAsOf,
+	queryCandidates,
+	queryClientUsage,
+}
+
+// statements - prepared statements of the query files, shared by all requests
+type statements struct {
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+// loadQueries - reads all query files, preferring the dialect's own versions of them
+func (s *Storage) loadQueries() error {
+	s.queries = make(map[string]string)
+
+	for _, name := range append([]string{queryInitDB}, statementFiles...) {
+		query, err := s.readQuery(name)
+		if err != nil {
+			return fmt.Errorf("failed to read SQL file %s: %w", name, err)
+		}
+		s.queries[name] = query
+	}
+
+	return nil
+}
+
+// readQuery - reads the storage/queries file `name`, preferring the dialect's own version of it
+func (s *Storage) readQuery(name string) (string, error) {
+	if path := s.db.dialect.queryFile(name); path != "" {
+		query, err := s.h.ReadSQLFile(path)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return query, err
+		}
+	}
+
+	return s.h.ReadSQLFile("storage/queries/" + name)
+}
+
+// Prepare - prepares the statements of all query files. Call it after Migrations: a query referring to a table or
+// column that does not exist fails here instead of on the first request using it
+func (s *Storage) Prepare(ctx context.Context) error {
+	for _, name := range statementFiles {
+		if _, err := s.prepared(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prepared - the prepared statement of the query file `name`. Statements not prepared by Prepare are prepared on
+// first use
+func (s *Storage) prepared(ctx context.Context, name string) (*sql.Stmt, error) {
+	s.stmts.mu.Lock()
+	defer s.stmts.mu.Unlock()
+
+	if stmt, ok := s.stmts.stmts[name]; ok {
+		return stmt, nil
+	}
+
+	query, ok := s.queries[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown SQL file %s", name)
+	}
+
+	stmt, err := s.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare %s: %w", name, err)
+	}
+
+	if s.stmts.stmts == nil {
+		s.stmts.stmts = make(map[string]*sql.Stmt)
+	}
+	s.stmts.stmts[name] = stmt
+
+	return stmt, nil
+}
+
+// stmt - the prepared statement of the query file `name` to run through `q`, which may be a transaction
+func (s *Storage) stmt(ctx context.Context, q queryer, name string) (*sql.Stmt, error) {
+	stmt, err := s.prepared(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx, ok := q.(*dialectTx); ok {
+		return tx.StmtContext(ctx, stmt), nil
+	}
+
+	return stmt, nil
+}
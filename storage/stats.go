@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ClientsStats - capacity utilization of every active client and totals per priority
+func (s *Storage) ClientsStats(ctx context.Context) (*ClientsStats, error) {
+	statsQuery, err := s.stmt(ctx, s.db, queryClientStats)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := statsQuery.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client stats: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now().UTC()
+	stats := newClientsStats(now)
+
+	for rows.Next() {
+		var c ClientStats
+		var start, end time.Time
+
+		err := rows.Scan(&c.ClientID, &c.Name, &c.Priority, scanTime(&start), scanTime(&end), &c.LeadCapacity, &c.UsedCapacity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		stats.add(c, start, end, now)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get client stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+func newClientsStats(now time.Time) *ClientsStats {
+	return &ClientsStats{
+		GeneratedAt: now.Format(time.RFC3339),
+		Clients:     []ClientStats{},
+		Priorities:  make(map[Priority]PriorityStats),
+	}
+}
+
+// add - adds the client with its capacity and time frame `start`-`end` to the report and the totals of its priority
+func (stats *ClientsStats) add(c ClientStats, start, end, now time.Time) {
+	c.AvailableCapacity = max(c.LeadCapacity-c.UsedCapacity, 0)
+	c.FreePercentage = freeLeadsPercentage(c.LeadCapacity, c.UsedCapacity)
+
+	if !start.IsZero() && !end.IsZero() {
+		c.LeadsPerDay = leadsPerDay(c.UsedCapacity, start, end, now)
+		c.WindowEndsInSeconds = max(int64(end.Sub(now).Seconds()), 0)
+	}
+
+	stats.Clients = append(stats.Clients, c)
+
+	p := stats.Priorities[c.Priority]
+	p.Clients++
+	p.LeadCapacity += c.LeadCapacity
+	p.UsedCapacity += c.UsedCapacity
+	p.AvailableCapacity += c.AvailableCapacity
+	p.FreePercentage = freeLeadsPercentage(p.LeadCapacity, p.UsedCapacity)
+	stats.Priorities[c.Priority] = p
+}
+
+// leadsPerDay - leads per day of the elapsed part of the time frame. A started frame counts as at least one day
+func leadsPerDay(leads int, start, end, now time.Time) float64 {
+	if now.Before(start) {
+		return 0
+	}
+	if now.After(end) {
+		now = end
+	}
+
+	days := math.Max(now.Sub(start).Hours()/24, 1)
+
+	return float64(leads) / days
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// testClientsStats - utilization of every active client and totals of its priority
+func testClientsStats(t *testing.T, r Repository) {
+	createClient(t, r, ClientRequest{Name: "a", Priority: "HIGH", LeadCapacity: 4})
+	createClient(t, r, ClientRequest{Name: "b", Priority: "HIGH", LeadCapacity: 2})
+	createClient(t, r, ClientRequest{Name: "c", Priority: "LOW", LeadCapacity: 5})
+	createClient(t, r, ClientRequest{
+		Name:         "d",
+		Priority:     "LOW",
+		LeadCapacity: 1,
+		StartDate:    Timestamp{Time: date("2024-01-01T00:00:00Z")},
+		EndDate:      Timestamp{Time: date("2100-01-01T00:00:00Z")},
+	})
+	archived := createClient(t, r, ClientRequest{Name: "e", Priority: "HIGH", LeadCapacity: 9})
+	if _, err := r.ArchiveClient(context.Background(), archived, LeadsKeep); err != nil {
+		t.Fatalf("ArchiveClient: %v", err)
+	}
+	for range 3 {
+		assignLead(t, r, leadRequest())
+	}
+
+	stats, err := r.ClientsStats(context.Background())
+	if err != nil {
+		t.Fatalf("ClientsStats: %v", err)
+	}
+
+	if len(stats.Clients) != 4 {
+		t.Fatalf("stats of %d clients, want 4 active ones", len(stats.Clients))
+	}
+	for _, c := range stats.Clients {
+		if c.AvailableCapacity != c.LeadCapacity-c.UsedCapacity || c.FreePercentage != freeLeadsPercentage(c.LeadCapacity, c.UsedCapacity) {
+			t.Errorf("stats of client %d = %+v, want available and free capacity of the used one", c.ClientID, c)
+		}
+
+		// The time frames of all clients started on 1 January 2024, the ones of January are over
+		days := 30.0
+		if c.ClientID == 4 {
+			days = time.Since(date("2024-01-01T00:00:00Z")).Hours() / 24
+		}
+		if want := float64(c.UsedCapacity) / days; math.Abs(c.LeadsPerDay-want) > 1e-3 {
+			t.Errorf("leads per day of client %d = %v, want %v", c.ClientID, c.LeadsPerDay, want)
+		}
+		if (c.WindowEndsInSeconds > 0) != (c.ClientID == 4) {
+			t.Errorf("window of client %d ends in %ds", c.ClientID, c.WindowEndsInSeconds)
+		}
+	}
+
+	want := map[Priority]PriorityStats{
+		"HIGH": {Clients: 2, LeadCapacity: 6, UsedCapacity: 3, AvailableCapacity: 3, FreePercentage: 50},
+		"LOW":  {Clients: 2, LeadCapacity: 6, UsedCapacity: 0, AvailableCapacity: 6, FreePercentage: 100},
+	}
+	for priority, p := range want {
+		if got := stats.Priorities[priority]; got != p {
+			t.Errorf("stats of %s priority = %+v, want %+v", priority, got, p)
+		}
+	}
+}
+
+func TestLeadsPerDay(t *testing.T) {
+	start, end := date("2024-01-01T00:00:00Z"), date("2024-01-11T00:00:00Z")
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want float64
+	}{
+		{"not started", date("2023-12-31T00:00:00Z"), 0},
+		{"first day", date("2024-01-01T06:00:00Z"), 20},
+		{"half elapsed", date("2024-01-06T00:00:00Z"), 4},
+		{"over", date("2024-02-01T00:00:00Z"), 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := leadsPerDay(20, start, end, tt.now); got != tt.want {
+				t.Errorf("leadsPerDay = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
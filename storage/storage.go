@@ -1,35 +1,241 @@
 package storage
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type Priority = string
 
+// Metadata - arbitrary JSON object attached to a client or a lead
+type Metadata = json.RawMessage
+
+type MetadataEntity = string
+
+const (
+	MetadataClient MetadataEntity = "client"
+	MetadataLead   MetadataEntity = "lead"
+)
+
+// MetadataSchema - JSON Schema the metadata of all clients or all leads a tenant writes must match
+type MetadataSchema struct {
+	Tenant    string          `json:"tenant"`
+	Entity    MetadataEntity  `json:"entity"`
+	Schema    json.RawMessage `json:"schema" swaggertype:"object"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type LeadStatus = string
+
+const (
+	LeadStatusAssigned LeadStatus = "ASSIGNED"
+	LeadStatusPending  LeadStatus = "PENDING" // Lead waits in the pending queue without a client
+)
+
+// LeadsPolicy - what happens to the leads of an archived client
+type LeadsPolicy = string
+
+const (
+	LeadsKeep     LeadsPolicy = "keep"     // Leads stay with the archived client
+	LeadsReassign LeadsPolicy = "reassign" // Leads go through the assignment engine again
+	LeadsPending  LeadsPolicy = "pending"  // Leads are moved to the pending queue
+)
+
 type Lead struct {
-	ClientID  int    `json:"client_id"`
-	LeadID    string `json:"lead_id"`
-	LeadStart string `json:"lead_start"`
-	LeadEnd   string `json:"lead_end"`
+	ClientID  int        `json:"client_id"`
+	LeadID    string     `json:"lead_id"`
+	Status    LeadStatus `json:"status"`
+	LeadStart time.Time  `json:"lead_start"`
+	LeadEnd   time.Time  `json:"lead_end"`
+	Metadata  Metadata   `json:"metadata,omitempty" swaggertype:"object"`
 }
 
 type Client struct {
-	ID           int      `json:"id"`
-	Name         string   `json:"name"`
-	StartDate    string   `json:"start_date"`
-	EndDate      string   `json:"end_date"`
-	Priority     Priority `json:"priority" binding:"oneof=HIGH MEDIUM LOW"`
-	LeadCapacity int      `json:"lead_capacity"`
-	Leads        []Lead   `json:"leads"`
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	StartDate    time.Time `json:"start_date"`
+	EndDate      time.Time `json:"end_date"`
+	Priority     Priority  `json:"priority" binding:"oneof=HIGH MEDIUM LOW"`
+	LeadCapacity int       `json:"lead_capacity"`
+	GroupID      *int      `json:"group_id"` // Client group sharing its capacity pool with the client, if any
+	Metadata     Metadata  `json:"metadata,omitempty" swaggertype:"object"`
+	Leads        []Lead    `json:"leads"`
 }
 
 type ClientRequest struct {
-	Name         string   `json:"name"`
-	StartDate    string   `json:"start_date"`
-	EndDate      string   `json:"end_date"`
-	Priority     Priority `json:"priority" binding:"oneof=HIGH MEDIUM LOW"`
-	LeadCapacity int      `json:"lead_capacity"`
+	Name         string    `json:"name" binding:"required"`
+	StartDate    Timestamp `json:"start_date" binding:"required,timestamp" swaggertype:"string" format:"date-time"`
+	EndDate      Timestamp `json:"end_date" binding:"required,timestamp,after=StartDate" swaggertype:"string" format:"date-time"`
+	Priority     Priority  `json:"priority" binding:"required,oneof=HIGH MEDIUM LOW"`
+	LeadCapacity int       `json:"lead_capacity" binding:"gt=0"`
+	GroupID      *int      `json:"group_id" binding:"omitempty,gt=0"`
+	Metadata     Metadata  `json:"metadata,omitempty" swaggertype:"object"`
 }
 
 type AssignLeadRequest struct {
-	LeadStart string `json:"lead_start"`
-	LeadEnd   string `json:"lead_end"`
+	LeadStart Timestamp `json:"lead_start" binding:"required,timestamp" swaggertype:"string" format:"date-time"`
+	LeadEnd   Timestamp `json:"lead_end" binding:"required,timestamp,after=LeadStart" swaggertype:"string" format:"date-time"`
+	Metadata  Metadata  `json:"metadata,omitempty" swaggertype:"object"`
+}
+
+// ClientGroup - agency with several clients. Leads of all members are taken from the group's capacity pool
+type ClientGroup struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	LeadCapacity      int    `json:"lead_capacity"`
+	UsedCapacity      int    `json:"used_capacity"`
+	AvailableCapacity int    `json:"available_capacity"`
+	Members           []int  `json:"members"` // IDs of member clients
+}
+
+type ClientGroupRequest struct {
+	Name         string `json:"name" binding:"required"`
+	LeadCapacity int    `json:"lead_capacity" binding:"gt=0"`
+}
+
+type GroupMemberRequest struct {
+	ClientID int `json:"client_id" binding:"required"`
+}
+
+// ClientsFilter - filters, sorting and pagination of the clients list
+type ClientsFilter struct {
+	Priority    []Priority        `form:"priority" binding:"dive,oneof=HIGH MEDIUM LOW"`
+	ActiveFrom  Timestamp         `form:"active_from" binding:"timestamp"` // Client's time frame starts not later than this date
+	ActiveTo    Timestamp         `form:"active_to" binding:"timestamp"`   // Client's time frame ends not earlier than this date
+	HasCapacity *bool             `form:"has_capacity"`                    // Client still can (true) or can't (false) receive leads
+	NamePrefix  string            `form:"name_prefix"`
+	Metadata    map[string]string `form:"-"`    // Metadata keys (dot-separated for nested ones) and their expected values
+	Sort        string            `form:"sort"` // Sort field, prefixed with "-" for descending order
+	Cursor      string            `form:"cursor"`
+	Limit       int               `form:"limit"`
+}
+
+type PageMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+type ClientsPage struct {
+	Data []Client `json:"data"`
+	Meta PageMeta `json:"meta"`
+}
+
+// LeadsFilter - filters and pagination of the leads list. Leads are ordered by their start date
+type LeadsFilter struct {
+	ClientID *int              `form:"client_id"`
+	Status   LeadStatus        `form:"status" binding:"omitempty,oneof=ASSIGNED PENDING"`
+	From     Timestamp         `form:"from" binding:"timestamp"` // Lead starts not earlier than this date
+	To       Timestamp         `form:"to" binding:"timestamp"`   // Lead ends not later than this date
+	Metadata map[string]string `form:"-"`                        // Metadata keys (dot-separated for nested ones) and their expected values
+	Cursor   string            `form:"cursor"`
+	Limit    int               `form:"limit"`
+}
+
+type LeadsPage struct {
+	Data []Lead   `json:"data"`
+	Meta PageMeta `json:"meta"`
+}
+
+type ClientStats struct {
+	ClientID            int      `json:"client_id"`
+	Name                string   `json:"name"`
+	Priority            Priority `json:"priority"`
+	LeadCapacity        int      `json:"lead_capacity"`
+	UsedCapacity        int      `json:"used_capacity"`
+	AvailableCapacity   int      `json:"available_capacity"`
+	FreePercentage      int      `json:"free_percentage"`        // Same metric the assignment engine ranks clients by
+	LeadsPerDay         float64  `json:"leads_per_day"`          // Assigned leads per day of the elapsed part of the time frame
+	WindowEndsInSeconds int64    `json:"window_ends_in_seconds"` // 0 when the time frame has already ended
+}
+
+type PriorityStats struct {
+	Clients           int `json:"clients"`
+	LeadCapacity      int `json:"lead_capacity"`
+	UsedCapacity      int `json:"used_capacity"`
+	AvailableCapacity int `json:"available_capacity"`
+	FreePercentage    int `json:"free_percentage"`
+}
+
+type ClientsStats struct {
+	GeneratedAt string                     `json:"generated_at"`
+	Clients     []ClientStats              `json:"clients"`
+	Priorities  map[Priority]PriorityStats `json:"priorities"`
+}
+
+// ImportMode - how a bulk import treats invalid rows
+type ImportMode = string
+
+const (
+	ImportAllOrNothing ImportMode = "all_or_nothing" // Nothing is imported when at least one row is invalid
+	ImportSkipInvalid  ImportMode = "skip_invalid"   // Valid rows are imported, invalid ones are reported
+)
+
+type ImportRowStatus = string
+
+const (
+	ImportRowCreated  ImportRowStatus = "created"
+	ImportRowInvalid  ImportRowStatus = "invalid"
+	ImportRowRejected ImportRowStatus = "rejected" // Valid row that was not imported because of other invalid rows
+)
+
+type ImportRow struct {
+	Row      int             `json:"row"` // Line number in the uploaded file
+	Status   ImportRowStatus `json:"status"`
+	ClientID int             `json:"client_id,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Client   ClientRequest   `json:"-"`
+}
+
+type ImportReport struct {
+	Mode      ImportMode  `json:"mode"`
+	Committed bool        `json:"committed"`
+	Total     int         `json:"total"`
+	Imported  int         `json:"imported"`
+	Invalid   int         `json:"invalid"`
+	Rows      []ImportRow `json:"rows"`
+}
+
+type ClientOperation = string
+
+const (
+	ClientCreated  ClientOperation = "create"
+	ClientUpdated  ClientOperation = "update"
+	ClientArchived ClientOperation = "archive"
+	ClientRestored ClientOperation = "restore"
+	ClientSnapshot ClientOperation = "snapshot" // State of a client that existed before the history was kept
+)
+
+// ClientChange - a version of the client row: who changed it, when, and its values before and after the change
+type ClientChange struct {
+	Version   int             `json:"version"`
+	Operation ClientOperation `json:"operation"`
+	ChangedAt time.Time       `json:"changed_at"`
+	ChangedBy string          `json:"changed_by"`
+	OldValues json.RawMessage `json:"old_values,omitempty" swaggertype:"object"`
+	NewValues json.RawMessage `json:"new_values" swaggertype:"object"`
+}
+
+// ClientAsOf - the client as it was at the given moment
+type ClientAsOf struct {
+	ID           int        `json:"id"`
+	Name         string     `json:"name"`
+	StartDate    time.Time  `json:"start_date"`
+	EndDate      time.Time  `json:"end_date"`
+	Priority     Priority   `json:"priority"`
+	LeadCapacity int        `json:"lead_capacity"`
+	GroupID      *int       `json:"group_id"`
+	Metadata     Metadata   `json:"metadata,omitempty" swaggertype:"object"`
+	ArchivedAt   *time.Time `json:"archived_at"`
+	LeadCount    int        `json:"lead_count"` // Leads assigned to the client at that moment
+	Version      int        `json:"version"`
+	AsOf         time.Time  `json:"as_of"`
+}
+
+type ArchiveResult struct {
+	ClientID int         `json:"client_id"`
+	Policy   LeadsPolicy `json:"leads_policy"`
+	Leads    []Lead      `json:"leads"` // Former leads of the client after the policy was applied
 }
 
 var PriorityMap = map[string]int{
@@ -0,0 +1,492 @@
+// Code generated by fakegen from repository.go; DO NOT EDIT.
+
+// Package storagefake - test doubles of the storage interfaces
+package storagefake
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"leads/storage"
+)
+
+// ClientRepository - double of storage.ClientRepository
+type ClientRepository struct {
+	CreateClientFunc         func(ctx context.Context, c storage.ClientRequest) (*storage.Client, error)
+	GetClientsFunc           func(ctx context.Context, clientID *int) ([]storage.Client, error)
+	ListClientsFunc          func(ctx context.Context, f storage.ClientsFilter) (*storage.ClientsPage, error)
+	ArchiveClientFunc        func(ctx context.Context, clientID int, policy storage.LeadsPolicy) (*storage.ArchiveResult, error)
+	RestoreClientFunc        func(ctx context.Context, clientID int) (*storage.Client, error)
+	ClientHistoryFunc        func(ctx context.Context, clientID int) ([]storage.ClientChange, error)
+	ClientAsOfFunc           func(ctx context.Context, clientID int, asOf time.Time) (*storage.ClientAsOf, error)
+	UpdateClientMetadataFunc func(ctx context.Context, clientID int, metadata storage.Metadata) (*storage.Client, error)
+	ImportClientsFunc        func(ctx context.Context, rows []storage.ImportRow, mode storage.ImportMode) (*storage.ImportReport, error)
+	EachClientFunc           func(ctx context.Context, f storage.ClientsFilter, fn func(c storage.Client, used int) error) error
+}
+
+var _ storage.ClientRepository = (*ClientRepository)(nil)
+
+func (fake *ClientRepository) CreateClient(ctx context.Context, c storage.ClientRequest) (*storage.Client, error) {
+	if fake.CreateClientFunc == nil {
+		panic("unexpected call of ClientRepository.CreateClient")
+	}
+	return fake.CreateClientFunc(ctx, c)
+}
+
+func (fake *ClientRepository) GetClients(ctx context.Context, clientID *int) ([]storage.Client, error) {
+	if fake.GetClientsFunc == nil {
+		panic("unexpected call of ClientRepository.GetClients")
+	}
+	return fake.GetClientsFunc(ctx, clientID)
+}
+
+func (fake *ClientRepository) ListClients(ctx context.Context, f storage.ClientsFilter) (*storage.ClientsPage, error) {
+	if fake.ListClientsFunc == nil {
+		panic("unexpected call of ClientRepository.ListClients")
+	}
+	return fake.ListClientsFunc(ctx, f)
+}
+
+func (fake *ClientRepository) ArchiveClient(ctx context.Context, clientID int, policy storage.LeadsPolicy) (*storage.ArchiveResult, error) {
+	if fake.ArchiveClientFunc == nil {
+		panic("unexpected call of ClientRepository.ArchiveClient")
+	}
+	return fake.ArchiveClientFunc(ctx, clientID, policy)
+}
+
+func (fake *ClientRepository) RestoreClient(ctx context.Context, clientID int) (*storage.Client, error) {
+	if fake.RestoreClientFunc == nil {
+		panic("unexpected call of ClientRepository.RestoreClient")
+	}
+	return fake.RestoreClientFunc(ctx, clientID)
+}
+
+func (fake *ClientRepository) ClientHistory(ctx context.Context, clientID int) ([]storage.ClientChange, error) {
+	if fake.ClientHistoryFunc == nil {
+		panic("unexpected call of ClientRepository.ClientHistory")
+	}
+	return fake.ClientHistoryFunc(ctx, clientID)
+}
+
+func (fake *ClientRepository) ClientAsOf(ctx context.Context, clientID int, asOf time.Time) (*storage.ClientAsOf, error) {
+	if fake.ClientAsOfFunc == nil {
+		panic("unexpected call of ClientRepository.ClientAsOf")
+	}
+	return fake.ClientAsOfFunc(ctx, clientID, asOf)
+}
+
+func (fake *ClientRepository) UpdateClientMetadata(ctx context.Context, clientID int, metadata storage.Metadata) (*storage.Client, error) {
+	if fake.UpdateClientMetadataFunc == nil {
+		panic("unexpected call of ClientRepository.UpdateClientMetadata")
+	}
+	return fake.UpdateClientMetadataFunc(ctx, clientID, metadata)
+}
+
+func (fake *ClientRepository) ImportClients(ctx context.Context, rows []storage.ImportRow, mode storage.ImportMode) (*storage.ImportReport, error) {
+	if fake.ImportClientsFunc == nil {
+		panic("unexpected call of ClientRepository.ImportClients")
+	}
+	return fake.ImportClientsFunc(ctx, rows, mode)
+}
+
+func (fake *ClientRepository) EachClient(ctx context.Context, f storage.ClientsFilter, fn func(c storage.Client, used int) error) error {
+	if fake.EachClientFunc == nil {
+		panic("unexpected call of ClientRepository.EachClient")
+	}
+	return fake.EachClientFunc(ctx, f, fn)
+}
+
+// LeadRepository - double of storage.LeadRepository
+type LeadRepository struct {
+	AssignLeadFunc         func(ctx context.Context, l storage.AssignLeadRequest) (*storage.Lead, error)
+	ListLeadsFunc          func(ctx context.Context, f storage.LeadsFilter) (*storage.LeadsPage, error)
+	GetLeadFunc            func(ctx context.Context, leadID string) (*storage.Lead, error)
+	UpdateLeadMetadataFunc func(ctx context.Context, leadID string, metadata storage.Metadata) (*storage.Lead, error)
+	EachLeadFunc           func(ctx context.Context, f storage.LeadsFilter, fn func(l storage.Lead) error) error
+}
+
+var _ storage.LeadRepository = (*LeadRepository)(nil)
+
+func (fake *LeadRepository) AssignLead(ctx context.Context, l storage.AssignLeadRequest) (*storage.Lead, error) {
+	if fake.AssignLeadFunc == nil {
+		panic("unexpected call of LeadRepository.AssignLead")
+	}
+	return fake.AssignLeadFunc(ctx, l)
+}
+
+func (fake *LeadRepository) ListLeads(ctx context.Context, f storage.LeadsFilter) (*storage.LeadsPage, error) {
+	if fake.ListLeadsFunc == nil {
+		panic("unexpected call of LeadRepository.ListLeads")
+	}
+	return fake.ListLeadsFunc(ctx, f)
+}
+
+func (fake *LeadRepository) GetLead(ctx context.Context, leadID string) (*storage.Lead, error) {
+	if fake.GetLeadFunc == nil {
+		panic("unexpected call of LeadRepository.GetLead")
+	}
+	return fake.GetLeadFunc(ctx, leadID)
+}
+
+func (fake *LeadRepository) UpdateLeadMetadata(ctx context.Context, leadID string, metadata storage.Metadata) (*storage.Lead, error) {
+	if fake.UpdateLeadMetadataFunc == nil {
+		panic("unexpected call of LeadRepository.UpdateLeadMetadata")
+	}
+	return fake.UpdateLeadMetadataFunc(ctx, leadID, metadata)
+}
+
+func (fake *LeadRepository) EachLead(ctx context.Context, f storage.LeadsFilter, fn func(l storage.Lead) error) error {
+	if fake.EachLeadFunc == nil {
+		panic("unexpected call of LeadRepository.EachLead")
+	}
+	return fake.EachLeadFunc(ctx, f, fn)
+}
+
+// GroupRepository - double of storage.GroupRepository
+type GroupRepository struct {
+	GetGroupsFunc         func(ctx context.Context, groupID *int) ([]storage.ClientGroup, error)
+	CreateGroupFunc       func(ctx context.Context, g storage.ClientGroupRequest) (*storage.ClientGroup, error)
+	UpdateGroupFunc       func(ctx context.Context, groupID int, g storage.ClientGroupRequest) (*storage.ClientGroup, error)
+	DeleteGroupFunc       func(ctx context.Context, groupID int) (bool, error)
+	SetGroupMemberFunc    func(ctx context.Context, groupID *int, clientID int) (*storage.ClientGroup, error)
+	RemoveGroupMemberFunc func(ctx context.Context, groupID int, clientID int) (*storage.ClientGroup, error)
+}
+
+var _ storage.GroupRepository = (*GroupRepository)(nil)
+
+func (fake *GroupRepository) GetGroups(ctx context.Context, groupID *int) ([]storage.ClientGroup, error) {
+	if fake.GetGroupsFunc == nil {
+		panic("unexpected call of GroupRepository.GetGroups")
+	}
+	return fake.GetGroupsFunc(ctx, groupID)
+}
+
+func (fake *GroupRepository) CreateGroup(ctx context.Context, g storage.ClientGroupRequest) (*storage.ClientGroup, error) {
+	if fake.CreateGroupFunc == nil {
+		panic("unexpected call of GroupRepository.CreateGroup")
+	}
+	return fake.CreateGroupFunc(ctx, g)
+}
+
+func (fake *GroupRepository) UpdateGroup(ctx context.Context, groupID int, g storage.ClientGroupRequest) (*storage.ClientGroup, error) {
+	if fake.UpdateGroupFunc == nil {
+		panic("unexpected call of GroupRepository.UpdateGroup")
+	}
+	return fake.UpdateGroupFunc(ctx, groupID, g)
+}
+
+func (fake *GroupRepository) DeleteGroup(ctx context.Context, groupID int) (bool, error) {
+	if fake.DeleteGroupFunc == nil {
+		panic("unexpected call of GroupRepository.DeleteGroup")
+	}
+	return fake.DeleteGroupFunc(ctx, groupID)
+}
+
+func (fake *GroupRepository) SetGroupMember(ctx context.Context, groupID *int, clientID int) (*storage.ClientGroup, error) {
+	if fake.SetGroupMemberFunc == nil {
+		panic("unexpected call of GroupRepository.SetGroupMember")
+	}
+	return fake.SetGroupMemberFunc(ctx, groupID, clientID)
+}
+
+func (fake *GroupRepository) RemoveGroupMember(ctx context.Context, groupID int, clientID int) (*storage.ClientGroup, error) {
+	if fake.RemoveGroupMemberFunc == nil {
+		panic("unexpected call of GroupRepository.RemoveGroupMember")
+	}
+	return fake.RemoveGroupMemberFunc(ctx, groupID, clientID)
+}
+
+// MetadataRepository - double of storage.MetadataRepository
+type MetadataRepository struct {
+	GetMetadataSchemaFunc    func(ctx context.Context, entity storage.MetadataEntity) (*storage.MetadataSchema, error)
+	SetMetadataSchemaFunc    func(ctx context.Context, entity storage.MetadataEntity, schema json.RawMessage) (*storage.MetadataSchema, error)
+	DeleteMetadataSchemaFunc func(ctx context.Context, entity storage.MetadataEntity) (bool, error)
+}
+
+var _ storage.MetadataRepository = (*MetadataRepository)(nil)
+
+func (fake *MetadataRepository) GetMetadataSchema(ctx context.Context, entity storage.MetadataEntity) (*storage.MetadataSchema, error) {
+	if fake.GetMetadataSchemaFunc == nil {
+		panic("unexpected call of MetadataRepository.GetMetadataSchema")
+	}
+	return fake.GetMetadataSchemaFunc(ctx, entity)
+}
+
+func (fake *MetadataRepository) SetMetadataSchema(ctx context.Context, entity storage.MetadataEntity, schema json.RawMessage) (*storage.MetadataSchema, error) {
+	if fake.SetMetadataSchemaFunc == nil {
+		panic("unexpected call of MetadataRepository.SetMetadataSchema")
+	}
+	return fake.SetMetadataSchemaFunc(ctx, entity, schema)
+}
+
+func (fake *MetadataRepository) DeleteMetadataSchema(ctx context.Context, entity storage.MetadataEntity) (bool, error) {
+	if fake.DeleteMetadataSchemaFunc == nil {
+		panic("unexpected call of MetadataRepository.DeleteMetadataSchema")
+	}
+	return fake.DeleteMetadataSchemaFunc(ctx, entity)
+}
+
+// StatsRepository - double of storage.StatsRepository
+type StatsRepository struct {
+	ClientsStatsFunc func(ctx context.Context) (*storage.ClientsStats, error)
+}
+
+var _ storage.StatsRepository = (*StatsRepository)(nil)
+
+func (fake *StatsRepository) ClientsStats(ctx context.Context) (*storage.ClientsStats, error) {
+	if fake.ClientsStatsFunc == nil {
+		panic("unexpected call of StatsRepository.ClientsStats")
+	}
+	return fake.ClientsStatsFunc(ctx)
+}
+
+// BackupRepository - double of storage.BackupRepository
+type BackupRepository struct {
+	BackupFunc func(ctx context.Context, path string) (*storage.BackupInfo, error)
+}
+
+var _ storage.BackupRepository = (*BackupRepository)(nil)
+
+func (fake *BackupRepository) Backup(ctx context.Context, path string) (*storage.BackupInfo, error) {
+	if fake.BackupFunc == nil {
+		panic("unexpected call of BackupRepository.Backup")
+	}
+	return fake.BackupFunc(ctx, path)
+}
+
+// CapacityIndexRepository - double of storage.CapacityIndexRepository
+type CapacityIndexRepository struct {
+	CheckCapacityIndexFunc func(ctx context.Context) (*storage.CapacityIndexReport, error)
+}
+
+var _ storage.CapacityIndexRepository = (*CapacityIndexRepository)(nil)
+
+func (fake *CapacityIndexRepository) CheckCapacityIndex(ctx context.Context) (*storage.CapacityIndexReport, error) {
+	if fake.CheckCapacityIndexFunc == nil {
+		panic("unexpected call of CapacityIndexRepository.CheckCapacityIndex")
+	}
+	return fake.CheckCapacityIndexFunc(ctx)
+}
+
+// Repository - double of storage.Repository
+type Repository struct {
+	CreateClientFunc         func(ctx context.Context, c storage.ClientRequest) (*storage.Client, error)
+	GetClientsFunc           func(ctx context.Context, clientID *int) ([]storage.Client, error)
+	ListClientsFunc          func(ctx context.Context, f storage.ClientsFilter) (*storage.ClientsPage, error)
+	ArchiveClientFunc        func(ctx context.Context, clientID int, policy storage.LeadsPolicy) (*storage.ArchiveResult, error)
+	RestoreClientFunc        func(ctx context.Context, clientID int) (*storage.Client, error)
+	ClientHistoryFunc        func(ctx context.Context, clientID int) ([]storage.ClientChange, error)
+	ClientAsOfFunc           func(ctx context.Context, clientID int, asOf time.Time) (*storage.ClientAsOf, error)
+	UpdateClientMetadataFunc func(ctx context.Context, clientID int, metadata storage.Metadata) (*storage.Client, error)
+	ImportClientsFunc        func(ctx context.Context, rows []storage.ImportRow, mode storage.ImportMode) (*storage.ImportReport, error)
+	EachClientFunc           func(ctx context.Context, f storage.ClientsFilter, fn func(c storage.Client, used int) error) error
+	AssignLeadFunc           func(ctx context.Context, l storage.AssignLeadRequest) (*storage.Lead, error)
+	ListLeadsFunc            func(ctx context.Context, f storage.LeadsFilter) (*storage.LeadsPage, error)
+	GetLeadFunc              func(ctx context.Context, leadID string) (*storage.Lead, error)
+	UpdateLeadMetadataFunc   func(ctx context.Context, leadID string, metadata storage.Metadata) (*storage.Lead, error)
+	EachLeadFunc             func(ctx context.Context, f storage.LeadsFilter, fn func(l storage.Lead) error) error
+	GetGroupsFunc            func(ctx context.Context, groupID *int) ([]storage.ClientGroup, error)
+	CreateGroupFunc          func(ctx context.Context, g storage.ClientGroupRequest) (*storage.ClientGroup, error)
+	UpdateGroupFunc          func(ctx context.Context, groupID int, g storage.ClientGroupRequest) (*storage.ClientGroup, error)
+	DeleteGroupFunc          func(ctx context.Context, groupID int) (bool, error)
+	SetGroupMemberFunc       func(ctx context.Context, groupID *int, clientID int) (*storage.ClientGroup, error)
+	RemoveGroupMemberFunc    func(ctx context.Context, groupID int, clientID int) (*storage.ClientGroup, error)
+	GetMetadataSchemaFunc    func(ctx context.Context, entity storage.MetadataEntity) (*storage.MetadataSchema, error)
+	SetMetadataSchemaFunc    func(ctx context.Context, entity storage.MetadataEntity, schema json.RawMessage) (*storage.MetadataSchema, error)
+	DeleteMetadataSchemaFunc func(ctx context.Context, entity storage.MetadataEntity) (bool, error)
+	ClientsStatsFunc         func(ctx context.Context) (*storage.ClientsStats, error)
+	BackupFunc               func(ctx context.Context, path string) (*storage.BackupInfo, error)
+	CheckCapacityIndexFunc   func(ctx context.Context) (*storage.CapacityIndexReport, error)
+}
+
+var _ storage.Repository = (*Repository)(nil)
+
+func (fake *Repository) CreateClient(ctx context.Context, c storage.ClientRequest) (*storage.Client, error) {
+	if fake.CreateClientFunc == nil {
+		panic("unexpected call of Repository.CreateClient")
+	}
+	return fake.CreateClientFunc(ctx, c)
+}
+
+func (fake *Repository) GetClients(ctx context.Context, clientID *int) ([]storage.Client, error) {
+	if fake.GetClientsFunc == nil {
+		panic("unexpected call of Repository.GetClients")
+	}
+	return fake.GetClientsFunc(ctx, clientID)
+}
+
+func (fake *Repository) ListClients(ctx context.Context, f storage.ClientsFilter) (*storage.ClientsPage, error) {
+	if fake.ListClientsFunc == nil {
+		panic("unexpected call of Repository.ListClients")
+	}
+	return fake.ListClientsFunc(ctx, f)
+}
+
+func (fake *Repository) ArchiveClient(ctx context.Context, clientID int, policy storage.LeadsPolicy) (*storage.ArchiveResult, error) {
+	if fake.ArchiveClientFunc == nil {
+		panic("unexpected call of Repository.ArchiveClient")
+	}
+	return fake.ArchiveClientFunc(ctx, clientID, policy)
+}
+
+func (fake *Repository) RestoreClient(ctx context.Context, clientID int) (*storage.Client, error) {
+	if fake.RestoreClientFunc == nil {
+		panic("unexpected call of Repository.RestoreClient")
+	}
+	return fake.RestoreClientFunc(ctx, clientID)
+}
+
+func (fake *Repository) ClientHistory(ctx context.Context, clientID int) ([]storage.ClientChange, error) {
+	if fake.ClientHistoryFunc == nil {
+		panic("unexpected call of Repository.ClientHistory")
+	}
+	return fake.ClientHistoryFunc(ctx, clientID)
+}
+
+func (fake *Repository) ClientAsOf(ctx context.Context, clientID int, asOf time.Time) (*storage.ClientAsOf, error) {
+	if fake.ClientAsOfFunc == nil {
+		panic("unexpected call of Repository.ClientAsOf")
+	}
+	return fake.ClientAsOfFunc(ctx, clientID, asOf)
+}
+
+func (fake *Repository) UpdateClientMetadata(ctx context.Context, clientID int, metadata storage.Metadata) (*storage.Client, error) {
+	if fake.UpdateClientMetadataFunc == nil {
+		panic("unexpected call of Repository.UpdateClientMetadata")
+	}
+	return fake.UpdateClientMetadataFunc(ctx, clientID, metadata)
+}
+
+func (fake *Repository) ImportClients(ctx context.Context, rows []storage.ImportRow, mode storage.ImportMode) (*storage.ImportReport, error) {
+	if fake.ImportClientsFunc == nil {
+		panic("unexpected call of Repository.ImportClients")
+	}
+	return fake.ImportClientsFunc(ctx, rows, mode)
+}
+
+func (fake *Repository) EachClient(ctx context.Context, f storage.ClientsFilter, fn func(c storage.Client, used int) error) error {
+	if fake.EachClientFunc == nil {
+		panic("unexpected call of Repository.EachClient")
+	}
+	return fake.EachClientFunc(ctx, f, fn)
+}
+
+func (fake *Repository) AssignLead(ctx context.Context, l storage.AssignLeadRequest) (*storage.Lead, error) {
+	if fake.AssignLeadFunc == nil {
+		panic("unexpected call of Repository.AssignLead")
+	}
+	return fake.AssignLeadFunc(ctx, l)
+}
+
+func (fake *Repository) ListLeads(ctx context.Context, f storage.LeadsFilter) (*storage.LeadsPage, error) {
+	if fake.ListLeadsFunc == nil {
+		panic("unexpected call of Repository.ListLeads")
+	}
+	return fake.ListLeadsFunc(ctx, f)
+}
+
+func (fake *Repository) GetLead(ctx context.Context, leadID string) (*storage.Lead, error) {
+	if fake.GetLeadFunc == nil {
+		panic("unexpected call of Repository.GetLead")
+	}
+	return fake.GetLeadFunc(ctx, leadID)
+}
+
+func (fake *Repository) UpdateLeadMetadata(ctx context.Context, leadID string, metadata storage.Metadata) (*storage.Lead, error) {
+	if fake.UpdateLeadMetadataFunc == nil {
+		panic("unexpected call of Repository.UpdateLeadMetadata")
+	}
+	return fake.UpdateLeadMetadataFunc(ctx, leadID, metadata)
+}
+
+func (fake *Repository) EachLead(ctx context.Context, f storage.LeadsFilter, fn func(l storage.Lead) error) error {
+	if fake.EachLeadFunc == nil {
+		panic("unexpected call of Repository.EachLead")
+	}
+	return fake.EachLeadFunc(ctx, f, fn)
+}
+
+func (fake *Repository) GetGroups(ctx context.Context, groupID *int) ([]storage.ClientGroup, error) {
+	if fake.GetGroupsFunc == nil {
+		panic("unexpected call of Repository.GetGroups")
+	}
+	return fake.GetGroupsFunc(ctx, groupID)
+}
+
+func (fake *Repository) CreateGroup(ctx context.Context, g storage.ClientGroupRequest) (*storage.ClientGroup, error) {
+	if fake.CreateGroupFunc == nil {
+		panic("unexpected call of Repository.CreateGroup")
+	}
+	return fake.CreateGroupFunc(ctx, g)
+}
+
+func (fake *Repository) UpdateGroup(ctx context.Context, groupID int, g storage.ClientGroupRequest) (*storage.ClientGroup, error) {
+	if fake.UpdateGroupFunc == nil {
+		panic("unexpected call of Repository.UpdateGroup")
+	}
+	return fake.UpdateGroupFunc(ctx, groupID, g)
+}
+
+func (fake *Repository) DeleteGroup(ctx context.Context, groupID int) (bool, error) {
+	if fake.DeleteGroupFunc == nil {
+		panic("unexpected call of Repository.DeleteGroup")
+	}
+	return fake.DeleteGroupFunc(ctx, groupID)
+}
+
+func (fake *Repository) SetGroupMember(ctx context.Context, groupID *int, clientID int) (*storage.ClientGroup, error) {
+	if fake.SetGroupMemberFunc == nil {
+		panic("unexpected call of Repository.SetGroupMember")
+	}
+	return fake.SetGroupMemberFunc(ctx, groupID, clientID)
+}
+
+func (fake *Repository) RemoveGroupMember(ctx context.Context, groupID int, clientID int) (*storage.ClientGroup, error) {
+	if fake.RemoveGroupMemberFunc == nil {
+		panic("unexpected call of Repository.RemoveGroupMember")
+	}
+	return fake.RemoveGroupMemberFunc(ctx, groupID, clientID)
+}
+
+func (fake *Repository) GetMetadataSchema(ctx context.Context, entity storage.MetadataEntity) (*storage.MetadataSchema, error) {
+	if fake.GetMetadataSchemaFunc == nil {
+		panic("unexpected call of Repository.GetMetadataSchema")
+	}
+	return fake.GetMetadataSchemaFunc(ctx, entity)
+}
+
+func (fake *Repository) SetMetadataSchema(ctx context.Context, entity storage.MetadataEntity, schema json.RawMessage) (*storage.MetadataSchema, error) {
+	if fake.SetMetadataSchemaFunc == nil {
+		panic("unexpected call of Repository.SetMetadataSchema")
+	}
+	return fake.SetMetadataSchemaFunc(ctx, entity, schema)
+}
+
+func (fake *Repository) DeleteMetadataSchema(ctx context.Context, entity storage.MetadataEntity) (bool, error) {
+	if fake.DeleteMetadataSchemaFunc == nil {
+		panic("unexpected call of Repository.DeleteMetadataSchema")
+	}
+	return fake.DeleteMetadataSchemaFunc(ctx, entity)
+}
+
+func (fake *Repository) ClientsStats(ctx context.Context) (*storage.ClientsStats, error) {
+	if fake.ClientsStatsFunc == nil {
+		panic("unexpected call of Repository.ClientsStats")
+	}
+	return fake.ClientsStatsFunc(ctx)
+}
+
+func (fake *Repository) Backup(ctx context.Context, path string) (*storage.BackupInfo, error) {
+	if fake.BackupFunc == nil {
+		panic("unexpected call of Repository.Backup")
+	}
+	return fake.BackupFunc(ctx, path)
+}
+
+func (fake *Repository) CheckCapacityIndex(ctx context.Context) (*storage.CapacityIndexReport, error) {
+	if fake.CheckCapacityIndexFunc == nil {
+		panic("unexpected call of Repository.CheckCapacityIndex")
+	}
+	return fake.CheckCapacityIndexFunc(ctx)
+}
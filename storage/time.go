@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// LegacyTimeLayout - layout of dates the API accepted before RFC 3339. Such dates are UTC
+const LegacyTimeLayout = "2006-01-02 15:04:05"
+
+const (
+	// storedTimeLayout - canonical form of client and lead dates in the database: RFC 3339 in UTC with a fixed width,
+	// so comparing the text compares the moments
+	storedTimeLayout = "2006-01-02T15:04:05Z"
+	// historyTimeLayout - storedTimeLayout with milliseconds, keeps the order of quick changes in the history
+	historyTimeLayout = "2006-01-02T15:04:05.000Z"
+)
+
+// ParseTime - parses an RFC 3339 or a LegacyTimeLayout date. The result is in UTC
+func ParseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.UTC(), nil
+	}
+
+	t, err := time.Parse(LegacyTimeLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date '%s': expected RFC 3339 or YYYY-MM-DD HH:MM:SS", value)
+	}
+
+	return t, nil
+}
+
+// Timestamp - date of a request. Accepts both ParseTime formats in JSON and in query parameters, zero when not set.
+// A value that is not a date is kept for validation instead of failing the decoding, so it can be reported with its field
+type Timestamp struct {
+	time.Time
+	invalid string
+}
+
+// Valid - the value was a date or was not set
+func (t Timestamp) Valid() bool {
+	return t.invalid == ""
+}
+
+// Raw - the value that could not be parsed
+func (t Timestamp) Raw() string {
+	return t.invalid
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*t = Timestamp{}
+		return nil
+	}
+
+	value, err := strconv.Unquote(string(data))
+	if err != nil {
+		*t = Timestamp{invalid: string(data)}
+		return nil
+	}
+
+	return t.UnmarshalParam(value)
+}
+
+// UnmarshalParam - binds query and form parameters
+func (t *Timestamp) UnmarshalParam(param string) error {
+	*t = Timestamp{}
+	if param == "" {
+		return nil
+	}
+
+	parsed, err := ParseTime(param)
+	if err != nil {
+		t.invalid = param
+		return nil
+	}
+
+	t.Time = parsed
+
+	return nil
+}
+
+// timeArg - the date as a query argument in the canonical form
+func timeArg(t time.Time) string {
+	return t.UTC().Format(storedTimeLayout)
+}
+
+// scanTime - destination for a date column. NULL leaves the date zero
+func scanTime(dest *time.Time) sql.Scanner {
+	return timeScanner{dest: dest}
+}
+
+type timeScanner struct {
+	dest *time.Time
+}
+
+func (s timeScanner) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*s.dest = time.Time{}
+		return nil
+	case time.Time:
+		*s.dest = v.UTC()
+		return nil
+	case []byte:
+		return s.Scan(string(v))
+	case string:
+		t, err := ParseTime(v)
+		if err != nil {
+			return err
+		}
+		*s.dest = t
+		return nil
+	}
+
+	return fmt.Errorf("can't scan %T into a date", src)
+}
@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	t, err := ParseTime(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "RFC 3339 UTC", value: "2024-03-01T10:00:00Z", want: "2024-03-01T10:00:00Z"},
+		{name: "RFC 3339 with fraction", value: "2024-03-01T10:00:00.250Z", want: "2024-03-01T10:00:00.25Z"},
+		{name: "positive offset", value: "2024-03-01T10:00:00+03:00", want: "2024-03-01T07:00:00Z"},
+		{name: "negative offset across midnight", value: "2024-03-01T22:30:00-05:00", want: "2024-03-02T03:30:00Z"},
+		{name: "legacy", value: "2024-03-01 10:00:00", want: "2024-03-01T10:00:00Z"},
+		// The layout the request was about: "01" read the month into the day
+		{name: "legacy day after the 12th", value: "2024-03-25 00:00:00", want: "2024-03-25T00:00:00Z"},
+		{name: "date only", value: "2024-03-01", wantErr: true},
+		{name: "legacy with offset", value: "2024-03-01 10:00:00+03:00", wantErr: true},
+		{name: "not a date", value: "yesterday", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTime(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTime(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTime(%q): %v", tt.value, err)
+			}
+
+			if got.Location() != time.UTC {
+				t.Errorf("ParseTime(%q) location = %v, want UTC", tt.value, got.Location())
+			}
+			if s := got.Format(time.RFC3339Nano); s != tt.want {
+				t.Errorf("ParseTime(%q) = %s, want %s", tt.value, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimestampUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		json      string
+		want      string
+		wantValid bool
+	}{
+		{name: "RFC 3339", json: `"2024-03-01T10:00:00Z"`, want: "2024-03-01T10:00:00Z", wantValid: true},
+		{name: "offset", json: `"2024-03-01T10:00:00+02:00"`, want: "2024-03-01T08:00:00Z", wantValid: true},
+		{name: "legacy", json: `"2024-03-01 10:00:00"`, want: "2024-03-01T10:00:00Z", wantValid: true},
+		{name: "null", json: `null`, wantValid: true},
+		{name: "empty", json: `""`, wantValid: true},
+		{name: "invalid is kept", json: `"2024-13-01 10:00:00"`, wantValid: false},
+		{name: "not a string", json: `20240301`, wantValid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ts Timestamp
+			if err := json.Unmarshal([]byte(tt.json), &ts); err != nil {
+				t.Fatalf("Unmarshal(%s): %v", tt.json, err)
+			}
+
+			if ts.Valid() != tt.wantValid {
+				t.Fatalf("Unmarshal(%s) valid = %v, want %v (raw %q)", tt.json, ts.Valid(), tt.wantValid, ts.Raw())
+			}
+			if !tt.wantValid {
+				if ts.Raw() == "" {
+					t.Errorf("Unmarshal(%s) lost the invalid value", tt.json)
+				}
+				return
+			}
+
+			got := ""
+			if !ts.IsZero() {
+				got = ts.Format(time.RFC3339)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal(%s) = %q, want %q", tt.json, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeArg(t *testing.T) {
+	tests := []struct {
+		name string
+		time time.Time
+		want string
+	}{
+		{name: "UTC", time: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), want: "2024-03-01T10:00:00Z"},
+		{name: "offset", time: time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("", 3*3600)), want: "2024-03-01T07:00:00Z"},
+		{name: "fraction is dropped", time: time.Date(2024, 3, 1, 10, 0, 0, 999, time.UTC), want: "2024-03-01T10:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := timeArg(tt.time); got != tt.want {
+				t.Errorf("timeArg(%v) = %s, want %s", tt.time, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithinWindow(t *testing.T) {
+	windowStart := date("2024-01-01T00:00:00Z")
+	windowEnd := date("2024-01-31T00:00:00Z")
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       bool
+	}{
+		{name: "inside", start: date("2024-01-10T00:00:00Z"), end: date("2024-01-11T00:00:00Z"), want: true},
+		{name: "whole window", start: windowStart, end: windowEnd, want: true},
+		{name: "starts at the window start", start: windowStart, end: date("2024-01-02T00:00:00Z"), want: true},
+		{name: "ends at the window end", start: date("2024-01-30T00:00:00Z"), end: windowEnd, want: true},
+		{name: "single moment at the end", start: windowEnd, end: windowEnd, want: true},
+		{name: "spans the window end", start: date("2024-01-30T00:00:00Z"), end: date("2024-02-02T00:00:00Z"), want: false},
+		{name: "ends a second after the window", start: date("2024-01-30T00:00:00Z"), end: date("2024-01-31T00:00:01Z"), want: false},
+		{name: "spans the window start", start: date("2023-12-31T00:00:00Z"), end: date("2024-01-02T00:00:00Z"), want: false},
+		{name: "after the window", start: date("2024-02-01T00:00:00Z"), end: date("2024-02-02T00:00:00Z"), want: false},
+		{name: "covers the window", start: date("2023-12-01T00:00:00Z"), end: date("2024-03-01T00:00:00Z"), want: false},
+		{name: "inverted", start: date("2024-01-11T00:00:00Z"), end: date("2024-01-10T00:00:00Z"), want: false},
+		{name: "unset start", end: date("2024-01-11T00:00:00Z"), want: false},
+		{name: "unset end", start: date("2024-01-10T00:00:00Z"), want: false},
+		{
+			name:  "offset moments inside",
+			start: time.Date(2024, 1, 1, 2, 0, 0, 0, time.FixedZone("", 2*3600)),    // 2024-01-01T00:00:00Z
+			end:   time.Date(2024, 1, 30, 19, 0, 0, 0, time.FixedZone("", -5*3600)), // 2024-01-31T00:00:00Z
+			want:  true,
+		},
+		{
+			name:  "offset moment past the end",
+			start: date("2024-01-30T00:00:00Z"),
+			// Looks like the last day of the window, is an hour after its end in UTC
+			end:  time.Date(2024, 1, 30, 20, 0, 0, 0, time.FixedZone("", -5*3600)),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withinWindow(windowStart, windowEnd, tt.start, tt.end); got != tt.want {
+				t.Errorf("withinWindow(%v, %v) = %v, want %v", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("inverted window", func(t *testing.T) {
+		if withinWindow(windowEnd, windowStart, date("2024-01-10T00:00:00Z"), date("2024-01-11T00:00:00Z")) {
+			t.Error("an inverted window contains a lead")
+		}
+	})
+	t.Run("unset window", func(t *testing.T) {
+		if withinWindow(time.Time{}, windowEnd, date("2024-01-10T00:00:00Z"), date("2024-01-11T00:00:00Z")) {
+			t.Error("a window without a start contains a lead")
+		}
+	})
+}
+
+func TestUnsuitableTime(t *testing.T) {
+	client := Client{
+		StartDate: date("2024-01-01T00:00:00Z"),
+		EndDate:   date("2024-01-31T00:00:00Z"),
+	}
+
+	tests := []struct {
+		name       string
+		start, end string
+		want       bool
+	}{
+		{name: "RFC 3339 inside", start: "2024-01-10T00:00:00Z", end: "2024-01-11T00:00:00Z", want: false},
+		{name: "legacy inside", start: "2024-01-10 00:00:00", end: "2024-01-11 00:00:00", want: false},
+		{name: "legacy and RFC 3339 mixed", start: "2024-01-01 00:00:00", end: "2024-01-31T00:00:00Z", want: false},
+		{name: "legacy on both bounds", start: "2024-01-01 00:00:00", end: "2024-01-31 00:00:00", want: false},
+		{name: "legacy spanning the end", start: "2024-01-30 00:00:00", end: "2024-02-01 00:00:00", want: true},
+		{name: "RFC 3339 spanning the end", start: "2024-01-30T00:00:00Z", end: "2024-02-01T00:00:00Z", want: true},
+		// Checked against the client's end date, not a date derived from its start
+		{name: "late in the frame", start: "2024-01-29T00:00:00Z", end: "2024-01-30T00:00:00Z", want: false},
+		{name: "offset normalized inside", start: "2024-01-01T03:00:00+03:00", end: "2024-01-30T21:00:00-03:00", want: false},
+		{name: "offset normalized before the start", start: "2024-01-01T02:00:00+03:00", end: "2024-01-02T00:00:00Z", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lead := AssignLeadRequest{
+				LeadStart: Timestamp{Time: date(tt.start)},
+				LeadEnd:   Timestamp{Time: date(tt.end)},
+			}
+
+			if got := unsuitableTime(client, lead); got != tt.want {
+				t.Errorf("unsuitableTime(%s, %s) = %v, want %v", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}